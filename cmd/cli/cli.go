@@ -7,10 +7,13 @@
 package cli
 
 import (
+	"flag"
 	"fmt"
+	"io"
+	"regexp"
+	"slices"
 	"strings"
-
-	"github.com/google/uuid"
+	"time"
 )
 
 // HelpPrompt displays the helper message with all the options accepted by rlcp
@@ -31,24 +34,173 @@ OPERATIONS
          rlcp run pwd
          rlcp run "ls -la"
          rlcp run "tail -f server.log"
+
+    run --script <file> [--interpreter <interpreter>] [<arguments>]
+        uploads the local <file> and runs it on the server with <interpreter>, /bin/sh when not informed.
+        The script is stored in a temporary file, which is removed when the job ends.
+
+        Examples:
+         rlcp run --script ./fix.sh
+         rlcp run --script ./report.py --interpreter python3 "weekly yesterday"
+
+    run --artifact <glob> [--artifact <glob>...] <command>
+        every job runs in its own workspace directory, which is removed when the job ends. The files matching
+        the <glob> patterns are kept as artifacts, which are listed by the status operation and can be downloaded
+        with the artifacts operation. Patterns without a / match files in any directory of the workspace.
+
+        Example:
+         rlcp run --artifact "*.hprof" --artifact "reports/*.csv" "./export.sh"
+
+    run --max-output <bytes> [--output-policy stop|ring|head-tail] <command>
+        limits the output stored for the job, up to the limit set on the server. Once the output gets to the limit,
+        stop stops the job, ring keeps it running and drops the oldest output, and head-tail keeps the start and the
+        end of the output, dropping the middle. The server policy is used when --output-policy is not informed.
+        The output dropped is shown as a marker by the output operation, and the status operation reports it.
+
+        Example:
+         rlcp run --max-output 10000000 --output-policy ring "./soak-test.sh"
+
+    run --secret <variable>=<secret> [--secret <variable>=<secret>...] <command>
+        sets the environment <variable> for the job to the value of a <secret> stored on the server, so passwords are
+        not informed on the command line. The user must be allowed to use the secret, and its value is masked in the output.
+
+        Example:
+         rlcp run --secret PGPASSWORD=db-password "psql -h db -U app -c 'select 1'"
+
+    run --label <key>=<value> [--label <key>=<value>...] <command>
+        sets labels on the job, to select it along with other jobs with the same labels. The stop, signal, delete and
+        list operations accept a label selector with -l.
+
+        Example:
+         rlcp run --label deploy=42 --label role=migrate "./migrate.sh"
+
+    run --description <text> <command>
+        describes the job with a free-form <text>, up to 1024 bytes, which is shown by the status operation.
+
+        Example:
+         rlcp run --description "nightly export for the finance team" "./export.sh"
+
+    run --webhook <url> [--webhook <url>...] <command>
+        posts a JSON notification to each <url> once the job ends, along with the webhooks configured on the server.
+        The notification has the event, like job.completed or job.errored, the job details and the last lines of its
+        output, and is retried when it fails. The attempts are shown by the status operation.
+
+        Example:
+         rlcp run --webhook https://ci.example.com/hooks/rlcp "./build.sh"
+
+    run --name <name> <command>
+        gives the job a unique <name>, starting with a letter and followed by letters, digits, '.', '_' or '-'.
+        The name, or a prefix of the job id matching a single job, can be used anywhere a job id is accepted.
+
+        Example:
+         rlcp run --name nightly-reindex "./reindex.sh"
+         rlcp status nightly-reindex
+         rlcp output bf7a1e
     
     status <job id>
         gets the status for the job or an error message if the id is invalid or the user doesn't have the appropriate permissions.
+        Along with the status, it shows who scheduled the job, its command line and description, the host and pid running it,
+        when it was submitted, started and ended, and the size of its output.
 
         Example:
         rlcp status bf7a1eae-8d25-4de5-995b-8c4d3ef8b848
+
+    wait [--timeout <duration>] <job id>
+        waits for the job to end and exits with its exit code, or with 128 plus the signal number when it was killed by a signal.
+        It exits with 1 when the exit code is unknown, like for jobs lost by a server restart, or when the job can't be watched.
+        With --timeout, like 30s or 2h, it exits with 124 if the job is still running once the timeout expires.
+
+        Examples:
+        rlcp wait nightly-reindex
+        rlcp wait --timeout 10m bf7a1eae-8d25-4de5-995b-8c4d3ef8b848
     
-    output <job id>
+    output [-f|--follow] [--tail <lines>] [--tail-bytes <bytes>] [--lines] [--timestamps] [--format text|ndjson]
+           [--grep <regex>] [--exclude <regex>] [--since <time>] [--until <time>] [--max-lines <lines>] <job id>
         prints the output for the job or an error message if the id is invalid or the user doesn't have the appropriate permissions.
+        By default, only the output available at the moment is printed. With -f or --follow, the new output is printed until the
+        job ends, and it's resumed from where it stopped when the connection to the server is lost.
+        --tail and --tail-bytes print only the last lines or bytes from the output.
+        --lines prefixes every line with its number, and --timestamps with the time it was captured on the server.
+        --format ndjson prints one JSON object per line instead, with its time, stream (stdout or stderr), number and text.
+        With these options the server sends whole lines, and a line still waiting for its end is sent after a second.
+        The filters are applied on the server, so only the matching lines are sent: --grep and --exclude print only the lines
+        matching or not matching a regular expression, --since and --until the lines captured in a time range, and --max-lines
+        stops after that number of lines. Times are in RFC 3339 format, like 2024-05-01T10:00:00Z, or a duration ago, like 10m.
 
-        Example:
+        Examples:
         rlcp output 8060271e-b776-4444-9e75-bd2e3db3cc7d
+        rlcp output -f 8060271e-b776-4444-9e75-bd2e3db3cc7d
+        rlcp output --tail 100 -f 8060271e-b776-4444-9e75-bd2e3db3cc7d
+        rlcp output --timestamps 8060271e-b776-4444-9e75-bd2e3db3cc7d
+        rlcp output --format ndjson 8060271e-b776-4444-9e75-bd2e3db3cc7d > output.ndjson
+        rlcp output --grep ERROR --since 10m 8060271e-b776-4444-9e75-bd2e3db3cc7d
+
+    list [--owner <email>] [--status <status>[,<status>...]] [--command <text>] [-l <selector>] [--since <time>] [--until <time>]
+         [--sort submitted|started|ended|owner|command] [--asc] [--limit <jobs>] [--page-token <token>] [--format table|json]
+        lists the jobs, the most recently submitted first. Users other than admins only see their own jobs.
+        --status filters by running, completed, errored, stopped or lost, --command by a text in the command line,
+        -l by a label selector, and --since and --until by the time the job was submitted, in the same formats as the output operation.
+        --sort changes the order, which is descending unless --asc is informed. Up to 50 jobs are listed by default,
+        or up to --limit, and when there are more, the token to list the next ones with --page-token is printed.
+        --format json prints the jobs and the next page token as a JSON object.
+
+        Examples:
+        rlcp list
+        rlcp list --status running,errored --since 24h
+        rlcp list --owner marcel+client@email.com --command backup --format json
+        rlcp list -l deploy=42
 
     stop <job id>
         stops the job identified by job id. Returns an error message if the id is invalid or the user doesn't have the appropriate permissions.
 
         Example:
-        rlcp stop af1f8215-bee7-455d-874a-55f0e3fb20b5`
+        rlcp stop af1f8215-bee7-455d-874a-55f0e3fb20b5
+
+    stop -l <selector>
+    signal -l <selector> <signal>
+    delete -l <selector>
+        stops, sends a signal to, or deletes every job matching the label <selector>, printing the result for each job.
+        The selector is a list of requirements separated by commas: key=value, key!=value, key for jobs with the label,
+        and !key for jobs without it. Users other than admins only select their own jobs. The signal is a name like TERM,
+        HUP or USR1. Deleting removes finished jobs with their output and artifacts, and is only allowed for admins.
+
+        Examples:
+        rlcp stop -l deploy=42,role=migrate
+        rlcp signal -l deploy=42 HUP
+        rlcp delete -l deploy=41
+
+    purge <job id>
+        removes the output stored on the server for a finished job. Only allowed for admins.
+
+        Example:
+        rlcp purge af1f8215-bee7-455d-874a-55f0e3fb20b5
+
+    artifacts <job id> [<file>]
+        downloads the artifacts kept from a finished job as a tar.gz archive to <file>, <job id>.tar.gz by default.
+
+        Example:
+        rlcp artifacts 8060271e-b776-4444-9e75-bd2e3db3cc7d reports.tar.gz
+
+    cp [--resume] <source> <destination>
+        copies a file to or from the server. The path on the server is prefixed by remote:, and exactly one of
+        <source> or <destination> should be remote. With --resume, a previous partial transfer is continued
+        from where it stopped.
+
+        Examples:
+        rlcp cp ./input.csv remote:/tmp/input.csv
+        rlcp cp --resume remote:heap.hprof ./heap.hprof
+
+    secret set [--user <email>...] <name>
+    secret list
+    secret delete <name>
+        manages the secrets stored on the server, which can be injected into jobs with run --secret. Only allowed for admins.
+        set reads the value from the standard input, and --user adds the users allowed to use the secret.
+        list shows the secrets and their users, without the values.
+
+        Examples:
+        rlcp secret set --user marcel+client@email.com db-password < password.txt
+        rlcp secret list
+        rlcp secret delete db-password`
 
 type Operation uint
 
@@ -58,8 +210,42 @@ const (
 	Output
 	Stop
 	Help
+	Copy
+	Artifacts
+	Purge
+	SecretSet
+	SecretList
+	SecretDelete
+	List
+	Signal
+	Delete
+	Wait
 )
 
+// RemotePrefix identifies the path on the server for a Copy operation
+const RemotePrefix = "remote:"
+
+// OutputPolicies are the policies accepted for a job once its output gets to the limit
+var OutputPolicies = []string{"stop", "ring", "head-tail"}
+
+// Formats accepted for the output of an Output operation. Text is used when no format is informed.
+const (
+	FormatText   = "text"
+	FormatNDJSON = "ndjson"
+)
+
+// Formats accepted for a List operation. Table is used when no format is informed.
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+)
+
+// JobStatuses are the statuses accepted to filter the jobs listed
+var JobStatuses = []string{"running", "completed", "errored", "stopped", "lost"}
+
+// ListSorts are the fields accepted to sort the jobs listed
+var ListSorts = []string{"submitted", "started", "ended", "owner", "command"}
+
 type ErrInvalidCommand struct {
 	err string
 }
@@ -72,9 +258,63 @@ func (e ErrInvalidCommand) Error() string {
 	return e.err
 }
 
+// Option is the operation parsed from the command line, with its arguments.
+// Most fields are only set for the operations they apply to.
 type Option struct {
 	Op   Operation
 	Args []string
+	// Script and Interpreter are set for Run operations uploading a local script
+	Script      string
+	Interpreter string
+	// Artifacts are the files kept from the job workspace for Run operations
+	Artifacts []string
+	// MaxOutput limits the output kept for the job of a Run operation, and OutputPolicy is applied once the
+	// output gets to it
+	MaxOutput    int64
+	OutputPolicy string
+	// Resume continues a previous Copy operation
+	Resume bool
+	// Follow, TailLines and TailBytes select the output printed by Output operations
+	Follow    bool
+	TailLines int64
+	TailBytes int64
+	// Timestamps, Grep, Exclude, MaxLines and Lines shape the lines printed by Output operations
+	Timestamps bool
+	// Format is the output format for Output and List operations
+	Format  string
+	Grep    string
+	Exclude string
+	// Since and Until filter the lines printed by Output operations, or the jobs listed by List operations
+	Since    time.Time
+	Until    time.Time
+	MaxLines int64
+	Lines    bool
+	// SecretEnv maps the environment variables set for a Run operation to the names of the secrets
+	SecretEnv map[string]string
+	// Users are the users allowed to use a secret for SecretSet operations
+	Users []string
+	// Description is the text describing the job for Run operations, and Name its unique name
+	Description string
+	Name        string
+	// Owner, Statuses and Command filter the jobs for List operations
+	Owner    string
+	Statuses []string
+	Command  string
+	// SortBy is the field the jobs of a List operation are sorted by, in descending order unless Ascending is set
+	SortBy    string
+	Ascending bool
+	// PageSize is the number of jobs returned at a time by List operations, starting at PageToken
+	PageSize  int
+	PageToken string
+	// Labels are set on the job for Run operations
+	Labels map[string]string
+	// Selector selects the jobs by their labels for List operations, and for Stop, Signal and Delete operations
+	// on a group of jobs
+	Selector string
+	// Timeout limits how long a Wait operation waits
+	Timeout time.Duration
+	// Webhooks are the urls posted to once the job of a Run operation ends
+	Webhooks []string
 }
 
 func ParseCommand(args []string) (Option, error) {
@@ -83,28 +323,47 @@ func ParseCommand(args []string) (Option, error) {
 	}
 
 	if len(args) == 2 {
-		if args[1] != "--help" {
+		switch args[1] {
+		case "--help":
+			return Option{
+				Op: Help,
+			}, nil
+		case "list":
+			return parseList(nil)
+		default:
 			return Option{}, ErrInvalidCommand{fmt.Sprintf("invalid option: %s", args[1])}
 		}
-		return Option{
-			Op: Help,
-		}, nil
+	}
+
+	switch args[1] {
+	case "run":
+		return parseRun(args[2:])
+	case "cp":
+		return parseCopy(args[2:])
+	case "artifacts":
+		return parseArtifacts(args[2:])
+	case "output":
+		return parseOutput(args[2:])
+	case "secret":
+		return parseSecret(args[2:])
+	case "list":
+		return parseList(args[2:])
+	case "stop":
+		return parseSelectorOperation(Stop, args[2:], true, 0)
+	case "signal":
+		return parseSelectorOperation(Signal, args[2:], false, 1)
+	case "delete":
+		return parseSelectorOperation(Delete, args[2:], false, 0)
+	case "wait":
+		return parseWait(args[2:])
 	}
 
 	if len(args) == 3 {
 		switch args[1] {
-		case "run":
-			runArgs := splitArguments(args[2])
-			return Option{
-				Op:   Run,
-				Args: runArgs,
-			}, nil
 		case "status":
 			return validateOperation(Status, args[2])
-		case "output":
-			return validateOperation(Output, args[2])
-		case "stop":
-			return validateOperation(Stop, args[2])
+		case "purge":
+			return validateOperation(Purge, args[2])
 		default:
 			return Option{}, ErrInvalidCommand{fmt.Sprintf("invalid option: %s", args[1])}
 		}
@@ -112,6 +371,397 @@ func ParseCommand(args []string) (Option, error) {
 	return Option{}, NewErrInvalidCommand("invalid command")
 }
 
+// parseRun parses the arguments for a Run operation, which is either a command line or a local script
+// followed by its optional arguments
+func parseRun(args []string) (Option, error) {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	script := flags.String("script", "", "local script to upload and run")
+	interpreter := flags.String("interpreter", "", "interpreter used to run the script")
+	var artifacts []string
+	flags.Func("artifact", "glob for the files kept from the workspace", func(glob string) error {
+		artifacts = append(artifacts, glob)
+		return nil
+	})
+	var secretEnv map[string]string
+	flags.Func("secret", "environment variable set to a secret, as variable=secret", func(value string) error {
+		name, secret, ok := strings.Cut(value, "=")
+		if !ok || name == "" || secret == "" {
+			return fmt.Errorf("invalid secret %s, expected <variable>=<secret>", value)
+		}
+		if secretEnv == nil {
+			secretEnv = make(map[string]string)
+		}
+		secretEnv[name] = secret
+		return nil
+	})
+	var webhooks []string
+	flags.Func("webhook", "url posted to once the job ends", func(url string) error {
+		webhooks = append(webhooks, url)
+		return nil
+	})
+	var labels map[string]string
+	flags.Func("label", "label set on the job, as key=value", func(value string) error {
+		key, val, ok := strings.Cut(value, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("invalid label %s, expected <key>=<value>", value)
+		}
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+		labels[key] = val
+		return nil
+	})
+	maxOutput := flags.Int64("max-output", 0, "maximum output stored for the job")
+	policy := flags.String("output-policy", "", "what happens once the job gets to the output limit")
+	description := flags.String("description", "", "text describing the job")
+	name := flags.String("name", "", "unique name for the job")
+	if err := flags.Parse(args); err != nil {
+		return Option{}, NewErrInvalidCommand(err.Error())
+	}
+	if *maxOutput < 0 {
+		return Option{}, NewErrInvalidCommand("max output must not be negative")
+	}
+	if *policy != "" && !slices.Contains(OutputPolicies, *policy) {
+		return Option{}, NewErrInvalidCommand(fmt.Sprintf("invalid output policy: %s", *policy))
+	}
+	// an empty script would otherwise be taken as a command line
+	scriptSet := false
+	flags.Visit(func(f *flag.Flag) {
+		scriptSet = scriptSet || f.Name == "script"
+	})
+	if scriptSet && len(*script) == 0 {
+		return Option{}, NewErrInvalidCommand("--script requires a file")
+	}
+	rest := flags.Args()
+
+	if len(*script) == 0 {
+		if len(*interpreter) > 0 {
+			return Option{}, NewErrInvalidCommand("--interpreter requires --script")
+		}
+		if len(rest) != 1 {
+			return Option{}, NewErrInvalidCommand("invalid command")
+		}
+		return Option{
+			Op:           Run,
+			Args:         splitArguments(rest[0]),
+			Artifacts:    artifacts,
+			MaxOutput:    *maxOutput,
+			OutputPolicy: *policy,
+			SecretEnv:    secretEnv,
+			Description:  *description,
+			Name:         *name,
+			Labels:       labels,
+			Webhooks:     webhooks,
+		}, nil
+	}
+
+	if len(rest) > 1 {
+		return Option{}, NewErrInvalidCommand("invalid command")
+	}
+	var runArgs []string
+	if len(rest) == 1 {
+		runArgs = splitArguments(rest[0])
+	}
+	return Option{
+		Op:           Run,
+		Args:         runArgs,
+		Script:       *script,
+		Interpreter:  *interpreter,
+		Artifacts:    artifacts,
+		MaxOutput:    *maxOutput,
+		OutputPolicy: *policy,
+		SecretEnv:    secretEnv,
+		Description:  *description,
+		Name:         *name,
+		Labels:       labels,
+		Webhooks:     webhooks,
+	}, nil
+}
+
+// parseOutput parses the job id and the flags selecting which part of the output is printed for an Output operation
+func parseOutput(args []string) (Option, error) {
+	flags := flag.NewFlagSet("output", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	var follow bool
+	flags.BoolVar(&follow, "follow", false, "print new output until the job ends")
+	flags.BoolVar(&follow, "f", false, "print new output until the job ends")
+	tailLines := flags.Int64("tail", 0, "print only the last lines")
+	tailBytes := flags.Int64("tail-bytes", 0, "print only the last bytes")
+	timestamps := flags.Bool("timestamps", false, "prefix every line with the time it was captured")
+	format := flags.String("format", "", "output format, text or ndjson")
+	grep := flags.String("grep", "", "print only the lines matching the regular expression")
+	exclude := flags.String("exclude", "", "skip the lines matching the regular expression")
+	since := flags.String("since", "", "print only the lines captured since a time or a duration ago")
+	until := flags.String("until", "", "print only the lines captured until a time or a duration ago")
+	maxLines := flags.Int64("max-lines", 0, "print at most this number of lines")
+	lines := flags.Bool("lines", false, "prefix every line with its number")
+	if err := flags.Parse(args); err != nil {
+		return Option{}, NewErrInvalidCommand(err.Error())
+	}
+	for _, expr := range []string{*grep, *exclude} {
+		if _, err := regexp.Compile(expr); err != nil {
+			return Option{}, NewErrInvalidCommand(fmt.Sprintf("invalid regular expression: %s", expr))
+		}
+	}
+	sinceTime, err := parseTime(*since, time.Now())
+	if err != nil {
+		return Option{}, err
+	}
+	untilTime, err := parseTime(*until, time.Now())
+	if err != nil {
+		return Option{}, err
+	}
+	if *maxLines < 0 {
+		return Option{}, NewErrInvalidCommand("max lines must not be negative")
+	}
+	if *tailLines < 0 || *tailBytes < 0 {
+		return Option{}, NewErrInvalidCommand("tail must not be negative")
+	}
+	if *format != "" && *format != FormatText && *format != FormatNDJSON {
+		return Option{}, NewErrInvalidCommand(fmt.Sprintf("invalid format: %s", *format))
+	}
+	if flags.NArg() != 1 {
+		return Option{}, NewErrInvalidCommand("invalid command")
+	}
+	option, err := validateOperation(Output, flags.Arg(0))
+	if err != nil {
+		return Option{}, err
+	}
+	option.Follow = follow
+	option.TailLines = *tailLines
+	option.TailBytes = *tailBytes
+	option.Timestamps = *timestamps
+	option.Format = *format
+	option.Grep = *grep
+	option.Exclude = *exclude
+	option.Since = sinceTime
+	option.Until = untilTime
+	option.MaxLines = *maxLines
+	option.Lines = *lines
+	return option, nil
+}
+
+// parseWait parses the job id and the timeout for a Wait operation
+func parseWait(args []string) (Option, error) {
+	flags := flag.NewFlagSet("wait", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	timeout := flags.Duration("timeout", 0, "how long to wait for the job to end")
+	if err := flags.Parse(args); err != nil {
+		return Option{}, NewErrInvalidCommand(err.Error())
+	}
+	if *timeout < 0 {
+		return Option{}, NewErrInvalidCommand("timeout must not be negative")
+	}
+	if flags.NArg() != 1 {
+		return Option{}, NewErrInvalidCommand("invalid command")
+	}
+	option, err := validateOperation(Wait, flags.Arg(0))
+	if err != nil {
+		return Option{}, err
+	}
+	option.Timeout = *timeout
+	return option, nil
+}
+
+// parseList parses the filters, sorting and format for a List operation
+func parseList(args []string) (Option, error) {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	owner := flags.String("owner", "", "list only the jobs scheduled by this user")
+	var statuses []string
+	flags.Func("status", "list only the jobs with these statuses, separated by commas", func(value string) error {
+		for _, status := range strings.Split(value, ",") {
+			if !slices.Contains(JobStatuses, status) {
+				return fmt.Errorf("invalid status %s", status)
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	command := flags.String("command", "", "list only the jobs with this text in the command line")
+	since := flags.String("since", "", "list only the jobs submitted since a time or a duration ago")
+	until := flags.String("until", "", "list only the jobs submitted until a time or a duration ago")
+	sortBy := flags.String("sort", "", "field the jobs are sorted by")
+	ascending := flags.Bool("asc", false, "sort in ascending order")
+	pageSize := flags.Int("limit", 0, "most jobs listed")
+	pageToken := flags.String("page-token", "", "continue a previous list")
+	format := flags.String("format", "", "output format, table or json")
+	var selector string
+	flags.StringVar(&selector, "l", "", "list only the jobs matching the label selector")
+	flags.StringVar(&selector, "selector", "", "list only the jobs matching the label selector")
+	if err := flags.Parse(args); err != nil {
+		return Option{}, NewErrInvalidCommand(err.Error())
+	}
+	if flags.NArg() != 0 {
+		return Option{}, NewErrInvalidCommand("invalid command")
+	}
+	sinceTime, err := parseTime(*since, time.Now())
+	if err != nil {
+		return Option{}, err
+	}
+	untilTime, err := parseTime(*until, time.Now())
+	if err != nil {
+		return Option{}, err
+	}
+	if *sortBy != "" && !slices.Contains(ListSorts, *sortBy) {
+		return Option{}, NewErrInvalidCommand(fmt.Sprintf("invalid sort: %s", *sortBy))
+	}
+	if *pageSize < 0 {
+		return Option{}, NewErrInvalidCommand("limit must not be negative")
+	}
+	if *format != "" && *format != FormatTable && *format != FormatJSON {
+		return Option{}, NewErrInvalidCommand(fmt.Sprintf("invalid format: %s", *format))
+	}
+	return Option{
+		Op:        List,
+		Owner:     *owner,
+		Statuses:  statuses,
+		Command:   *command,
+		Since:     sinceTime,
+		Until:     untilTime,
+		SortBy:    *sortBy,
+		Ascending: *ascending,
+		PageSize:  *pageSize,
+		PageToken: *pageToken,
+		Format:    *format,
+		Selector:  selector,
+	}, nil
+}
+
+// parseSelectorOperation parses an operation on the jobs matching the label selector, informed by -l or --selector,
+// followed by nargs arguments. When byId is true, the operation can be applied to a single job instead,
+// informing only its id.
+func parseSelectorOperation(op Operation, args []string, byId bool, nargs int) (Option, error) {
+	flags := flag.NewFlagSet("selector", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	var selector string
+	flags.StringVar(&selector, "l", "", "label selector for the jobs")
+	flags.StringVar(&selector, "selector", "", "label selector for the jobs")
+	if err := flags.Parse(args); err != nil {
+		return Option{}, NewErrInvalidCommand(err.Error())
+	}
+	if selector == "" {
+		if byId && flags.NArg() == 1 {
+			return validateOperation(op, flags.Arg(0))
+		}
+		return Option{}, NewErrInvalidCommand("invalid command")
+	}
+	if flags.NArg() != nargs {
+		return Option{}, NewErrInvalidCommand("invalid command")
+	}
+	return Option{
+		Op:       op,
+		Args:     flags.Args(),
+		Selector: selector,
+	}, nil
+}
+
+// parseTime parses a time in RFC 3339 format, or a duration before now, like 10m. An empty value
+// returns the zero time.
+func parseTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, NewErrInvalidCommand(fmt.Sprintf("invalid time: %s", value))
+	}
+	return t, nil
+}
+
+// parseArtifacts parses the job id and the optional destination file for an Artifacts operation
+func parseArtifacts(args []string) (Option, error) {
+	if len(args) == 0 || len(args) > 2 {
+		return Option{}, NewErrInvalidCommand("invalid command")
+	}
+	option, err := validateOperation(Artifacts, args[0])
+	if err != nil {
+		return Option{}, err
+	}
+	dest := args[0] + ".tar.gz"
+	if len(args) == 2 {
+		dest = args[1]
+	}
+	option.Args = append(option.Args, dest)
+	return option, nil
+}
+
+// parseSecret parses the subcommand managing the secrets stored on the server and its arguments
+func parseSecret(args []string) (Option, error) {
+	if len(args) == 0 {
+		return Option{}, NewErrInvalidCommand("invalid command")
+	}
+	switch args[0] {
+	case "set":
+		flags := flag.NewFlagSet("secret set", flag.ContinueOnError)
+		flags.SetOutput(io.Discard)
+		var users []string
+		flags.Func("user", "email of a user allowed to use the secret", func(email string) error {
+			users = append(users, email)
+			return nil
+		})
+		if err := flags.Parse(args[1:]); err != nil {
+			return Option{}, NewErrInvalidCommand(err.Error())
+		}
+		if flags.NArg() != 1 {
+			return Option{}, NewErrInvalidCommand("invalid command")
+		}
+		return Option{
+			Op:    SecretSet,
+			Args:  flags.Args(),
+			Users: users,
+		}, nil
+	case "list":
+		if len(args) != 1 {
+			return Option{}, NewErrInvalidCommand("invalid command")
+		}
+		return Option{Op: SecretList}, nil
+	case "delete":
+		if len(args) != 2 {
+			return Option{}, NewErrInvalidCommand("invalid command")
+		}
+		return Option{
+			Op:   SecretDelete,
+			Args: args[1:],
+		}, nil
+	default:
+		return Option{}, NewErrInvalidCommand(fmt.Sprintf("invalid secret operation: %s", args[0]))
+	}
+}
+
+// parseCopy parses the source and destination for a Copy operation. Exactly one of them must be
+// a path on the server, prefixed by RemotePrefix
+func parseCopy(args []string) (Option, error) {
+	flags := flag.NewFlagSet("cp", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	resume := flags.Bool("resume", false, "resume a partial transfer")
+	if err := flags.Parse(args); err != nil {
+		return Option{}, NewErrInvalidCommand(err.Error())
+	}
+	rest := flags.Args()
+	if len(rest) != 2 {
+		return Option{}, NewErrInvalidCommand("invalid command")
+	}
+
+	srcRemote := strings.HasPrefix(rest[0], RemotePrefix)
+	dstRemote := strings.HasPrefix(rest[1], RemotePrefix)
+	if srcRemote == dstRemote {
+		return Option{}, NewErrInvalidCommand("exactly one of source or destination must be remote")
+	}
+	if rest[0] == RemotePrefix || rest[1] == RemotePrefix {
+		return Option{}, NewErrInvalidCommand("invalid remote path")
+	}
+	return Option{
+		Op:     Copy,
+		Args:   rest,
+		Resume: *resume,
+	}, nil
+}
+
 // splitArguments leverages the OS parsing on the input, which guarantees that all quotes are balanced.
 // it then constructs a stack to parse nested quotes and only return one argument per outer quote boundary, including any possible inner quotes.
 func splitArguments(cmd string) []string {
@@ -164,11 +814,14 @@ func splitArguments(cmd string) []string {
 	return args
 }
 
+// jobRefPattern matches the ways to refer to a job: its id, a prefix of it or the job name, which are
+// resolved by the server
+var jobRefPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,62}$`)
+
 // validateOperation returns an Option object with the informed operation if the id parameter is a
-// valid uuid, otherwise returns an error
+// job id, a prefix of it or a job name, otherwise returns an error
 func validateOperation(op Operation, id string) (Option, error) {
-	_, err := uuid.Parse(id)
-	if err != nil {
+	if !jobRefPattern.MatchString(id) {
 		return Option{}, NewErrInvalidCommand("invalid job id")
 	}
 	return Option{
@@ -3,6 +3,7 @@ package cli_test
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/mhsantos/rlcp/cmd/cli"
@@ -72,6 +73,51 @@ func TestArguments(t *testing.T) {
 				Args: []string{"sh", "-c", "echo 'my name is jonas'"},
 			},
 		},
+		{
+			name: "valid run script command",
+			args: []string{"rlcp", "run", "--script", "./fix.sh"},
+			expectedOption: cli.Option{
+				Op:     cli.Run,
+				Script: "./fix.sh",
+			},
+		},
+		{
+			name: "valid run script command with interpreter and arguments",
+			args: []string{"rlcp", "run", "--script", "./report.py", "--interpreter", "python3", "weekly 'last week'"},
+			expectedOption: cli.Option{
+				Op:          cli.Run,
+				Args:        []string{"weekly", "last week"},
+				Script:      "./report.py",
+				Interpreter: "python3",
+			},
+		},
+		{
+			name: "valid run command with artifacts",
+			args: []string{"rlcp", "run", "--artifact", "*.hprof", "--artifact", "reports/*.csv", "./export.sh"},
+			expectedOption: cli.Option{
+				Op:        cli.Run,
+				Args:      []string{"./export.sh"},
+				Artifacts: []string{"*.hprof", "reports/*.csv"},
+			},
+		},
+		{
+			name:           "invalid run command with interpreter and no script",
+			args:           []string{"rlcp", "run", "--interpreter", "python3", "pwd"},
+			expectedOption: cli.Option{},
+			expectedError:  cli.NewErrInvalidCommand("--interpreter requires --script"),
+		},
+		{
+			name:           "invalid run command with an empty script",
+			args:           []string{"rlcp", "run", "--script", "", "pwd"},
+			expectedOption: cli.Option{},
+			expectedError:  cli.NewErrInvalidCommand("--script requires a file"),
+		},
+		{
+			name:           "invalid run command with multiple arguments",
+			args:           []string{"rlcp", "run", "ls", "-la"},
+			expectedOption: cli.Option{},
+			expectedError:  cli.NewErrInvalidCommand("invalid command"),
+		},
 		{
 			name: "valid status command",
 			args: []string{"rlcp", "status", "af1f8215-bee7-455d-874a-55f0e3fb20b5"},
@@ -82,10 +128,26 @@ func TestArguments(t *testing.T) {
 		},
 		{
 			name:           "invalid status command argument",
-			args:           []string{"rlcp", "status", "invalid-uuid"},
+			args:           []string{"rlcp", "status", "../invalid-id"},
 			expectedOption: cli.Option{},
 			expectedError:  cli.NewErrInvalidCommand("invalid job id"),
 		},
+		{
+			name: "valid run command with output limit",
+			args: []string{"rlcp", "run", "--max-output", "1048576", "--output-policy", "head-tail", "./soak.sh"},
+			expectedOption: cli.Option{
+				Op:           cli.Run,
+				Args:         []string{"./soak.sh"},
+				MaxOutput:    1048576,
+				OutputPolicy: "head-tail",
+			},
+		},
+		{
+			name:           "invalid run command with unknown output policy",
+			args:           []string{"rlcp", "run", "--output-policy", "drop", "./soak.sh"},
+			expectedOption: cli.Option{},
+			expectedError:  cli.NewErrInvalidCommand("invalid output policy: drop"),
+		},
 		{
 			name: "valid output command",
 			args: []string{"rlcp", "output", "6c4bc197-b0e4-4ee3-a6be-b3b591ffad70"},
@@ -94,12 +156,268 @@ func TestArguments(t *testing.T) {
 				Args: []string{"6c4bc197-b0e4-4ee3-a6be-b3b591ffad70"},
 			},
 		},
+		{
+			name: "valid output command with follow",
+			args: []string{"rlcp", "output", "--follow", "6c4bc197-b0e4-4ee3-a6be-b3b591ffad70"},
+			expectedOption: cli.Option{
+				Op:     cli.Output,
+				Args:   []string{"6c4bc197-b0e4-4ee3-a6be-b3b591ffad70"},
+				Follow: true,
+			},
+		},
+		{
+			name: "valid output command with tail and follow",
+			args: []string{"rlcp", "output", "--tail", "100", "-f", "6c4bc197-b0e4-4ee3-a6be-b3b591ffad70"},
+			expectedOption: cli.Option{
+				Op:        cli.Output,
+				Args:      []string{"6c4bc197-b0e4-4ee3-a6be-b3b591ffad70"},
+				Follow:    true,
+				TailLines: 100,
+			},
+		},
+		{
+			name: "valid output command with tail bytes",
+			args: []string{"rlcp", "output", "--tail-bytes", "4096", "6c4bc197-b0e4-4ee3-a6be-b3b591ffad70"},
+			expectedOption: cli.Option{
+				Op:        cli.Output,
+				Args:      []string{"6c4bc197-b0e4-4ee3-a6be-b3b591ffad70"},
+				TailBytes: 4096,
+			},
+		},
+		{
+			name: "valid output command with timestamps",
+			args: []string{"rlcp", "output", "--timestamps", "6c4bc197-b0e4-4ee3-a6be-b3b591ffad70"},
+			expectedOption: cli.Option{
+				Op:         cli.Output,
+				Args:       []string{"6c4bc197-b0e4-4ee3-a6be-b3b591ffad70"},
+				Timestamps: true,
+			},
+		},
+		{
+			name: "valid output command with line numbers",
+			args: []string{"rlcp", "output", "--lines", "-f", "6c4bc197-b0e4-4ee3-a6be-b3b591ffad70"},
+			expectedOption: cli.Option{
+				Op:     cli.Output,
+				Args:   []string{"6c4bc197-b0e4-4ee3-a6be-b3b591ffad70"},
+				Follow: true,
+				Lines:  true,
+			},
+		},
+		{
+			name: "valid output command with ndjson format",
+			args: []string{"rlcp", "output", "--format", "ndjson", "6c4bc197-b0e4-4ee3-a6be-b3b591ffad70"},
+			expectedOption: cli.Option{
+				Op:     cli.Output,
+				Args:   []string{"6c4bc197-b0e4-4ee3-a6be-b3b591ffad70"},
+				Format: cli.FormatNDJSON,
+			},
+		},
+		{
+			name:           "invalid output command with unknown format",
+			args:           []string{"rlcp", "output", "--format", "xml", "6c4bc197-b0e4-4ee3-a6be-b3b591ffad70"},
+			expectedOption: cli.Option{},
+			expectedError:  cli.NewErrInvalidCommand("invalid format: xml"),
+		},
+		{
+			name: "valid output command with filters",
+			args: []string{"rlcp", "output", "--grep", "ERROR", "--exclude", "retry", "--since", "2024-05-01T10:00:00Z",
+				"--max-lines", "50", "6c4bc197-b0e4-4ee3-a6be-b3b591ffad70"},
+			expectedOption: cli.Option{
+				Op:       cli.Output,
+				Args:     []string{"6c4bc197-b0e4-4ee3-a6be-b3b591ffad70"},
+				Grep:     "ERROR",
+				Exclude:  "retry",
+				Since:    time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+				MaxLines: 50,
+			},
+		},
+		{
+			name:           "invalid output command with invalid regular expression",
+			args:           []string{"rlcp", "output", "--grep", "ERROR(", "6c4bc197-b0e4-4ee3-a6be-b3b591ffad70"},
+			expectedOption: cli.Option{},
+			expectedError:  cli.NewErrInvalidCommand("invalid regular expression: ERROR("),
+		},
+		{
+			name:           "invalid output command with invalid time",
+			args:           []string{"rlcp", "output", "--since", "yesterday", "6c4bc197-b0e4-4ee3-a6be-b3b591ffad70"},
+			expectedOption: cli.Option{},
+			expectedError:  cli.NewErrInvalidCommand("invalid time: yesterday"),
+		},
+		{
+			name:           "invalid output command with negative tail",
+			args:           []string{"rlcp", "output", "--tail", "-1", "6c4bc197-b0e4-4ee3-a6be-b3b591ffad70"},
+			expectedOption: cli.Option{},
+			expectedError:  cli.NewErrInvalidCommand("tail must not be negative"),
+		},
 		{
 			name:           "invalid output command argument",
-			args:           []string{"rlcp", "output", "invalid-uuid"},
+			args:           []string{"rlcp", "output", "../invalid-id"},
 			expectedOption: cli.Option{},
 			expectedError:  cli.NewErrInvalidCommand("invalid job id"),
 		},
+		{
+			name: "valid run command with secrets",
+			args: []string{"rlcp", "run", "--secret", "PGPASSWORD=db-password", "--secret", "TOKEN=api-token", "./deploy.sh"},
+			expectedOption: cli.Option{
+				Op:        cli.Run,
+				Args:      []string{"./deploy.sh"},
+				SecretEnv: map[string]string{"PGPASSWORD": "db-password", "TOKEN": "api-token"},
+			},
+		},
+		{
+			name: "valid run command with description",
+			args: []string{"rlcp", "run", "--description", "nightly export", "./export.sh --all"},
+			expectedOption: cli.Option{
+				Op:          cli.Run,
+				Args:        []string{"./export.sh", "--all"},
+				Description: "nightly export",
+			},
+		},
+		{
+			name: "valid run command with name",
+			args: []string{"rlcp", "run", "--name", "nightly-reindex", "./reindex.sh"},
+			expectedOption: cli.Option{
+				Op:   cli.Run,
+				Args: []string{"./reindex.sh"},
+				Name: "nightly-reindex",
+			},
+		},
+		{
+			name: "valid run command with webhooks",
+			args: []string{"rlcp", "run", "--webhook", "https://ci.example.com/hook", "--webhook", "http://localhost:9000/", "./build.sh"},
+			expectedOption: cli.Option{
+				Op:       cli.Run,
+				Args:     []string{"./build.sh"},
+				Webhooks: []string{"https://ci.example.com/hook", "http://localhost:9000/"},
+			},
+		},
+		{
+			name: "valid status command with job name",
+			args: []string{"rlcp", "status", "nightly-reindex"},
+			expectedOption: cli.Option{
+				Op:   cli.Status,
+				Args: []string{"nightly-reindex"},
+			},
+		},
+		{
+			name: "valid wait command with timeout",
+			args: []string{"rlcp", "wait", "--timeout", "90s", "nightly-reindex"},
+			expectedOption: cli.Option{
+				Op:      cli.Wait,
+				Args:    []string{"nightly-reindex"},
+				Timeout: 90 * time.Second,
+			},
+		},
+		{
+			name:           "invalid wait command without job id",
+			args:           []string{"rlcp", "wait", "--timeout", "90s"},
+			expectedOption: cli.Option{},
+			expectedError:  cli.NewErrInvalidCommand("invalid command"),
+		},
+		{
+			name: "valid output command with id prefix",
+			args: []string{"rlcp", "output", "af1f82"},
+			expectedOption: cli.Option{
+				Op:   cli.Output,
+				Args: []string{"af1f82"},
+			},
+		},
+		{
+			name:           "valid list command",
+			args:           []string{"rlcp", "list"},
+			expectedOption: cli.Option{Op: cli.List},
+		},
+		{
+			name: "valid list command with filters",
+			args: []string{"rlcp", "list", "--owner", "marcel+client@email.com", "--status", "running,lost", "--command", "backup",
+				"--since", "2024-05-01T10:00:00Z", "--sort", "ended", "--asc", "--limit", "10", "--format", "json"},
+			expectedOption: cli.Option{
+				Op:        cli.List,
+				Owner:     "marcel+client@email.com",
+				Statuses:  []string{"running", "lost"},
+				Command:   "backup",
+				Since:     time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+				SortBy:    "ended",
+				Ascending: true,
+				PageSize:  10,
+				Format:    "json",
+			},
+		},
+		{
+			name: "valid run command with labels",
+			args: []string{"rlcp", "run", "--label", "deploy=42", "--label", "role=migrate", "./migrate.sh"},
+			expectedOption: cli.Option{
+				Op:     cli.Run,
+				Args:   []string{"./migrate.sh"},
+				Labels: map[string]string{"deploy": "42", "role": "migrate"},
+			},
+		},
+		{
+			name: "valid stop command with selector",
+			args: []string{"rlcp", "stop", "-l", "deploy=42,role=migrate"},
+			expectedOption: cli.Option{
+				Op:       cli.Stop,
+				Args:     []string{},
+				Selector: "deploy=42,role=migrate",
+			},
+		},
+		{
+			name: "valid signal command",
+			args: []string{"rlcp", "signal", "--selector", "deploy=42", "HUP"},
+			expectedOption: cli.Option{
+				Op:       cli.Signal,
+				Args:     []string{"HUP"},
+				Selector: "deploy=42",
+			},
+		},
+		{
+			name:           "invalid signal command without selector",
+			args:           []string{"rlcp", "signal", "HUP"},
+			expectedOption: cli.Option{},
+			expectedError:  cli.NewErrInvalidCommand("invalid command"),
+		},
+		{
+			name:           "invalid list command status",
+			args:           []string{"rlcp", "list", "--status", "running,done"},
+			expectedOption: cli.Option{},
+			expectedError:  cli.NewErrInvalidCommand("invalid value \"running,done\" for flag -status: invalid status done"),
+		},
+		{
+			name:           "invalid run command with secret missing the variable",
+			args:           []string{"rlcp", "run", "--secret", "db-password", "./deploy.sh"},
+			expectedOption: cli.Option{},
+			expectedError:  cli.NewErrInvalidCommand("invalid value \"db-password\" for flag -secret: invalid secret db-password, expected <variable>=<secret>"),
+		},
+		{
+			name: "valid secret set command",
+			args: []string{"rlcp", "secret", "set", "--user", "marcel+client@email.com", "db-password"},
+			expectedOption: cli.Option{
+				Op:    cli.SecretSet,
+				Args:  []string{"db-password"},
+				Users: []string{"marcel+client@email.com"},
+			},
+		},
+		{
+			name: "valid secret list command",
+			args: []string{"rlcp", "secret", "list"},
+			expectedOption: cli.Option{
+				Op: cli.SecretList,
+			},
+		},
+		{
+			name: "valid secret delete command",
+			args: []string{"rlcp", "secret", "delete", "db-password"},
+			expectedOption: cli.Option{
+				Op:   cli.SecretDelete,
+				Args: []string{"db-password"},
+			},
+		},
+		{
+			name:           "invalid secret operation",
+			args:           []string{"rlcp", "secret", "get", "db-password"},
+			expectedOption: cli.Option{},
+			expectedError:  cli.NewErrInvalidCommand("invalid secret operation: get"),
+		},
 		{
 			name: "valid stop command",
 			args: []string{"rlcp", "stop", "cc430a1e-ab90-4cc0-b3b5-0ed22303b99a"},
@@ -108,12 +426,65 @@ func TestArguments(t *testing.T) {
 				Args: []string{"cc430a1e-ab90-4cc0-b3b5-0ed22303b99a"},
 			},
 		},
+		{
+			name: "valid purge command",
+			args: []string{"rlcp", "purge", "cc430a1e-ab90-4cc0-b3b5-0ed22303b99a"},
+			expectedOption: cli.Option{
+				Op:   cli.Purge,
+				Args: []string{"cc430a1e-ab90-4cc0-b3b5-0ed22303b99a"},
+			},
+		},
 		{
 			name:           "invalid stop command argument",
-			args:           []string{"rlcp", "stop", "invalid-uuid"},
+			args:           []string{"rlcp", "stop", "../invalid-id"},
 			expectedOption: cli.Option{},
 			expectedError:  cli.NewErrInvalidCommand("invalid job id"),
 		},
+		{
+			name: "valid artifacts command",
+			args: []string{"rlcp", "artifacts", "cc430a1e-ab90-4cc0-b3b5-0ed22303b99a"},
+			expectedOption: cli.Option{
+				Op:   cli.Artifacts,
+				Args: []string{"cc430a1e-ab90-4cc0-b3b5-0ed22303b99a", "cc430a1e-ab90-4cc0-b3b5-0ed22303b99a.tar.gz"},
+			},
+		},
+		{
+			name: "valid artifacts command with destination",
+			args: []string{"rlcp", "artifacts", "cc430a1e-ab90-4cc0-b3b5-0ed22303b99a", "reports.tar.gz"},
+			expectedOption: cli.Option{
+				Op:   cli.Artifacts,
+				Args: []string{"cc430a1e-ab90-4cc0-b3b5-0ed22303b99a", "reports.tar.gz"},
+			},
+		},
+		{
+			name: "valid upload command",
+			args: []string{"rlcp", "cp", "./input.csv", "remote:/tmp/input.csv"},
+			expectedOption: cli.Option{
+				Op:   cli.Copy,
+				Args: []string{"./input.csv", "remote:/tmp/input.csv"},
+			},
+		},
+		{
+			name: "valid resumed download command",
+			args: []string{"rlcp", "cp", "--resume", "remote:heap.hprof", "out.bin"},
+			expectedOption: cli.Option{
+				Op:     cli.Copy,
+				Args:   []string{"remote:heap.hprof", "out.bin"},
+				Resume: true,
+			},
+		},
+		{
+			name:           "invalid copy command between local files",
+			args:           []string{"rlcp", "cp", "a.txt", "b.txt"},
+			expectedOption: cli.Option{},
+			expectedError:  cli.NewErrInvalidCommand("exactly one of source or destination must be remote"),
+		},
+		{
+			name:           "invalid copy command with empty remote path",
+			args:           []string{"rlcp", "cp", "a.txt", "remote:"},
+			expectedOption: cli.Option{},
+			expectedError:  cli.NewErrInvalidCommand("invalid remote path"),
+		},
 	}
 
 	for _, tc := range tcs {
@@ -10,6 +10,7 @@ import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -22,6 +23,107 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// What happens once the output from a job gets to its limit: STOP stops the job, RING drops the oldest
+// output and HEAD_TAIL keeps the start and the end of the output, dropping the middle.
+// DEFAULT uses the server policy on requests, and means the output didn't get to the limit on job details.
+type OutputPolicy int32
+
+const (
+	OutputPolicy_DEFAULT   OutputPolicy = 0
+	OutputPolicy_STOP      OutputPolicy = 1
+	OutputPolicy_RING      OutputPolicy = 2
+	OutputPolicy_HEAD_TAIL OutputPolicy = 3
+)
+
+// Enum value maps for OutputPolicy.
+var (
+	OutputPolicy_name = map[int32]string{
+		0: "DEFAULT",
+		1: "STOP",
+		2: "RING",
+		3: "HEAD_TAIL",
+	}
+	OutputPolicy_value = map[string]int32{
+		"DEFAULT":   0,
+		"STOP":      1,
+		"RING":      2,
+		"HEAD_TAIL": 3,
+	}
+)
+
+func (x OutputPolicy) Enum() *OutputPolicy {
+	p := new(OutputPolicy)
+	*p = x
+	return p
+}
+
+func (x OutputPolicy) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (OutputPolicy) Descriptor() protoreflect.EnumDescriptor {
+	return file_pb_remote_exec_proto_enumTypes[0].Descriptor()
+}
+
+func (OutputPolicy) Type() protoreflect.EnumType {
+	return &file_pb_remote_exec_proto_enumTypes[0]
+}
+
+func (x OutputPolicy) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use OutputPolicy.Descriptor instead.
+func (OutputPolicy) EnumDescriptor() ([]byte, []int) {
+	return file_pb_remote_exec_proto_rawDescGZIP(), []int{0}
+}
+
+type GetRequest_Mode int32
+
+const (
+	GetRequest_FOLLOW   GetRequest_Mode = 0
+	GetRequest_SNAPSHOT GetRequest_Mode = 1
+)
+
+// Enum value maps for GetRequest_Mode.
+var (
+	GetRequest_Mode_name = map[int32]string{
+		0: "FOLLOW",
+		1: "SNAPSHOT",
+	}
+	GetRequest_Mode_value = map[string]int32{
+		"FOLLOW":   0,
+		"SNAPSHOT": 1,
+	}
+)
+
+func (x GetRequest_Mode) Enum() *GetRequest_Mode {
+	p := new(GetRequest_Mode)
+	*p = x
+	return p
+}
+
+func (x GetRequest_Mode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (GetRequest_Mode) Descriptor() protoreflect.EnumDescriptor {
+	return file_pb_remote_exec_proto_enumTypes[1].Descriptor()
+}
+
+func (GetRequest_Mode) Type() protoreflect.EnumType {
+	return &file_pb_remote_exec_proto_enumTypes[1]
+}
+
+func (x GetRequest_Mode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use GetRequest_Mode.Descriptor instead.
+func (GetRequest_Mode) EnumDescriptor() ([]byte, []int) {
+	return file_pb_remote_exec_proto_rawDescGZIP(), []int{1, 0}
+}
+
 type JobDetails_Status int32
 
 const (
@@ -29,6 +131,8 @@ const (
 	JobDetails_COMPLETED JobDetails_Status = 1
 	JobDetails_ERRORED   JobDetails_Status = 2
 	JobDetails_STOPPED   JobDetails_Status = 3
+	// LOST jobs were running when the server stopped, status_message explains what happened to them
+	JobDetails_LOST JobDetails_Status = 4
 )
 
 // Enum value maps for JobDetails_Status.
@@ -38,12 +142,14 @@ var (
 		1: "COMPLETED",
 		2: "ERRORED",
 		3: "STOPPED",
+		4: "LOST",
 	}
 	JobDetails_Status_value = map[string]int32{
 		"RUNNING":   0,
 		"COMPLETED": 1,
 		"ERRORED":   2,
 		"STOPPED":   3,
+		"LOST":      4,
 	}
 )
 
@@ -58,11 +164,11 @@ func (x JobDetails_Status) String() string {
 }
 
 func (JobDetails_Status) Descriptor() protoreflect.EnumDescriptor {
-	return file_pb_remote_exec_proto_enumTypes[0].Descriptor()
+	return file_pb_remote_exec_proto_enumTypes[2].Descriptor()
 }
 
 func (JobDetails_Status) Type() protoreflect.EnumType {
-	return &file_pb_remote_exec_proto_enumTypes[0]
+	return &file_pb_remote_exec_proto_enumTypes[2]
 }
 
 func (x JobDetails_Status) Number() protoreflect.EnumNumber {
@@ -74,11 +180,187 @@ func (JobDetails_Status) EnumDescriptor() ([]byte, []int) {
 	return file_pb_remote_exec_proto_rawDescGZIP(), []int{2, 0}
 }
 
-// The request message containing the command
+type JobOutput_Stream int32
+
+const (
+	JobOutput_STDOUT JobOutput_Stream = 0
+	JobOutput_STDERR JobOutput_Stream = 1
+)
+
+// Enum value maps for JobOutput_Stream.
+var (
+	JobOutput_Stream_name = map[int32]string{
+		0: "STDOUT",
+		1: "STDERR",
+	}
+	JobOutput_Stream_value = map[string]int32{
+		"STDOUT": 0,
+		"STDERR": 1,
+	}
+)
+
+func (x JobOutput_Stream) Enum() *JobOutput_Stream {
+	p := new(JobOutput_Stream)
+	*p = x
+	return p
+}
+
+func (x JobOutput_Stream) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (JobOutput_Stream) Descriptor() protoreflect.EnumDescriptor {
+	return file_pb_remote_exec_proto_enumTypes[3].Descriptor()
+}
+
+func (JobOutput_Stream) Type() protoreflect.EnumType {
+	return &file_pb_remote_exec_proto_enumTypes[3]
+}
+
+func (x JobOutput_Stream) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use JobOutput_Stream.Descriptor instead.
+func (JobOutput_Stream) EnumDescriptor() ([]byte, []int) {
+	return file_pb_remote_exec_proto_rawDescGZIP(), []int{4, 0}
+}
+
+type ListJobsRequest_SortBy int32
+
+const (
+	ListJobsRequest_SUBMIT_TIME ListJobsRequest_SortBy = 0
+	ListJobsRequest_START_TIME  ListJobsRequest_SortBy = 1
+	ListJobsRequest_END_TIME    ListJobsRequest_SortBy = 2
+	ListJobsRequest_OWNER       ListJobsRequest_SortBy = 3
+	ListJobsRequest_COMMAND     ListJobsRequest_SortBy = 4
+)
+
+// Enum value maps for ListJobsRequest_SortBy.
+var (
+	ListJobsRequest_SortBy_name = map[int32]string{
+		0: "SUBMIT_TIME",
+		1: "START_TIME",
+		2: "END_TIME",
+		3: "OWNER",
+		4: "COMMAND",
+	}
+	ListJobsRequest_SortBy_value = map[string]int32{
+		"SUBMIT_TIME": 0,
+		"START_TIME":  1,
+		"END_TIME":    2,
+		"OWNER":       3,
+		"COMMAND":     4,
+	}
+)
+
+func (x ListJobsRequest_SortBy) Enum() *ListJobsRequest_SortBy {
+	p := new(ListJobsRequest_SortBy)
+	*p = x
+	return p
+}
+
+func (x ListJobsRequest_SortBy) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ListJobsRequest_SortBy) Descriptor() protoreflect.EnumDescriptor {
+	return file_pb_remote_exec_proto_enumTypes[4].Descriptor()
+}
+
+func (ListJobsRequest_SortBy) Type() protoreflect.EnumType {
+	return &file_pb_remote_exec_proto_enumTypes[4]
+}
+
+func (x ListJobsRequest_SortBy) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ListJobsRequest_SortBy.Descriptor instead.
+func (ListJobsRequest_SortBy) EnumDescriptor() ([]byte, []int) {
+	return file_pb_remote_exec_proto_rawDescGZIP(), []int{14, 0}
+}
+
+type JobEvent_Type int32
+
+const (
+	JobEvent_SUBMITTED        JobEvent_Type = 0
+	JobEvent_STARTED          JobEvent_Type = 1
+	JobEvent_OUTPUT_THRESHOLD JobEvent_Type = 2
+	JobEvent_EXITED           JobEvent_Type = 3
+	JobEvent_STOPPED          JobEvent_Type = 4
+)
+
+// Enum value maps for JobEvent_Type.
+var (
+	JobEvent_Type_name = map[int32]string{
+		0: "SUBMITTED",
+		1: "STARTED",
+		2: "OUTPUT_THRESHOLD",
+		3: "EXITED",
+		4: "STOPPED",
+	}
+	JobEvent_Type_value = map[string]int32{
+		"SUBMITTED":        0,
+		"STARTED":          1,
+		"OUTPUT_THRESHOLD": 2,
+		"EXITED":           3,
+		"STOPPED":          4,
+	}
+)
+
+func (x JobEvent_Type) Enum() *JobEvent_Type {
+	p := new(JobEvent_Type)
+	*p = x
+	return p
+}
+
+func (x JobEvent_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (JobEvent_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_pb_remote_exec_proto_enumTypes[5].Descriptor()
+}
+
+func (JobEvent_Type) Type() protoreflect.EnumType {
+	return &file_pb_remote_exec_proto_enumTypes[5]
+}
+
+func (x JobEvent_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use JobEvent_Type.Descriptor instead.
+func (JobEvent_Type) EnumDescriptor() ([]byte, []int) {
+	return file_pb_remote_exec_proto_rawDescGZIP(), []int{20, 0}
+}
+
+// The request message containing the command.
+// When script is set, its body is written to a temporary file on the server and run
+// with the interpreter, instead of running command.
+// Files in the job workspace matching the artifacts globs are kept after the job exits.
+// max_output_bytes limits the output stored for the job, up to the server limit, applying output_policy.
+// secret_env maps the environment variables set for the job to the names of the secrets in the server
+// secret store holding their values, which are masked in the job output.
 type CmdRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Command       string                 `protobuf:"bytes,1,opt,name=command,proto3" json:"command,omitempty"`
-	Arguments     []string               `protobuf:"bytes,2,rep,name=arguments,proto3" json:"arguments,omitempty"`
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Command        string                 `protobuf:"bytes,1,opt,name=command,proto3" json:"command,omitempty"`
+	Arguments      []string               `protobuf:"bytes,2,rep,name=arguments,proto3" json:"arguments,omitempty"`
+	Script         []byte                 `protobuf:"bytes,3,opt,name=script,proto3" json:"script,omitempty"`
+	Interpreter    string                 `protobuf:"bytes,4,opt,name=interpreter,proto3" json:"interpreter,omitempty"`
+	Artifacts      []string               `protobuf:"bytes,5,rep,name=artifacts,proto3" json:"artifacts,omitempty"`
+	MaxOutputBytes int64                  `protobuf:"varint,6,opt,name=max_output_bytes,json=maxOutputBytes,proto3" json:"max_output_bytes,omitempty"`
+	OutputPolicy   OutputPolicy           `protobuf:"varint,7,opt,name=output_policy,json=outputPolicy,proto3,enum=OutputPolicy" json:"output_policy,omitempty"`
+	SecretEnv      map[string]string      `protobuf:"bytes,8,rep,name=secret_env,json=secretEnv,proto3" json:"secret_env,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// description is a free-form text describing the job, shown with its status
+	Description string `protobuf:"bytes,9,opt,name=description,proto3" json:"description,omitempty"`
+	// labels are key=value pairs stored on the job, to select groups of jobs
+	Labels map[string]string `protobuf:"bytes,10,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// name is a unique name for the job, which can be used instead of its id
+	Name string `protobuf:"bytes,11,opt,name=name,proto3" json:"name,omitempty"`
+	// webhooks are the urls posted to once the job ends, besides the ones configured on the server
+	Webhooks      []string `protobuf:"bytes,12,rep,name=webhooks,proto3" json:"webhooks,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -127,10 +409,100 @@ func (x *CmdRequest) GetArguments() []string {
 	return nil
 }
 
-// The request for a Job status
+func (x *CmdRequest) GetScript() []byte {
+	if x != nil {
+		return x.Script
+	}
+	return nil
+}
+
+func (x *CmdRequest) GetInterpreter() string {
+	if x != nil {
+		return x.Interpreter
+	}
+	return ""
+}
+
+func (x *CmdRequest) GetArtifacts() []string {
+	if x != nil {
+		return x.Artifacts
+	}
+	return nil
+}
+
+func (x *CmdRequest) GetMaxOutputBytes() int64 {
+	if x != nil {
+		return x.MaxOutputBytes
+	}
+	return 0
+}
+
+func (x *CmdRequest) GetOutputPolicy() OutputPolicy {
+	if x != nil {
+		return x.OutputPolicy
+	}
+	return OutputPolicy_DEFAULT
+}
+
+func (x *CmdRequest) GetSecretEnv() map[string]string {
+	if x != nil {
+		return x.SecretEnv
+	}
+	return nil
+}
+
+func (x *CmdRequest) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *CmdRequest) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *CmdRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CmdRequest) GetWebhooks() []string {
+	if x != nil {
+		return x.Webhooks
+	}
+	return nil
+}
+
+// The request for a Job status or output. For output requests, offset is the position in the output
+// from where the stream starts, allowing clients to resume a previous stream.
+// In FOLLOW mode the stream ends when the job ends, and in SNAPSHOT mode it ends after the output
+// available at the time of the request is sent. tail_lines and tail_bytes start the stream at the
+// last lines or bytes of the output instead.
+// job_id, here and in the other requests for a job, also accepts the job name or a prefix of its id
+// matching a single job.
+// The filters only send the lines matching the include regular expression, not matching the exclude one,
+// and captured between since and until, up to max_lines. When filtering, every message holds one line.
+// With lines, every message holds a whole line, or the part of a line available once it waits too long
+// for the rest of it, followed later by the rest of it.
 type GetRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	Offset        int64                  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	Mode          GetRequest_Mode        `protobuf:"varint,3,opt,name=mode,proto3,enum=GetRequest_Mode" json:"mode,omitempty"`
+	TailLines     int64                  `protobuf:"varint,4,opt,name=tail_lines,json=tailLines,proto3" json:"tail_lines,omitempty"`
+	TailBytes     int64                  `protobuf:"varint,5,opt,name=tail_bytes,json=tailBytes,proto3" json:"tail_bytes,omitempty"`
+	Include       string                 `protobuf:"bytes,6,opt,name=include,proto3" json:"include,omitempty"`
+	Exclude       string                 `protobuf:"bytes,7,opt,name=exclude,proto3" json:"exclude,omitempty"`
+	Since         *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=since,proto3" json:"since,omitempty"`
+	Until         *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=until,proto3" json:"until,omitempty"`
+	MaxLines      int64                  `protobuf:"varint,10,opt,name=max_lines,json=maxLines,proto3" json:"max_lines,omitempty"`
+	Lines         bool                   `protobuf:"varint,11,opt,name=lines,proto3" json:"lines,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -172,13 +544,113 @@ func (x *GetRequest) GetJobId() string {
 	return ""
 }
 
+func (x *GetRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *GetRequest) GetMode() GetRequest_Mode {
+	if x != nil {
+		return x.Mode
+	}
+	return GetRequest_FOLLOW
+}
+
+func (x *GetRequest) GetTailLines() int64 {
+	if x != nil {
+		return x.TailLines
+	}
+	return 0
+}
+
+func (x *GetRequest) GetTailBytes() int64 {
+	if x != nil {
+		return x.TailBytes
+	}
+	return 0
+}
+
+func (x *GetRequest) GetInclude() string {
+	if x != nil {
+		return x.Include
+	}
+	return ""
+}
+
+func (x *GetRequest) GetExclude() string {
+	if x != nil {
+		return x.Exclude
+	}
+	return ""
+}
+
+func (x *GetRequest) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+func (x *GetRequest) GetUntil() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Until
+	}
+	return nil
+}
+
+func (x *GetRequest) GetMaxLines() int64 {
+	if x != nil {
+		return x.MaxLines
+	}
+	return 0
+}
+
+func (x *GetRequest) GetLines() bool {
+	if x != nil {
+		return x.Lines
+	}
+	return false
+}
+
 // The status for a Get Job
 type JobDetails struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
-	Status        JobDetails_Status      `protobuf:"varint,2,opt,name=status,proto3,enum=JobDetails_Status" json:"status,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	JobId     string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	Status    JobDetails_Status      `protobuf:"varint,2,opt,name=status,proto3,enum=JobDetails_Status" json:"status,omitempty"`
+	Artifacts []string               `protobuf:"bytes,3,rep,name=artifacts,proto3" json:"artifacts,omitempty"`
+	// output_bytes is the size of the output, and stored_bytes the space used to store it, after compression
+	OutputBytes int64 `protobuf:"varint,4,opt,name=output_bytes,json=outputBytes,proto3" json:"output_bytes,omitempty"`
+	StoredBytes int64 `protobuf:"varint,5,opt,name=stored_bytes,json=storedBytes,proto3" json:"stored_bytes,omitempty"`
+	// truncation is the policy applied once the output got to its limit, and dropped_bytes the output dropped
+	Truncation   OutputPolicy `protobuf:"varint,6,opt,name=truncation,proto3,enum=OutputPolicy" json:"truncation,omitempty"`
+	DroppedBytes int64        `protobuf:"varint,7,opt,name=dropped_bytes,json=droppedBytes,proto3" json:"dropped_bytes,omitempty"`
+	// redactions is the number of secrets masked in the output
+	Redactions    int64  `protobuf:"varint,8,opt,name=redactions,proto3" json:"redactions,omitempty"`
+	StatusMessage string `protobuf:"bytes,9,opt,name=status_message,json=statusMessage,proto3" json:"status_message,omitempty"`
+	// owner is the email of the user who scheduled the job. command and arguments are the command line run by
+	// the job, and for scripts, command is empty and interpreter is the program running the script.
+	Owner       string   `protobuf:"bytes,10,opt,name=owner,proto3" json:"owner,omitempty"`
+	Command     string   `protobuf:"bytes,11,opt,name=command,proto3" json:"command,omitempty"`
+	Arguments   []string `protobuf:"bytes,12,rep,name=arguments,proto3" json:"arguments,omitempty"`
+	Interpreter string   `protobuf:"bytes,13,opt,name=interpreter,proto3" json:"interpreter,omitempty"`
+	Description string   `protobuf:"bytes,14,opt,name=description,proto3" json:"description,omitempty"`
+	// submit_time is when the job was scheduled, start_time when its process started and end_time when it exited
+	SubmitTime *timestamppb.Timestamp `protobuf:"bytes,15,opt,name=submit_time,json=submitTime,proto3" json:"submit_time,omitempty"`
+	StartTime  *timestamppb.Timestamp `protobuf:"bytes,16,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	EndTime    *timestamppb.Timestamp `protobuf:"bytes,17,opt,name=end_time,json=endTime,proto3" json:"end_time,omitempty"`
+	// host is the server running the job, and pid the process id of the command on it
+	Host   string            `protobuf:"bytes,18,opt,name=host,proto3" json:"host,omitempty"`
+	Pid    int32             `protobuf:"varint,19,opt,name=pid,proto3" json:"pid,omitempty"`
+	Labels map[string]string `protobuf:"bytes,20,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Name   string            `protobuf:"bytes,21,opt,name=name,proto3" json:"name,omitempty"`
+	// exit_code is the exit status of the command, or 128 plus the signal number when it was killed by a signal.
+	// It's -1 while the job is running, and when the exit status is unknown.
+	ExitCode          int32              `protobuf:"varint,22,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
+	WebhookDeliveries []*WebhookDelivery `protobuf:"bytes,23,rep,name=webhook_deliveries,json=webhookDeliveries,proto3" json:"webhook_deliveries,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
 }
 
 func (x *JobDetails) Reset() {
@@ -225,74 +697,1088 @@ func (x *JobDetails) GetStatus() JobDetails_Status {
 	return JobDetails_RUNNING
 }
 
-// The response for a Get Job, with the combined output from stdout and stderr
-type JobOutput struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Output        []byte                 `protobuf:"bytes,1,opt,name=output,proto3" json:"output,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *JobDetails) GetArtifacts() []string {
+	if x != nil {
+		return x.Artifacts
+	}
+	return nil
 }
 
-func (x *JobOutput) Reset() {
-	*x = JobOutput{}
-	mi := &file_pb_remote_exec_proto_msgTypes[3]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+func (x *JobDetails) GetOutputBytes() int64 {
+	if x != nil {
+		return x.OutputBytes
+	}
+	return 0
 }
 
-func (x *JobOutput) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *JobDetails) GetStoredBytes() int64 {
+	if x != nil {
+		return x.StoredBytes
+	}
+	return 0
 }
 
-func (*JobOutput) ProtoMessage() {}
-
-func (x *JobOutput) ProtoReflect() protoreflect.Message {
-	mi := &file_pb_remote_exec_proto_msgTypes[3]
+func (x *JobDetails) GetTruncation() OutputPolicy {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+		return x.Truncation
 	}
-	return mi.MessageOf(x)
+	return OutputPolicy_DEFAULT
 }
 
-// Deprecated: Use JobOutput.ProtoReflect.Descriptor instead.
-func (*JobOutput) Descriptor() ([]byte, []int) {
+func (x *JobDetails) GetDroppedBytes() int64 {
+	if x != nil {
+		return x.DroppedBytes
+	}
+	return 0
+}
+
+func (x *JobDetails) GetRedactions() int64 {
+	if x != nil {
+		return x.Redactions
+	}
+	return 0
+}
+
+func (x *JobDetails) GetStatusMessage() string {
+	if x != nil {
+		return x.StatusMessage
+	}
+	return ""
+}
+
+func (x *JobDetails) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *JobDetails) GetCommand() string {
+	if x != nil {
+		return x.Command
+	}
+	return ""
+}
+
+func (x *JobDetails) GetArguments() []string {
+	if x != nil {
+		return x.Arguments
+	}
+	return nil
+}
+
+func (x *JobDetails) GetInterpreter() string {
+	if x != nil {
+		return x.Interpreter
+	}
+	return ""
+}
+
+func (x *JobDetails) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *JobDetails) GetSubmitTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.SubmitTime
+	}
+	return nil
+}
+
+func (x *JobDetails) GetStartTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartTime
+	}
+	return nil
+}
+
+func (x *JobDetails) GetEndTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EndTime
+	}
+	return nil
+}
+
+func (x *JobDetails) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *JobDetails) GetPid() int32 {
+	if x != nil {
+		return x.Pid
+	}
+	return 0
+}
+
+func (x *JobDetails) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *JobDetails) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *JobDetails) GetExitCode() int32 {
+	if x != nil {
+		return x.ExitCode
+	}
+	return 0
+}
+
+func (x *JobDetails) GetWebhookDeliveries() []*WebhookDelivery {
+	if x != nil {
+		return x.WebhookDeliveries
+	}
+	return nil
+}
+
+// An attempt to call a webhook once the job ended. url only has the scheme and host of the webhook, since the
+// rest may hold a token. status_code is the status of the response, 0 when there was none, and error explains
+// why the attempt failed, when it did.
+type WebhookDelivery struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Url           string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	Attempt       int32                  `protobuf:"varint,2,opt,name=attempt,proto3" json:"attempt,omitempty"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=time,proto3" json:"time,omitempty"`
+	StatusCode    int32                  `protobuf:"varint,4,opt,name=status_code,json=statusCode,proto3" json:"status_code,omitempty"`
+	Error         string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WebhookDelivery) Reset() {
+	*x = WebhookDelivery{}
+	mi := &file_pb_remote_exec_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WebhookDelivery) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WebhookDelivery) ProtoMessage() {}
+
+func (x *WebhookDelivery) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_remote_exec_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WebhookDelivery.ProtoReflect.Descriptor instead.
+func (*WebhookDelivery) Descriptor() ([]byte, []int) {
 	return file_pb_remote_exec_proto_rawDescGZIP(), []int{3}
 }
 
-func (x *JobOutput) GetOutput() []byte {
+func (x *WebhookDelivery) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *WebhookDelivery) GetAttempt() int32 {
+	if x != nil {
+		return x.Attempt
+	}
+	return 0
+}
+
+func (x *WebhookDelivery) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *WebhookDelivery) GetStatusCode() int32 {
+	if x != nil {
+		return x.StatusCode
+	}
+	return 0
+}
+
+func (x *WebhookDelivery) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// The response for a Get Job, with the combined output from stdout and stderr.
+// The offset is the position of the first byte of this chunk in the job output.
+// All the output in a chunk was written to the same stream, and captured at the same time.
+// next_offset is the offset to resume the stream from, after this chunk. When streaming lines, line is the
+// number of the line in the output, and resuming may send again lines already received, with the same offset.
+type JobOutput struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Output     []byte                 `protobuf:"bytes,1,opt,name=output,proto3" json:"output,omitempty"`
+	Offset     int64                  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	Time       *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=time,proto3" json:"time,omitempty"`
+	Stream     JobOutput_Stream       `protobuf:"varint,4,opt,name=stream,proto3,enum=JobOutput_Stream" json:"stream,omitempty"`
+	NextOffset int64                  `protobuf:"varint,5,opt,name=next_offset,json=nextOffset,proto3" json:"next_offset,omitempty"`
+	Line       int64                  `protobuf:"varint,6,opt,name=line,proto3" json:"line,omitempty"`
+	// missing is the number of bytes dropped from the output from offset on, when it was truncated.
+	// Messages with missing bytes have no output.
+	Missing       int64 `protobuf:"varint,7,opt,name=missing,proto3" json:"missing,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobOutput) Reset() {
+	*x = JobOutput{}
+	mi := &file_pb_remote_exec_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobOutput) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobOutput) ProtoMessage() {}
+
+func (x *JobOutput) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_remote_exec_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobOutput.ProtoReflect.Descriptor instead.
+func (*JobOutput) Descriptor() ([]byte, []int) {
+	return file_pb_remote_exec_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *JobOutput) GetOutput() []byte {
+	if x != nil {
+		return x.Output
+	}
+	return nil
+}
+
+func (x *JobOutput) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *JobOutput) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *JobOutput) GetStream() JobOutput_Stream {
+	if x != nil {
+		return x.Stream
+	}
+	return JobOutput_STDOUT
+}
+
+func (x *JobOutput) GetNextOffset() int64 {
+	if x != nil {
+		return x.NextOffset
+	}
+	return 0
+}
+
+func (x *JobOutput) GetLine() int64 {
+	if x != nil {
+		return x.Line
+	}
+	return 0
+}
+
+func (x *JobOutput) GetMissing() int64 {
+	if x != nil {
+		return x.Missing
+	}
+	return 0
+}
+
+// The request for a Stop operation containing the job id
+type StopRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StopRequest) Reset() {
+	*x = StopRequest{}
+	mi := &file_pb_remote_exec_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StopRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StopRequest) ProtoMessage() {}
+
+func (x *StopRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_remote_exec_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StopRequest.ProtoReflect.Descriptor instead.
+func (*StopRequest) Descriptor() ([]byte, []int) {
+	return file_pb_remote_exec_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *StopRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+// A piece of a file being transferred. The checksum is the sha256 of the whole file
+// and is only set on the last chunk.
+type FileChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Offset        int64                  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	Mode          uint32                 `protobuf:"varint,4,opt,name=mode,proto3" json:"mode,omitempty"`
+	Checksum      []byte                 `protobuf:"bytes,5,opt,name=checksum,proto3" json:"checksum,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileChunk) Reset() {
+	*x = FileChunk{}
+	mi := &file_pb_remote_exec_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileChunk) ProtoMessage() {}
+
+func (x *FileChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_remote_exec_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileChunk.ProtoReflect.Descriptor instead.
+func (*FileChunk) Descriptor() ([]byte, []int) {
+	return file_pb_remote_exec_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *FileChunk) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *FileChunk) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *FileChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *FileChunk) GetMode() uint32 {
+	if x != nil {
+		return x.Mode
+	}
+	return 0
+}
+
+func (x *FileChunk) GetChecksum() []byte {
+	if x != nil {
+		return x.Checksum
+	}
+	return nil
+}
+
+// The request for a file on the server, starting at offset
+type FileRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Offset        int64                  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileRequest) Reset() {
+	*x = FileRequest{}
+	mi := &file_pb_remote_exec_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileRequest) ProtoMessage() {}
+
+func (x *FileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_remote_exec_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileRequest.ProtoReflect.Descriptor instead.
+func (*FileRequest) Descriptor() ([]byte, []int) {
+	return file_pb_remote_exec_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *FileRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *FileRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+// The details for a file on the server
+type FileInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Size          int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	Mode          uint32                 `protobuf:"varint,3,opt,name=mode,proto3" json:"mode,omitempty"`
+	Checksum      []byte                 `protobuf:"bytes,4,opt,name=checksum,proto3" json:"checksum,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileInfo) Reset() {
+	*x = FileInfo{}
+	mi := &file_pb_remote_exec_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileInfo) ProtoMessage() {}
+
+func (x *FileInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_remote_exec_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileInfo.ProtoReflect.Descriptor instead.
+func (*FileInfo) Descriptor() ([]byte, []int) {
+	return file_pb_remote_exec_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *FileInfo) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *FileInfo) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *FileInfo) GetMode() uint32 {
+	if x != nil {
+		return x.Mode
+	}
+	return 0
+}
+
+func (x *FileInfo) GetChecksum() []byte {
+	if x != nil {
+		return x.Checksum
+	}
+	return nil
+}
+
+// A piece of the tar.gz archive with the artifacts from a job
+type ArchiveChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Data          []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ArchiveChunk) Reset() {
+	*x = ArchiveChunk{}
+	mi := &file_pb_remote_exec_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ArchiveChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ArchiveChunk) ProtoMessage() {}
+
+func (x *ArchiveChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_remote_exec_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ArchiveChunk.ProtoReflect.Descriptor instead.
+func (*ArchiveChunk) Descriptor() ([]byte, []int) {
+	return file_pb_remote_exec_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ArchiveChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+// The request for a Purge operation containing the job id
+type PurgeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PurgeRequest) Reset() {
+	*x = PurgeRequest{}
+	mi := &file_pb_remote_exec_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PurgeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PurgeRequest) ProtoMessage() {}
+
+func (x *PurgeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_remote_exec_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PurgeRequest.ProtoReflect.Descriptor instead.
+func (*PurgeRequest) Descriptor() ([]byte, []int) {
+	return file_pb_remote_exec_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *PurgeRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+// The request to set or delete a secret. value and users, the emails of the users allowed to use the secret,
+// are only informed to set it.
+type SecretRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Users         []string               `protobuf:"bytes,3,rep,name=users,proto3" json:"users,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SecretRequest) Reset() {
+	*x = SecretRequest{}
+	mi := &file_pb_remote_exec_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SecretRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SecretRequest) ProtoMessage() {}
+
+func (x *SecretRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_remote_exec_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SecretRequest.ProtoReflect.Descriptor instead.
+func (*SecretRequest) Descriptor() ([]byte, []int) {
+	return file_pb_remote_exec_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SecretRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *SecretRequest) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *SecretRequest) GetUsers() []string {
+	if x != nil {
+		return x.Users
+	}
+	return nil
+}
+
+// A secret in the server secret store, without its value
+type SecretInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Users         []string               `protobuf:"bytes,2,rep,name=users,proto3" json:"users,omitempty"`
+	Updated       *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=updated,proto3" json:"updated,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SecretInfo) Reset() {
+	*x = SecretInfo{}
+	mi := &file_pb_remote_exec_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SecretInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SecretInfo) ProtoMessage() {}
+
+func (x *SecretInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_remote_exec_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SecretInfo.ProtoReflect.Descriptor instead.
+func (*SecretInfo) Descriptor() ([]byte, []int) {
+	return file_pb_remote_exec_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *SecretInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *SecretInfo) GetUsers() []string {
+	if x != nil {
+		return x.Users
+	}
+	return nil
+}
+
+func (x *SecretInfo) GetUpdated() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Updated
+	}
+	return nil
+}
+
+// The secrets in the server secret store, sorted by name
+type SecretList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Secrets       []*SecretInfo          `protobuf:"bytes,1,rep,name=secrets,proto3" json:"secrets,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SecretList) Reset() {
+	*x = SecretList{}
+	mi := &file_pb_remote_exec_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SecretList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SecretList) ProtoMessage() {}
+
+func (x *SecretList) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_remote_exec_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SecretList.ProtoReflect.Descriptor instead.
+func (*SecretList) Descriptor() ([]byte, []int) {
+	return file_pb_remote_exec_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *SecretList) GetSecrets() []*SecretInfo {
+	if x != nil {
+		return x.Secrets
+	}
+	return nil
+}
+
+// The request to list the jobs. owner, statuses, since and until, which are compared to the submit time,
+// command, a substring of the command line, and label_selector filter the jobs, and the empty ones are ignored.
+// The jobs are sorted by sort_by, in descending order unless ascending is set, and by id on ties.
+// page_size limits the jobs returned, up to the server limit, and page_token continues a previous list,
+// from the next_page_token it returned, with the same filters and sorting.
+type ListJobsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Owner         string                 `protobuf:"bytes,1,opt,name=owner,proto3" json:"owner,omitempty"`
+	Statuses      []JobDetails_Status    `protobuf:"varint,2,rep,packed,name=statuses,proto3,enum=JobDetails_Status" json:"statuses,omitempty"`
+	Since         *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=since,proto3" json:"since,omitempty"`
+	Until         *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=until,proto3" json:"until,omitempty"`
+	Command       string                 `protobuf:"bytes,5,opt,name=command,proto3" json:"command,omitempty"`
+	SortBy        ListJobsRequest_SortBy `protobuf:"varint,6,opt,name=sort_by,json=sortBy,proto3,enum=ListJobsRequest_SortBy" json:"sort_by,omitempty"`
+	Ascending     bool                   `protobuf:"varint,7,opt,name=ascending,proto3" json:"ascending,omitempty"`
+	PageSize      int32                  `protobuf:"varint,8,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	PageToken     string                 `protobuf:"bytes,9,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	LabelSelector string                 `protobuf:"bytes,10,opt,name=label_selector,json=labelSelector,proto3" json:"label_selector,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListJobsRequest) Reset() {
+	*x = ListJobsRequest{}
+	mi := &file_pb_remote_exec_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListJobsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListJobsRequest) ProtoMessage() {}
+
+func (x *ListJobsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_remote_exec_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListJobsRequest.ProtoReflect.Descriptor instead.
+func (*ListJobsRequest) Descriptor() ([]byte, []int) {
+	return file_pb_remote_exec_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ListJobsRequest) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *ListJobsRequest) GetStatuses() []JobDetails_Status {
 	if x != nil {
-		return x.Output
+		return x.Statuses
 	}
 	return nil
 }
 
-// The request for a Stop operation containing the job id
-type StopRequest struct {
+func (x *ListJobsRequest) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+func (x *ListJobsRequest) GetUntil() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Until
+	}
+	return nil
+}
+
+func (x *ListJobsRequest) GetCommand() string {
+	if x != nil {
+		return x.Command
+	}
+	return ""
+}
+
+func (x *ListJobsRequest) GetSortBy() ListJobsRequest_SortBy {
+	if x != nil {
+		return x.SortBy
+	}
+	return ListJobsRequest_SUBMIT_TIME
+}
+
+func (x *ListJobsRequest) GetAscending() bool {
+	if x != nil {
+		return x.Ascending
+	}
+	return false
+}
+
+func (x *ListJobsRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListJobsRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *ListJobsRequest) GetLabelSelector() string {
+	if x != nil {
+		return x.LabelSelector
+	}
+	return ""
+}
+
+// A page of jobs. next_page_token is empty on the last page.
+type ListJobsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Jobs          []*JobDetails          `protobuf:"bytes,1,rep,name=jobs,proto3" json:"jobs,omitempty"`
+	NextPageToken string                 `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListJobsResponse) Reset() {
+	*x = ListJobsResponse{}
+	mi := &file_pb_remote_exec_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListJobsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListJobsResponse) ProtoMessage() {}
+
+func (x *ListJobsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_remote_exec_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListJobsResponse.ProtoReflect.Descriptor instead.
+func (*ListJobsResponse) Descriptor() ([]byte, []int) {
+	return file_pb_remote_exec_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ListJobsResponse) GetJobs() []*JobDetails {
+	if x != nil {
+		return x.Jobs
+	}
+	return nil
+}
+
+func (x *ListJobsResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+// The request for an operation on the jobs matching a label selector, a list of requirements separated by
+// commas which must all match: key=value, key!=value, key to require the label and !key to require its absence.
+// signal is the name of the signal sent by SignalJobs, like TERM or HUP.
+type SelectorRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Selector      string                 `protobuf:"bytes,1,opt,name=selector,proto3" json:"selector,omitempty"`
+	Signal        string                 `protobuf:"bytes,2,opt,name=signal,proto3" json:"signal,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SelectorRequest) Reset() {
+	*x = SelectorRequest{}
+	mi := &file_pb_remote_exec_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SelectorRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SelectorRequest) ProtoMessage() {}
+
+func (x *SelectorRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_remote_exec_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SelectorRequest.ProtoReflect.Descriptor instead.
+func (*SelectorRequest) Descriptor() ([]byte, []int) {
+	return file_pb_remote_exec_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *SelectorRequest) GetSelector() string {
+	if x != nil {
+		return x.Selector
+	}
+	return ""
+}
+
+func (x *SelectorRequest) GetSignal() string {
+	if x != nil {
+		return x.Signal
+	}
+	return ""
+}
+
+// The result of an operation on a job. error explains why it failed, when ok is false.
+type JobResult struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	Ok            bool                   `protobuf:"varint,2,opt,name=ok,proto3" json:"ok,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *StopRequest) Reset() {
-	*x = StopRequest{}
-	mi := &file_pb_remote_exec_proto_msgTypes[4]
+func (x *JobResult) Reset() {
+	*x = JobResult{}
+	mi := &file_pb_remote_exec_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *StopRequest) String() string {
+func (x *JobResult) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StopRequest) ProtoMessage() {}
+func (*JobResult) ProtoMessage() {}
 
-func (x *StopRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_pb_remote_exec_proto_msgTypes[4]
+func (x *JobResult) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_remote_exec_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -303,49 +1789,421 @@ func (x *StopRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StopRequest.ProtoReflect.Descriptor instead.
-func (*StopRequest) Descriptor() ([]byte, []int) {
-	return file_pb_remote_exec_proto_rawDescGZIP(), []int{4}
+// Deprecated: Use JobResult.ProtoReflect.Descriptor instead.
+func (*JobResult) Descriptor() ([]byte, []int) {
+	return file_pb_remote_exec_proto_rawDescGZIP(), []int{17}
 }
 
-func (x *StopRequest) GetJobId() string {
+func (x *JobResult) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *JobResult) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *JobResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// The results of an operation on the jobs matching a label selector, one for each job
+type BulkResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*JobResult           `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BulkResponse) Reset() {
+	*x = BulkResponse{}
+	mi := &file_pb_remote_exec_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BulkResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BulkResponse) ProtoMessage() {}
+
+func (x *BulkResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_remote_exec_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BulkResponse.ProtoReflect.Descriptor instead.
+func (*BulkResponse) Descriptor() ([]byte, []int) {
+	return file_pb_remote_exec_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *BulkResponse) GetResults() []*JobResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+// The request to watch the jobs: job_id watches a single job, and the stream ends after the job exits.
+// Otherwise, the stream watches the jobs matching the label selector, or all the jobs visible to the user
+// when it's empty, until the client cancels it.
+// output_threshold_bytes, when set, sends an OUTPUT_THRESHOLD event once the output of a job gets to that size.
+type WatchRequest struct {
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	JobId                string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	Selector             string                 `protobuf:"bytes,2,opt,name=selector,proto3" json:"selector,omitempty"`
+	OutputThresholdBytes int64                  `protobuf:"varint,3,opt,name=output_threshold_bytes,json=outputThresholdBytes,proto3" json:"output_threshold_bytes,omitempty"`
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	mi := &file_pb_remote_exec_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_remote_exec_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_pb_remote_exec_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *WatchRequest) GetJobId() string {
 	if x != nil {
 		return x.JobId
 	}
 	return ""
 }
 
+func (x *WatchRequest) GetSelector() string {
+	if x != nil {
+		return x.Selector
+	}
+	return ""
+}
+
+func (x *WatchRequest) GetOutputThresholdBytes() int64 {
+	if x != nil {
+		return x.OutputThresholdBytes
+	}
+	return 0
+}
+
+// A lifecycle event from a job, with the job details at the time of the event.
+// A job ends with an EXITED event, or with STOPPED when it was stopped by a user.
+type JobEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          JobEvent_Type          `protobuf:"varint,1,opt,name=type,proto3,enum=JobEvent_Type" json:"type,omitempty"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=time,proto3" json:"time,omitempty"`
+	Job           *JobDetails            `protobuf:"bytes,3,opt,name=job,proto3" json:"job,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobEvent) Reset() {
+	*x = JobEvent{}
+	mi := &file_pb_remote_exec_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobEvent) ProtoMessage() {}
+
+func (x *JobEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_remote_exec_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobEvent.ProtoReflect.Descriptor instead.
+func (*JobEvent) Descriptor() ([]byte, []int) {
+	return file_pb_remote_exec_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *JobEvent) GetType() JobEvent_Type {
+	if x != nil {
+		return x.Type
+	}
+	return JobEvent_SUBMITTED
+}
+
+func (x *JobEvent) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *JobEvent) GetJob() *JobDetails {
+	if x != nil {
+		return x.Job
+	}
+	return nil
+}
+
 var File_pb_remote_exec_proto protoreflect.FileDescriptor
 
 const file_pb_remote_exec_proto_rawDesc = "" +
 	"\n" +
-	"\x14pb/remote_exec.proto\x1a\x1bgoogle/protobuf/empty.proto\"D\n" +
+	"\x14pb/remote_exec.proto\x1a\x1bgoogle/protobuf/empty.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xb1\x04\n" +
 	"\n" +
 	"CmdRequest\x12\x18\n" +
 	"\acommand\x18\x01 \x01(\tR\acommand\x12\x1c\n" +
-	"\targuments\x18\x02 \x03(\tR\targuments\"#\n" +
+	"\targuments\x18\x02 \x03(\tR\targuments\x12\x16\n" +
+	"\x06script\x18\x03 \x01(\fR\x06script\x12 \n" +
+	"\vinterpreter\x18\x04 \x01(\tR\vinterpreter\x12\x1c\n" +
+	"\tartifacts\x18\x05 \x03(\tR\tartifacts\x12(\n" +
+	"\x10max_output_bytes\x18\x06 \x01(\x03R\x0emaxOutputBytes\x122\n" +
+	"\routput_policy\x18\a \x01(\x0e2\r.OutputPolicyR\foutputPolicy\x129\n" +
+	"\n" +
+	"secret_env\x18\b \x03(\v2\x1a.CmdRequest.SecretEnvEntryR\tsecretEnv\x12 \n" +
+	"\vdescription\x18\t \x01(\tR\vdescription\x12/\n" +
+	"\x06labels\x18\n" +
+	" \x03(\v2\x17.CmdRequest.LabelsEntryR\x06labels\x12\x12\n" +
+	"\x04name\x18\v \x01(\tR\x04name\x12\x1a\n" +
+	"\bwebhooks\x18\f \x03(\tR\bwebhooks\x1a<\n" +
+	"\x0eSecretEnvEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x8c\x03\n" +
 	"\n" +
 	"GetRequest\x12\x15\n" +
-	"\x06job_id\x18\x01 \x01(\tR\x05jobId\"\x8f\x01\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x03R\x06offset\x12$\n" +
+	"\x04mode\x18\x03 \x01(\x0e2\x10.GetRequest.ModeR\x04mode\x12\x1d\n" +
+	"\n" +
+	"tail_lines\x18\x04 \x01(\x03R\ttailLines\x12\x1d\n" +
+	"\n" +
+	"tail_bytes\x18\x05 \x01(\x03R\ttailBytes\x12\x18\n" +
+	"\ainclude\x18\x06 \x01(\tR\ainclude\x12\x18\n" +
+	"\aexclude\x18\a \x01(\tR\aexclude\x120\n" +
+	"\x05since\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\x05since\x120\n" +
+	"\x05until\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\x05until\x12\x1b\n" +
+	"\tmax_lines\x18\n" +
+	" \x01(\x03R\bmaxLines\x12\x14\n" +
+	"\x05lines\x18\v \x01(\bR\x05lines\" \n" +
+	"\x04Mode\x12\n" +
+	"\n" +
+	"\x06FOLLOW\x10\x00\x12\f\n" +
+	"\bSNAPSHOT\x10\x01\"\xdd\a\n" +
 	"\n" +
 	"JobDetails\x12\x15\n" +
 	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12*\n" +
-	"\x06status\x18\x02 \x01(\x0e2\x12.JobDetails.StatusR\x06status\">\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x12.JobDetails.StatusR\x06status\x12\x1c\n" +
+	"\tartifacts\x18\x03 \x03(\tR\tartifacts\x12!\n" +
+	"\foutput_bytes\x18\x04 \x01(\x03R\voutputBytes\x12!\n" +
+	"\fstored_bytes\x18\x05 \x01(\x03R\vstoredBytes\x12-\n" +
+	"\n" +
+	"truncation\x18\x06 \x01(\x0e2\r.OutputPolicyR\n" +
+	"truncation\x12#\n" +
+	"\rdropped_bytes\x18\a \x01(\x03R\fdroppedBytes\x12\x1e\n" +
+	"\n" +
+	"redactions\x18\b \x01(\x03R\n" +
+	"redactions\x12%\n" +
+	"\x0estatus_message\x18\t \x01(\tR\rstatusMessage\x12\x14\n" +
+	"\x05owner\x18\n" +
+	" \x01(\tR\x05owner\x12\x18\n" +
+	"\acommand\x18\v \x01(\tR\acommand\x12\x1c\n" +
+	"\targuments\x18\f \x03(\tR\targuments\x12 \n" +
+	"\vinterpreter\x18\r \x01(\tR\vinterpreter\x12 \n" +
+	"\vdescription\x18\x0e \x01(\tR\vdescription\x12;\n" +
+	"\vsubmit_time\x18\x0f \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"submitTime\x129\n" +
+	"\n" +
+	"start_time\x18\x10 \x01(\v2\x1a.google.protobuf.TimestampR\tstartTime\x125\n" +
+	"\bend_time\x18\x11 \x01(\v2\x1a.google.protobuf.TimestampR\aendTime\x12\x12\n" +
+	"\x04host\x18\x12 \x01(\tR\x04host\x12\x10\n" +
+	"\x03pid\x18\x13 \x01(\x05R\x03pid\x12/\n" +
+	"\x06labels\x18\x14 \x03(\v2\x17.JobDetails.LabelsEntryR\x06labels\x12\x12\n" +
+	"\x04name\x18\x15 \x01(\tR\x04name\x12\x1b\n" +
+	"\texit_code\x18\x16 \x01(\x05R\bexitCode\x12?\n" +
+	"\x12webhook_deliveries\x18\x17 \x03(\v2\x10.WebhookDeliveryR\x11webhookDeliveries\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"H\n" +
 	"\x06Status\x12\v\n" +
 	"\aRUNNING\x10\x00\x12\r\n" +
 	"\tCOMPLETED\x10\x01\x12\v\n" +
 	"\aERRORED\x10\x02\x12\v\n" +
-	"\aSTOPPED\x10\x03\"#\n" +
+	"\aSTOPPED\x10\x03\x12\b\n" +
+	"\x04LOST\x10\x04\"\xa4\x01\n" +
+	"\x0fWebhookDelivery\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\x12\x18\n" +
+	"\aattempt\x18\x02 \x01(\x05R\aattempt\x12.\n" +
+	"\x04time\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12\x1f\n" +
+	"\vstatus_code\x18\x04 \x01(\x05R\n" +
+	"statusCode\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\"\x87\x02\n" +
 	"\tJobOutput\x12\x16\n" +
-	"\x06output\x18\x01 \x01(\fR\x06output\"$\n" +
+	"\x06output\x18\x01 \x01(\fR\x06output\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x03R\x06offset\x12.\n" +
+	"\x04time\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12)\n" +
+	"\x06stream\x18\x04 \x01(\x0e2\x11.JobOutput.StreamR\x06stream\x12\x1f\n" +
+	"\vnext_offset\x18\x05 \x01(\x03R\n" +
+	"nextOffset\x12\x12\n" +
+	"\x04line\x18\x06 \x01(\x03R\x04line\x12\x18\n" +
+	"\amissing\x18\a \x01(\x03R\amissing\" \n" +
+	"\x06Stream\x12\n" +
+	"\n" +
+	"\x06STDOUT\x10\x00\x12\n" +
+	"\n" +
+	"\x06STDERR\x10\x01\"$\n" +
 	"\vStopRequest\x12\x15\n" +
-	"\x06job_id\x18\x01 \x01(\tR\x05jobId2\xc1\x01\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\"{\n" +
+	"\tFileChunk\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x03R\x06offset\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\x12\x12\n" +
+	"\x04mode\x18\x04 \x01(\rR\x04mode\x12\x1a\n" +
+	"\bchecksum\x18\x05 \x01(\fR\bchecksum\"9\n" +
+	"\vFileRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x03R\x06offset\"b\n" +
+	"\bFileInfo\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x03R\x04size\x12\x12\n" +
+	"\x04mode\x18\x03 \x01(\rR\x04mode\x12\x1a\n" +
+	"\bchecksum\x18\x04 \x01(\fR\bchecksum\"\"\n" +
+	"\fArchiveChunk\x12\x12\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data\"%\n" +
+	"\fPurgeRequest\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\"O\n" +
+	"\rSecretRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value\x12\x14\n" +
+	"\x05users\x18\x03 \x03(\tR\x05users\"l\n" +
+	"\n" +
+	"SecretInfo\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05users\x18\x02 \x03(\tR\x05users\x124\n" +
+	"\aupdated\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\aupdated\"3\n" +
+	"\n" +
+	"SecretList\x12%\n" +
+	"\asecrets\x18\x01 \x03(\v2\v.SecretInfoR\asecrets\"\xd9\x03\n" +
+	"\x0fListJobsRequest\x12\x14\n" +
+	"\x05owner\x18\x01 \x01(\tR\x05owner\x12.\n" +
+	"\bstatuses\x18\x02 \x03(\x0e2\x12.JobDetails.StatusR\bstatuses\x120\n" +
+	"\x05since\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x05since\x120\n" +
+	"\x05until\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x05until\x12\x18\n" +
+	"\acommand\x18\x05 \x01(\tR\acommand\x120\n" +
+	"\asort_by\x18\x06 \x01(\x0e2\x17.ListJobsRequest.SortByR\x06sortBy\x12\x1c\n" +
+	"\tascending\x18\a \x01(\bR\tascending\x12\x1b\n" +
+	"\tpage_size\x18\b \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\t \x01(\tR\tpageToken\x12%\n" +
+	"\x0elabel_selector\x18\n" +
+	" \x01(\tR\rlabelSelector\"O\n" +
+	"\x06SortBy\x12\x0f\n" +
+	"\vSUBMIT_TIME\x10\x00\x12\x0e\n" +
+	"\n" +
+	"START_TIME\x10\x01\x12\f\n" +
+	"\bEND_TIME\x10\x02\x12\t\n" +
+	"\x05OWNER\x10\x03\x12\v\n" +
+	"\aCOMMAND\x10\x04\"[\n" +
+	"\x10ListJobsResponse\x12\x1f\n" +
+	"\x04jobs\x18\x01 \x03(\v2\v.JobDetailsR\x04jobs\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"E\n" +
+	"\x0fSelectorRequest\x12\x1a\n" +
+	"\bselector\x18\x01 \x01(\tR\bselector\x12\x16\n" +
+	"\x06signal\x18\x02 \x01(\tR\x06signal\"H\n" +
+	"\tJobResult\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12\x0e\n" +
+	"\x02ok\x18\x02 \x01(\bR\x02ok\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"4\n" +
+	"\fBulkResponse\x12$\n" +
+	"\aresults\x18\x01 \x03(\v2\n" +
+	".JobResultR\aresults\"w\n" +
+	"\fWatchRequest\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12\x1a\n" +
+	"\bselector\x18\x02 \x01(\tR\bselector\x124\n" +
+	"\x16output_threshold_bytes\x18\x03 \x01(\x03R\x14outputThresholdBytes\"\xd0\x01\n" +
+	"\bJobEvent\x12\"\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x0e.JobEvent.TypeR\x04type\x12.\n" +
+	"\x04time\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12\x1d\n" +
+	"\x03job\x18\x03 \x01(\v2\v.JobDetailsR\x03job\"Q\n" +
+	"\x04Type\x12\r\n" +
+	"\tSUBMITTED\x10\x00\x12\v\n" +
+	"\aSTARTED\x10\x01\x12\x14\n" +
+	"\x10OUTPUT_THRESHOLD\x10\x02\x12\n" +
+	"\n" +
+	"\x06EXITED\x10\x03\x12\v\n" +
+	"\aSTOPPED\x10\x04*>\n" +
+	"\fOutputPolicy\x12\v\n" +
+	"\aDEFAULT\x10\x00\x12\b\n" +
+	"\x04STOP\x10\x01\x12\b\n" +
+	"\x04RING\x10\x02\x12\r\n" +
+	"\tHEAD_TAIL\x10\x032\xc5\x06\n" +
 	"\x0eRemoteExecutor\x12)\n" +
 	"\vExecCommand\x12\v.CmdRequest\x1a\v.JobDetails\"\x00\x12'\n" +
 	"\tGetStatus\x12\v.GetRequest\x1a\v.JobDetails\"\x00\x12(\n" +
 	"\tGetOutput\x12\v.GetRequest\x1a\n" +
 	".JobOutput\"\x000\x01\x121\n" +
-	"\aStopJob\x12\f.StopRequest\x1a\x16.google.protobuf.Empty\"\x00B\x06Z\x04.;pbb\x06proto3"
+	"\aStopJob\x12\f.StopRequest\x1a\x16.google.protobuf.Empty\"\x00\x12'\n" +
+	"\n" +
+	"UploadFile\x12\n" +
+	".FileChunk\x1a\t.FileInfo\"\x00(\x01\x12,\n" +
+	"\fDownloadFile\x12\f.FileRequest\x1a\n" +
+	".FileChunk\"\x000\x01\x12%\n" +
+	"\bStatFile\x12\f.FileRequest\x1a\t.FileInfo\"\x00\x123\n" +
+	"\x11DownloadArtifacts\x12\v.GetRequest\x1a\r.ArchiveChunk\"\x000\x01\x129\n" +
+	"\x0ePurgeJobOutput\x12\r.PurgeRequest\x1a\x16.google.protobuf.Empty\"\x00\x125\n" +
+	"\tSetSecret\x12\x0e.SecretRequest\x1a\x16.google.protobuf.Empty\"\x00\x124\n" +
+	"\vListSecrets\x12\x16.google.protobuf.Empty\x1a\v.SecretList\"\x00\x128\n" +
+	"\fDeleteSecret\x12\x0e.SecretRequest\x1a\x16.google.protobuf.Empty\"\x00\x121\n" +
+	"\bListJobs\x12\x10.ListJobsRequest\x1a\x11.ListJobsResponse\"\x00\x12-\n" +
+	"\bStopJobs\x12\x10.SelectorRequest\x1a\r.BulkResponse\"\x00\x12/\n" +
+	"\n" +
+	"SignalJobs\x12\x10.SelectorRequest\x1a\r.BulkResponse\"\x00\x12/\n" +
+	"\n" +
+	"DeleteJobs\x12\x10.SelectorRequest\x1a\r.BulkResponse\"\x00\x12)\n" +
+	"\tWatchJobs\x12\r.WatchRequest\x1a\t.JobEvent\"\x000\x01B\x06Z\x04.;pbb\x06proto3"
 
 var (
 	file_pb_remote_exec_proto_rawDescOnce sync.Once
@@ -359,32 +2217,109 @@ func file_pb_remote_exec_proto_rawDescGZIP() []byte {
 	return file_pb_remote_exec_proto_rawDescData
 }
 
-var file_pb_remote_exec_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_pb_remote_exec_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_pb_remote_exec_proto_enumTypes = make([]protoimpl.EnumInfo, 6)
+var file_pb_remote_exec_proto_msgTypes = make([]protoimpl.MessageInfo, 24)
 var file_pb_remote_exec_proto_goTypes = []any{
-	(JobDetails_Status)(0), // 0: JobDetails.Status
-	(*CmdRequest)(nil),     // 1: CmdRequest
-	(*GetRequest)(nil),     // 2: GetRequest
-	(*JobDetails)(nil),     // 3: JobDetails
-	(*JobOutput)(nil),      // 4: JobOutput
-	(*StopRequest)(nil),    // 5: StopRequest
-	(*emptypb.Empty)(nil),  // 6: google.protobuf.Empty
+	(OutputPolicy)(0),             // 0: OutputPolicy
+	(GetRequest_Mode)(0),          // 1: GetRequest.Mode
+	(JobDetails_Status)(0),        // 2: JobDetails.Status
+	(JobOutput_Stream)(0),         // 3: JobOutput.Stream
+	(ListJobsRequest_SortBy)(0),   // 4: ListJobsRequest.SortBy
+	(JobEvent_Type)(0),            // 5: JobEvent.Type
+	(*CmdRequest)(nil),            // 6: CmdRequest
+	(*GetRequest)(nil),            // 7: GetRequest
+	(*JobDetails)(nil),            // 8: JobDetails
+	(*WebhookDelivery)(nil),       // 9: WebhookDelivery
+	(*JobOutput)(nil),             // 10: JobOutput
+	(*StopRequest)(nil),           // 11: StopRequest
+	(*FileChunk)(nil),             // 12: FileChunk
+	(*FileRequest)(nil),           // 13: FileRequest
+	(*FileInfo)(nil),              // 14: FileInfo
+	(*ArchiveChunk)(nil),          // 15: ArchiveChunk
+	(*PurgeRequest)(nil),          // 16: PurgeRequest
+	(*SecretRequest)(nil),         // 17: SecretRequest
+	(*SecretInfo)(nil),            // 18: SecretInfo
+	(*SecretList)(nil),            // 19: SecretList
+	(*ListJobsRequest)(nil),       // 20: ListJobsRequest
+	(*ListJobsResponse)(nil),      // 21: ListJobsResponse
+	(*SelectorRequest)(nil),       // 22: SelectorRequest
+	(*JobResult)(nil),             // 23: JobResult
+	(*BulkResponse)(nil),          // 24: BulkResponse
+	(*WatchRequest)(nil),          // 25: WatchRequest
+	(*JobEvent)(nil),              // 26: JobEvent
+	nil,                           // 27: CmdRequest.SecretEnvEntry
+	nil,                           // 28: CmdRequest.LabelsEntry
+	nil,                           // 29: JobDetails.LabelsEntry
+	(*timestamppb.Timestamp)(nil), // 30: google.protobuf.Timestamp
+	(*emptypb.Empty)(nil),         // 31: google.protobuf.Empty
 }
 var file_pb_remote_exec_proto_depIdxs = []int32{
-	0, // 0: JobDetails.status:type_name -> JobDetails.Status
-	1, // 1: RemoteExecutor.ExecCommand:input_type -> CmdRequest
-	2, // 2: RemoteExecutor.GetStatus:input_type -> GetRequest
-	2, // 3: RemoteExecutor.GetOutput:input_type -> GetRequest
-	5, // 4: RemoteExecutor.StopJob:input_type -> StopRequest
-	3, // 5: RemoteExecutor.ExecCommand:output_type -> JobDetails
-	3, // 6: RemoteExecutor.GetStatus:output_type -> JobDetails
-	4, // 7: RemoteExecutor.GetOutput:output_type -> JobOutput
-	6, // 8: RemoteExecutor.StopJob:output_type -> google.protobuf.Empty
-	5, // [5:9] is the sub-list for method output_type
-	1, // [1:5] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	0,  // 0: CmdRequest.output_policy:type_name -> OutputPolicy
+	27, // 1: CmdRequest.secret_env:type_name -> CmdRequest.SecretEnvEntry
+	28, // 2: CmdRequest.labels:type_name -> CmdRequest.LabelsEntry
+	1,  // 3: GetRequest.mode:type_name -> GetRequest.Mode
+	30, // 4: GetRequest.since:type_name -> google.protobuf.Timestamp
+	30, // 5: GetRequest.until:type_name -> google.protobuf.Timestamp
+	2,  // 6: JobDetails.status:type_name -> JobDetails.Status
+	0,  // 7: JobDetails.truncation:type_name -> OutputPolicy
+	30, // 8: JobDetails.submit_time:type_name -> google.protobuf.Timestamp
+	30, // 9: JobDetails.start_time:type_name -> google.protobuf.Timestamp
+	30, // 10: JobDetails.end_time:type_name -> google.protobuf.Timestamp
+	29, // 11: JobDetails.labels:type_name -> JobDetails.LabelsEntry
+	9,  // 12: JobDetails.webhook_deliveries:type_name -> WebhookDelivery
+	30, // 13: WebhookDelivery.time:type_name -> google.protobuf.Timestamp
+	30, // 14: JobOutput.time:type_name -> google.protobuf.Timestamp
+	3,  // 15: JobOutput.stream:type_name -> JobOutput.Stream
+	30, // 16: SecretInfo.updated:type_name -> google.protobuf.Timestamp
+	18, // 17: SecretList.secrets:type_name -> SecretInfo
+	2,  // 18: ListJobsRequest.statuses:type_name -> JobDetails.Status
+	30, // 19: ListJobsRequest.since:type_name -> google.protobuf.Timestamp
+	30, // 20: ListJobsRequest.until:type_name -> google.protobuf.Timestamp
+	4,  // 21: ListJobsRequest.sort_by:type_name -> ListJobsRequest.SortBy
+	8,  // 22: ListJobsResponse.jobs:type_name -> JobDetails
+	23, // 23: BulkResponse.results:type_name -> JobResult
+	5,  // 24: JobEvent.type:type_name -> JobEvent.Type
+	30, // 25: JobEvent.time:type_name -> google.protobuf.Timestamp
+	8,  // 26: JobEvent.job:type_name -> JobDetails
+	6,  // 27: RemoteExecutor.ExecCommand:input_type -> CmdRequest
+	7,  // 28: RemoteExecutor.GetStatus:input_type -> GetRequest
+	7,  // 29: RemoteExecutor.GetOutput:input_type -> GetRequest
+	11, // 30: RemoteExecutor.StopJob:input_type -> StopRequest
+	12, // 31: RemoteExecutor.UploadFile:input_type -> FileChunk
+	13, // 32: RemoteExecutor.DownloadFile:input_type -> FileRequest
+	13, // 33: RemoteExecutor.StatFile:input_type -> FileRequest
+	7,  // 34: RemoteExecutor.DownloadArtifacts:input_type -> GetRequest
+	16, // 35: RemoteExecutor.PurgeJobOutput:input_type -> PurgeRequest
+	17, // 36: RemoteExecutor.SetSecret:input_type -> SecretRequest
+	31, // 37: RemoteExecutor.ListSecrets:input_type -> google.protobuf.Empty
+	17, // 38: RemoteExecutor.DeleteSecret:input_type -> SecretRequest
+	20, // 39: RemoteExecutor.ListJobs:input_type -> ListJobsRequest
+	22, // 40: RemoteExecutor.StopJobs:input_type -> SelectorRequest
+	22, // 41: RemoteExecutor.SignalJobs:input_type -> SelectorRequest
+	22, // 42: RemoteExecutor.DeleteJobs:input_type -> SelectorRequest
+	25, // 43: RemoteExecutor.WatchJobs:input_type -> WatchRequest
+	8,  // 44: RemoteExecutor.ExecCommand:output_type -> JobDetails
+	8,  // 45: RemoteExecutor.GetStatus:output_type -> JobDetails
+	10, // 46: RemoteExecutor.GetOutput:output_type -> JobOutput
+	31, // 47: RemoteExecutor.StopJob:output_type -> google.protobuf.Empty
+	14, // 48: RemoteExecutor.UploadFile:output_type -> FileInfo
+	12, // 49: RemoteExecutor.DownloadFile:output_type -> FileChunk
+	14, // 50: RemoteExecutor.StatFile:output_type -> FileInfo
+	15, // 51: RemoteExecutor.DownloadArtifacts:output_type -> ArchiveChunk
+	31, // 52: RemoteExecutor.PurgeJobOutput:output_type -> google.protobuf.Empty
+	31, // 53: RemoteExecutor.SetSecret:output_type -> google.protobuf.Empty
+	19, // 54: RemoteExecutor.ListSecrets:output_type -> SecretList
+	31, // 55: RemoteExecutor.DeleteSecret:output_type -> google.protobuf.Empty
+	21, // 56: RemoteExecutor.ListJobs:output_type -> ListJobsResponse
+	24, // 57: RemoteExecutor.StopJobs:output_type -> BulkResponse
+	24, // 58: RemoteExecutor.SignalJobs:output_type -> BulkResponse
+	24, // 59: RemoteExecutor.DeleteJobs:output_type -> BulkResponse
+	26, // 60: RemoteExecutor.WatchJobs:output_type -> JobEvent
+	44, // [44:61] is the sub-list for method output_type
+	27, // [27:44] is the sub-list for method input_type
+	27, // [27:27] is the sub-list for extension type_name
+	27, // [27:27] is the sub-list for extension extendee
+	0,  // [0:27] is the sub-list for field type_name
 }
 
 func init() { file_pb_remote_exec_proto_init() }
@@ -397,8 +2332,8 @@ func file_pb_remote_exec_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pb_remote_exec_proto_rawDesc), len(file_pb_remote_exec_proto_rawDesc)),
-			NumEnums:      1,
-			NumMessages:   5,
+			NumEnums:      6,
+			NumMessages:   24,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -20,10 +20,23 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	RemoteExecutor_ExecCommand_FullMethodName = "/RemoteExecutor/ExecCommand"
-	RemoteExecutor_GetStatus_FullMethodName   = "/RemoteExecutor/GetStatus"
-	RemoteExecutor_GetOutput_FullMethodName   = "/RemoteExecutor/GetOutput"
-	RemoteExecutor_StopJob_FullMethodName     = "/RemoteExecutor/StopJob"
+	RemoteExecutor_ExecCommand_FullMethodName       = "/RemoteExecutor/ExecCommand"
+	RemoteExecutor_GetStatus_FullMethodName         = "/RemoteExecutor/GetStatus"
+	RemoteExecutor_GetOutput_FullMethodName         = "/RemoteExecutor/GetOutput"
+	RemoteExecutor_StopJob_FullMethodName           = "/RemoteExecutor/StopJob"
+	RemoteExecutor_UploadFile_FullMethodName        = "/RemoteExecutor/UploadFile"
+	RemoteExecutor_DownloadFile_FullMethodName      = "/RemoteExecutor/DownloadFile"
+	RemoteExecutor_StatFile_FullMethodName          = "/RemoteExecutor/StatFile"
+	RemoteExecutor_DownloadArtifacts_FullMethodName = "/RemoteExecutor/DownloadArtifacts"
+	RemoteExecutor_PurgeJobOutput_FullMethodName    = "/RemoteExecutor/PurgeJobOutput"
+	RemoteExecutor_SetSecret_FullMethodName         = "/RemoteExecutor/SetSecret"
+	RemoteExecutor_ListSecrets_FullMethodName       = "/RemoteExecutor/ListSecrets"
+	RemoteExecutor_DeleteSecret_FullMethodName      = "/RemoteExecutor/DeleteSecret"
+	RemoteExecutor_ListJobs_FullMethodName          = "/RemoteExecutor/ListJobs"
+	RemoteExecutor_StopJobs_FullMethodName          = "/RemoteExecutor/StopJobs"
+	RemoteExecutor_SignalJobs_FullMethodName        = "/RemoteExecutor/SignalJobs"
+	RemoteExecutor_DeleteJobs_FullMethodName        = "/RemoteExecutor/DeleteJobs"
+	RemoteExecutor_WatchJobs_FullMethodName         = "/RemoteExecutor/WatchJobs"
 )
 
 // RemoteExecutorClient is the client API for RemoteExecutor service.
@@ -38,6 +51,34 @@ type RemoteExecutorClient interface {
 	GetOutput(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[JobOutput], error)
 	// Stops a job.
 	StopJob(ctx context.Context, in *StopRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Uploads a file to the server. The first chunk carries the destination path, mode and the
+	// offset to resume from, and the last chunk carries the checksum for the whole file
+	UploadFile(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[FileChunk, FileInfo], error)
+	// Downloads a file from the server, starting at the requested offset
+	DownloadFile(ctx context.Context, in *FileRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[FileChunk], error)
+	// Gets the size, mode and checksum of a file on the server, used to resume transfers
+	StatFile(ctx context.Context, in *FileRequest, opts ...grpc.CallOption) (*FileInfo, error)
+	// Downloads the artifacts kept from a finished job workspace as a tar.gz stream
+	DownloadArtifacts(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ArchiveChunk], error)
+	// Removes the output stored for a finished job. Only allowed for admins.
+	PurgeJobOutput(ctx context.Context, in *PurgeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Adds or replaces a secret in the server secret store. Only allowed for admins.
+	SetSecret(ctx context.Context, in *SecretRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Lists the secrets in the server secret store, without their values. Only allowed for admins.
+	ListSecrets(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*SecretList, error)
+	// Removes a secret from the server secret store. Only allowed for admins.
+	DeleteSecret(ctx context.Context, in *SecretRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Lists the jobs matching the filters, a page at a time. Only admins can list the jobs from other users.
+	ListJobs(ctx context.Context, in *ListJobsRequest, opts ...grpc.CallOption) (*ListJobsResponse, error)
+	// Stops every running job matching the label selector. Only admins can stop the jobs from other users.
+	StopJobs(ctx context.Context, in *SelectorRequest, opts ...grpc.CallOption) (*BulkResponse, error)
+	// Sends a signal to every running job matching the label selector. Only admins can signal the jobs from other users.
+	SignalJobs(ctx context.Context, in *SelectorRequest, opts ...grpc.CallOption) (*BulkResponse, error)
+	// Removes every finished job matching the label selector, with its output and artifacts. Only allowed for admins.
+	DeleteJobs(ctx context.Context, in *SelectorRequest, opts ...grpc.CallOption) (*BulkResponse, error)
+	// Streams the lifecycle events from a job, the jobs matching a label selector or all the jobs visible to the user.
+	// Only admins can watch the jobs from other users.
+	WatchJobs(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[JobEvent], error)
 }
 
 type remoteExecutorClient struct {
@@ -97,6 +138,166 @@ func (c *remoteExecutorClient) StopJob(ctx context.Context, in *StopRequest, opt
 	return out, nil
 }
 
+func (c *remoteExecutorClient) UploadFile(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[FileChunk, FileInfo], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RemoteExecutor_ServiceDesc.Streams[1], RemoteExecutor_UploadFile_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[FileChunk, FileInfo]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RemoteExecutor_UploadFileClient = grpc.ClientStreamingClient[FileChunk, FileInfo]
+
+func (c *remoteExecutorClient) DownloadFile(ctx context.Context, in *FileRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[FileChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RemoteExecutor_ServiceDesc.Streams[2], RemoteExecutor_DownloadFile_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[FileRequest, FileChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RemoteExecutor_DownloadFileClient = grpc.ServerStreamingClient[FileChunk]
+
+func (c *remoteExecutorClient) StatFile(ctx context.Context, in *FileRequest, opts ...grpc.CallOption) (*FileInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FileInfo)
+	err := c.cc.Invoke(ctx, RemoteExecutor_StatFile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *remoteExecutorClient) DownloadArtifacts(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ArchiveChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RemoteExecutor_ServiceDesc.Streams[3], RemoteExecutor_DownloadArtifacts_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[GetRequest, ArchiveChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RemoteExecutor_DownloadArtifactsClient = grpc.ServerStreamingClient[ArchiveChunk]
+
+func (c *remoteExecutorClient) PurgeJobOutput(ctx context.Context, in *PurgeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, RemoteExecutor_PurgeJobOutput_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *remoteExecutorClient) SetSecret(ctx context.Context, in *SecretRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, RemoteExecutor_SetSecret_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *remoteExecutorClient) ListSecrets(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*SecretList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SecretList)
+	err := c.cc.Invoke(ctx, RemoteExecutor_ListSecrets_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *remoteExecutorClient) DeleteSecret(ctx context.Context, in *SecretRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, RemoteExecutor_DeleteSecret_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *remoteExecutorClient) ListJobs(ctx context.Context, in *ListJobsRequest, opts ...grpc.CallOption) (*ListJobsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListJobsResponse)
+	err := c.cc.Invoke(ctx, RemoteExecutor_ListJobs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *remoteExecutorClient) StopJobs(ctx context.Context, in *SelectorRequest, opts ...grpc.CallOption) (*BulkResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BulkResponse)
+	err := c.cc.Invoke(ctx, RemoteExecutor_StopJobs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *remoteExecutorClient) SignalJobs(ctx context.Context, in *SelectorRequest, opts ...grpc.CallOption) (*BulkResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BulkResponse)
+	err := c.cc.Invoke(ctx, RemoteExecutor_SignalJobs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *remoteExecutorClient) DeleteJobs(ctx context.Context, in *SelectorRequest, opts ...grpc.CallOption) (*BulkResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BulkResponse)
+	err := c.cc.Invoke(ctx, RemoteExecutor_DeleteJobs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *remoteExecutorClient) WatchJobs(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[JobEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RemoteExecutor_ServiceDesc.Streams[4], RemoteExecutor_WatchJobs_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRequest, JobEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RemoteExecutor_WatchJobsClient = grpc.ServerStreamingClient[JobEvent]
+
 // RemoteExecutorServer is the server API for RemoteExecutor service.
 // All implementations must embed UnimplementedRemoteExecutorServer
 // for forward compatibility.
@@ -109,6 +310,34 @@ type RemoteExecutorServer interface {
 	GetOutput(*GetRequest, grpc.ServerStreamingServer[JobOutput]) error
 	// Stops a job.
 	StopJob(context.Context, *StopRequest) (*emptypb.Empty, error)
+	// Uploads a file to the server. The first chunk carries the destination path, mode and the
+	// offset to resume from, and the last chunk carries the checksum for the whole file
+	UploadFile(grpc.ClientStreamingServer[FileChunk, FileInfo]) error
+	// Downloads a file from the server, starting at the requested offset
+	DownloadFile(*FileRequest, grpc.ServerStreamingServer[FileChunk]) error
+	// Gets the size, mode and checksum of a file on the server, used to resume transfers
+	StatFile(context.Context, *FileRequest) (*FileInfo, error)
+	// Downloads the artifacts kept from a finished job workspace as a tar.gz stream
+	DownloadArtifacts(*GetRequest, grpc.ServerStreamingServer[ArchiveChunk]) error
+	// Removes the output stored for a finished job. Only allowed for admins.
+	PurgeJobOutput(context.Context, *PurgeRequest) (*emptypb.Empty, error)
+	// Adds or replaces a secret in the server secret store. Only allowed for admins.
+	SetSecret(context.Context, *SecretRequest) (*emptypb.Empty, error)
+	// Lists the secrets in the server secret store, without their values. Only allowed for admins.
+	ListSecrets(context.Context, *emptypb.Empty) (*SecretList, error)
+	// Removes a secret from the server secret store. Only allowed for admins.
+	DeleteSecret(context.Context, *SecretRequest) (*emptypb.Empty, error)
+	// Lists the jobs matching the filters, a page at a time. Only admins can list the jobs from other users.
+	ListJobs(context.Context, *ListJobsRequest) (*ListJobsResponse, error)
+	// Stops every running job matching the label selector. Only admins can stop the jobs from other users.
+	StopJobs(context.Context, *SelectorRequest) (*BulkResponse, error)
+	// Sends a signal to every running job matching the label selector. Only admins can signal the jobs from other users.
+	SignalJobs(context.Context, *SelectorRequest) (*BulkResponse, error)
+	// Removes every finished job matching the label selector, with its output and artifacts. Only allowed for admins.
+	DeleteJobs(context.Context, *SelectorRequest) (*BulkResponse, error)
+	// Streams the lifecycle events from a job, the jobs matching a label selector or all the jobs visible to the user.
+	// Only admins can watch the jobs from other users.
+	WatchJobs(*WatchRequest, grpc.ServerStreamingServer[JobEvent]) error
 	mustEmbedUnimplementedRemoteExecutorServer()
 }
 
@@ -131,6 +360,45 @@ func (UnimplementedRemoteExecutorServer) GetOutput(*GetRequest, grpc.ServerStrea
 func (UnimplementedRemoteExecutorServer) StopJob(context.Context, *StopRequest) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method StopJob not implemented")
 }
+func (UnimplementedRemoteExecutorServer) UploadFile(grpc.ClientStreamingServer[FileChunk, FileInfo]) error {
+	return status.Errorf(codes.Unimplemented, "method UploadFile not implemented")
+}
+func (UnimplementedRemoteExecutorServer) DownloadFile(*FileRequest, grpc.ServerStreamingServer[FileChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadFile not implemented")
+}
+func (UnimplementedRemoteExecutorServer) StatFile(context.Context, *FileRequest) (*FileInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StatFile not implemented")
+}
+func (UnimplementedRemoteExecutorServer) DownloadArtifacts(*GetRequest, grpc.ServerStreamingServer[ArchiveChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadArtifacts not implemented")
+}
+func (UnimplementedRemoteExecutorServer) PurgeJobOutput(context.Context, *PurgeRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PurgeJobOutput not implemented")
+}
+func (UnimplementedRemoteExecutorServer) SetSecret(context.Context, *SecretRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetSecret not implemented")
+}
+func (UnimplementedRemoteExecutorServer) ListSecrets(context.Context, *emptypb.Empty) (*SecretList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSecrets not implemented")
+}
+func (UnimplementedRemoteExecutorServer) DeleteSecret(context.Context, *SecretRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSecret not implemented")
+}
+func (UnimplementedRemoteExecutorServer) ListJobs(context.Context, *ListJobsRequest) (*ListJobsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListJobs not implemented")
+}
+func (UnimplementedRemoteExecutorServer) StopJobs(context.Context, *SelectorRequest) (*BulkResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StopJobs not implemented")
+}
+func (UnimplementedRemoteExecutorServer) SignalJobs(context.Context, *SelectorRequest) (*BulkResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SignalJobs not implemented")
+}
+func (UnimplementedRemoteExecutorServer) DeleteJobs(context.Context, *SelectorRequest) (*BulkResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteJobs not implemented")
+}
+func (UnimplementedRemoteExecutorServer) WatchJobs(*WatchRequest, grpc.ServerStreamingServer[JobEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchJobs not implemented")
+}
 func (UnimplementedRemoteExecutorServer) mustEmbedUnimplementedRemoteExecutorServer() {}
 func (UnimplementedRemoteExecutorServer) testEmbeddedByValue()                        {}
 
@@ -217,6 +485,208 @@ func _RemoteExecutor_StopJob_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _RemoteExecutor_UploadFile_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RemoteExecutorServer).UploadFile(&grpc.GenericServerStream[FileChunk, FileInfo]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RemoteExecutor_UploadFileServer = grpc.ClientStreamingServer[FileChunk, FileInfo]
+
+func _RemoteExecutor_DownloadFile_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(FileRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RemoteExecutorServer).DownloadFile(m, &grpc.GenericServerStream[FileRequest, FileChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RemoteExecutor_DownloadFileServer = grpc.ServerStreamingServer[FileChunk]
+
+func _RemoteExecutor_StatFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RemoteExecutorServer).StatFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RemoteExecutor_StatFile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RemoteExecutorServer).StatFile(ctx, req.(*FileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RemoteExecutor_DownloadArtifacts_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RemoteExecutorServer).DownloadArtifacts(m, &grpc.GenericServerStream[GetRequest, ArchiveChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RemoteExecutor_DownloadArtifactsServer = grpc.ServerStreamingServer[ArchiveChunk]
+
+func _RemoteExecutor_PurgeJobOutput_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PurgeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RemoteExecutorServer).PurgeJobOutput(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RemoteExecutor_PurgeJobOutput_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RemoteExecutorServer).PurgeJobOutput(ctx, req.(*PurgeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RemoteExecutor_SetSecret_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SecretRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RemoteExecutorServer).SetSecret(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RemoteExecutor_SetSecret_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RemoteExecutorServer).SetSecret(ctx, req.(*SecretRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RemoteExecutor_ListSecrets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RemoteExecutorServer).ListSecrets(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RemoteExecutor_ListSecrets_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RemoteExecutorServer).ListSecrets(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RemoteExecutor_DeleteSecret_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SecretRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RemoteExecutorServer).DeleteSecret(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RemoteExecutor_DeleteSecret_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RemoteExecutorServer).DeleteSecret(ctx, req.(*SecretRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RemoteExecutor_ListJobs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListJobsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RemoteExecutorServer).ListJobs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RemoteExecutor_ListJobs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RemoteExecutorServer).ListJobs(ctx, req.(*ListJobsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RemoteExecutor_StopJobs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SelectorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RemoteExecutorServer).StopJobs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RemoteExecutor_StopJobs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RemoteExecutorServer).StopJobs(ctx, req.(*SelectorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RemoteExecutor_SignalJobs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SelectorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RemoteExecutorServer).SignalJobs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RemoteExecutor_SignalJobs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RemoteExecutorServer).SignalJobs(ctx, req.(*SelectorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RemoteExecutor_DeleteJobs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SelectorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RemoteExecutorServer).DeleteJobs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RemoteExecutor_DeleteJobs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RemoteExecutorServer).DeleteJobs(ctx, req.(*SelectorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RemoteExecutor_WatchJobs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RemoteExecutorServer).WatchJobs(m, &grpc.GenericServerStream[WatchRequest, JobEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RemoteExecutor_WatchJobsServer = grpc.ServerStreamingServer[JobEvent]
+
 // RemoteExecutor_ServiceDesc is the grpc.ServiceDesc for RemoteExecutor service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -236,6 +706,42 @@ var RemoteExecutor_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "StopJob",
 			Handler:    _RemoteExecutor_StopJob_Handler,
 		},
+		{
+			MethodName: "StatFile",
+			Handler:    _RemoteExecutor_StatFile_Handler,
+		},
+		{
+			MethodName: "PurgeJobOutput",
+			Handler:    _RemoteExecutor_PurgeJobOutput_Handler,
+		},
+		{
+			MethodName: "SetSecret",
+			Handler:    _RemoteExecutor_SetSecret_Handler,
+		},
+		{
+			MethodName: "ListSecrets",
+			Handler:    _RemoteExecutor_ListSecrets_Handler,
+		},
+		{
+			MethodName: "DeleteSecret",
+			Handler:    _RemoteExecutor_DeleteSecret_Handler,
+		},
+		{
+			MethodName: "ListJobs",
+			Handler:    _RemoteExecutor_ListJobs_Handler,
+		},
+		{
+			MethodName: "StopJobs",
+			Handler:    _RemoteExecutor_StopJobs_Handler,
+		},
+		{
+			MethodName: "SignalJobs",
+			Handler:    _RemoteExecutor_SignalJobs_Handler,
+		},
+		{
+			MethodName: "DeleteJobs",
+			Handler:    _RemoteExecutor_DeleteJobs_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -243,6 +749,26 @@ var RemoteExecutor_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _RemoteExecutor_GetOutput_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "UploadFile",
+			Handler:       _RemoteExecutor_UploadFile_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "DownloadFile",
+			Handler:       _RemoteExecutor_DownloadFile_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "DownloadArtifacts",
+			Handler:       _RemoteExecutor_DownloadArtifacts_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchJobs",
+			Handler:       _RemoteExecutor_WatchJobs_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "pb/remote_exec.proto",
 }
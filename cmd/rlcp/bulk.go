@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/mhsantos/rlcp/cmd/cli"
+	"github.com/mhsantos/rlcp/cmd/internal/pb"
+)
+
+// callBulk stops, signals or deletes the jobs matching the label selector
+func callBulk(client pb.RemoteExecutorClient, option cli.Option) (*pb.BulkResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	req := &pb.SelectorRequest{Selector: option.Selector}
+	var resp *pb.BulkResponse
+	var err error
+	switch option.Op {
+	case cli.Stop:
+		resp, err = client.StopJobs(ctx, req)
+	case cli.Signal:
+		req.Signal = option.Args[0]
+		resp, err = client.SignalJobs(ctx, req)
+	case cli.Delete:
+		resp, err = client.DeleteJobs(ctx, req)
+	default:
+		return nil, fmt.Errorf("invalid operation %d for a label selector", option.Op)
+	}
+	if err != nil {
+		slog.Error("call to client bulk operation failed", slog.Any("op", option.Op), slog.Any("error", err))
+		return nil, err
+	}
+	return resp, nil
+}
+
+// printResults prints the result for each job, followed by the number of jobs the operation failed for
+func printResults(w io.Writer, resp *pb.BulkResponse) {
+	if len(resp.Results) == 0 {
+		fmt.Fprintln(w, "No jobs match the selector")
+		return
+	}
+	failed := 0
+	for _, result := range resp.Results {
+		if result.Ok {
+			fmt.Fprintf(w, "%s: ok\n", result.JobId)
+		} else {
+			fmt.Fprintf(w, "%s: %s\n", result.JobId, result.Error)
+			failed++
+		}
+	}
+	fmt.Fprintf(w, "%d jobs, %d failed\n", len(resp.Results), failed)
+}
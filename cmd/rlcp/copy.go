@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mhsantos/rlcp/cmd/cli"
+	"github.com/mhsantos/rlcp/cmd/internal/pb"
+)
+
+const fileChunkSize = 32 * 1024 // 32KB
+
+// callCopy uploads or downloads a file, depending on which of the arguments is the remote path
+func callCopy(client pb.RemoteExecutorClient, option cli.Option) error {
+	src, dst := option.Args[0], option.Args[1]
+	if remote, ok := strings.CutPrefix(src, cli.RemotePrefix); ok {
+		return downloadFile(client, remote, dst, option.Resume)
+	}
+	remote, _ := strings.CutPrefix(dst, cli.RemotePrefix)
+	return uploadFile(client, src, remote, option.Resume)
+}
+
+func uploadFile(client pb.RemoteExecutorClient, local, remote string, resume bool) error {
+	ctx := context.Background()
+
+	checksum, err := fileChecksum(local)
+	if err != nil {
+		slog.Error("error reading local file", slog.String("path", local), slog.Any("error", err))
+		return err
+	}
+	file, err := os.Open(local)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	var offset int64
+	if resume {
+		remoteInfo, err := client.StatFile(ctx, &pb.FileRequest{Path: remote})
+		if err != nil && status.Code(err) != codes.NotFound {
+			slog.Error("call to client.StatFile failed", slog.Any("error", err))
+			return err
+		}
+		offset = remoteInfo.GetSize()
+		if offset > info.Size() {
+			return fmt.Errorf("remote file %s is larger than %s", remote, local)
+		}
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+	}
+
+	stream, err := client.UploadFile(ctx)
+	if err != nil {
+		slog.Error("call to client.UploadFile failed", slog.Any("error", err))
+		return err
+	}
+
+	buffer := make([]byte, fileChunkSize)
+	first := true
+	for {
+		n, err := io.ReadFull(file, buffer)
+		last := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !last {
+			return err
+		}
+		chunk := &pb.FileChunk{
+			Offset: offset,
+			Data:   buffer[:n],
+		}
+		if first {
+			chunk.Path = remote
+			chunk.Mode = uint32(info.Mode().Perm())
+			first = false
+		}
+		if last {
+			chunk.Checksum = checksum
+		}
+		if err := stream.Send(chunk); err != nil {
+			// the server closed the stream, the actual error is returned by CloseAndRecv
+			break
+		}
+		offset += int64(n)
+		if last {
+			break
+		}
+	}
+
+	remoteInfo, err := stream.CloseAndRecv()
+	if err != nil {
+		slog.Error("client.UploadFile failed", slog.Any("error", err))
+		return err
+	}
+	fmt.Printf("Uploaded %s to %s%s (%d bytes)\n", local, cli.RemotePrefix, remoteInfo.Path, remoteInfo.Size)
+	return nil
+}
+
+// downloadFile writes the remote file to the local path. A resumed download appends to the existing local file,
+// while any other download is written to a temporary file, which only replaces the local file once its checksum
+// matches, so a failed download never truncates nor creates the local file.
+func downloadFile(client pb.RemoteExecutorClient, remote, local string, resume bool) error {
+	var file *os.File
+	var offset int64
+	if resume {
+		info, err := os.Stat(local)
+		if err == nil {
+			offset = info.Size()
+			file, err = os.OpenFile(local, os.O_WRONLY|os.O_APPEND, 0)
+		}
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	path := local
+	if file == nil {
+		var err error
+		file, err = os.CreateTemp(filepath.Dir(local), "."+filepath.Base(local)+".*")
+		if err != nil {
+			return err
+		}
+		path = file.Name()
+		// once renamed, the temporary file no longer exists and removing it does nothing
+		defer os.Remove(path)
+	}
+	defer file.Close()
+
+	stream, err := client.DownloadFile(context.Background(), &pb.FileRequest{Path: remote, Offset: offset})
+	if err != nil {
+		slog.Error("call to client.DownloadFile failed", slog.Any("error", err))
+		return err
+	}
+
+	var mode fs.FileMode
+	var checksum []byte
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			slog.Error("client.DownloadFile stream iteration failed", slog.Any("error", err))
+			return err
+		}
+		if chunk.Mode != 0 {
+			mode = fs.FileMode(chunk.Mode).Perm()
+		}
+		if len(chunk.Checksum) > 0 {
+			checksum = chunk.Checksum
+		}
+		if _, err := file.Write(chunk.Data); err != nil {
+			return err
+		}
+	}
+
+	if mode != 0 {
+		if err := file.Chmod(mode); err != nil {
+			return err
+		}
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	localChecksum, err := fileChecksum(path)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(checksum, localChecksum) {
+		return errors.New("checksum mismatch, the downloaded file is corrupted")
+	}
+	if path != local {
+		if err := file.Close(); err != nil {
+			return err
+		}
+		if err := os.Rename(path, local); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Downloaded %s%s to %s\n", cli.RemotePrefix, remote, local)
+	return nil
+}
+
+// callDownloadArtifacts writes the tar.gz archive with the artifacts from the job to the local file
+func callDownloadArtifacts(client pb.RemoteExecutorClient, jobId, local string) error {
+	stream, err := client.DownloadArtifacts(context.Background(), &pb.GetRequest{JobId: jobId})
+	if err != nil {
+		slog.Error("call to client.DownloadArtifacts failed", slog.Any("error", err))
+		return err
+	}
+
+	// the file is only created after the first chunk, so errors returned by the server don't leave an empty archive behind
+	var file *os.File
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			slog.Error("client.DownloadArtifacts stream iteration failed", slog.Any("error", err))
+			return err
+		}
+		if file == nil {
+			file, err = os.Create(local)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+		}
+		if _, err := file.Write(chunk.Data); err != nil {
+			return err
+		}
+	}
+	if file == nil {
+		return errors.New("no artifacts received")
+	}
+	fmt.Printf("Artifacts saved to %s\n", local)
+	return nil
+}
+
+// fileChecksum returns the sha256 checksum for the whole file
+func fileChecksum(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
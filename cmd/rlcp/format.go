@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mhsantos/rlcp/cmd/cli"
+	"github.com/mhsantos/rlcp/cmd/internal/pb"
+)
+
+// timestampLayout is the layout of the time prefixed to the lines with --timestamps
+const timestampLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// outputPrinter prints the output received for an Output operation. data is the part of the chunk
+// which wasn't printed yet, and Flush prints anything held back once the output ends.
+// Chunks for output dropped on the server are printed as a marker with the number of bytes missing.
+type outputPrinter interface {
+	Print(chunk *pb.JobOutput, data []byte) error
+	Flush() error
+}
+
+// newOutputPrinter returns the printer for the format and timestamps options
+func newOutputPrinter(option cli.Option, w io.Writer) outputPrinter {
+	switch {
+	case option.Format == cli.FormatNDJSON:
+		return &linePrinter{w: w, printLine: printJSONLine}
+	case option.Timestamps || option.Lines:
+		return &textPrinter{w: w, timestamps: option.Timestamps, numbers: option.Lines}
+	default:
+		return &rawPrinter{w: w}
+	}
+}
+
+// rawPrinter prints the output as it's received. open is true when the last line printed is missing
+// the new line.
+type rawPrinter struct {
+	w    io.Writer
+	open bool
+}
+
+func (p *rawPrinter) Print(chunk *pb.JobOutput, data []byte) error {
+	if chunk.Missing > 0 {
+		marker := missingMarker(chunk.Missing)
+		if p.open {
+			marker = "\n" + marker
+		}
+		p.open = false
+		_, err := fmt.Fprintln(p.w, marker)
+		return err
+	}
+	if len(data) > 0 {
+		p.open = data[len(data)-1] != '\n'
+	}
+	_, err := p.w.Write(data)
+	return err
+}
+
+// missingMarker returns the text printed in place of the output dropped on the server
+func missingMarker(missing int64) string {
+	return fmt.Sprintf("[... %d bytes of output dropped ...]", missing)
+}
+
+func (p *rawPrinter) Flush() error {
+	return nil
+}
+
+// textPrinter prints the lines streamed by the server prefixed by their time and number. The parts of a
+// line sent before it's complete are printed as they are received, and when a line from the other stream
+// is received in between, the rest of the line is printed in a new line, with the prefix repeated.
+type textPrinter struct {
+	w          io.Writer
+	timestamps bool
+	numbers    bool
+	open       bool
+	stream     pb.JobOutput_Stream
+	number     int64
+}
+
+func (p *textPrinter) Print(chunk *pb.JobOutput, data []byte) error {
+	if chunk.Missing > 0 {
+		if err := p.Flush(); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintln(p.w, missingMarker(chunk.Missing))
+		return err
+	}
+	continued := p.open && p.stream == chunk.Stream && p.number == chunk.Line
+	var prefix string
+	if p.open && !continued {
+		prefix = "\n"
+	}
+	if !continued {
+		if p.numbers {
+			prefix += fmt.Sprintf("%6d  ", chunk.Line)
+		}
+		if p.timestamps {
+			prefix += chunk.Time.AsTime().Local().Format(timestampLayout) + " "
+		}
+	}
+	if _, err := fmt.Fprintf(p.w, "%s%s", prefix, data); err != nil {
+		return err
+	}
+	p.open = !bytes.HasSuffix(data, []byte{'\n'})
+	p.stream, p.number = chunk.Stream, chunk.Line
+	return nil
+}
+
+// Flush ends the last line, when it's missing the new line
+func (p *textPrinter) Flush() error {
+	if !p.open {
+		return nil
+	}
+	p.open = false
+	_, err := fmt.Fprintln(p.w)
+	return err
+}
+
+// outputLine is a line of output, with the time and number of the chunk where it starts
+type outputLine struct {
+	time   time.Time
+	number int64
+	stream pb.JobOutput_Stream
+	text   []byte
+}
+
+// linePrinter splits the output in lines, keeping a separate partial line for each stream, and prints
+// every line once it's complete, with printLine
+type linePrinter struct {
+	w         io.Writer
+	pending   map[pb.JobOutput_Stream]*outputLine
+	printLine func(w io.Writer, line *outputLine) error
+}
+
+func (p *linePrinter) Print(chunk *pb.JobOutput, data []byte) error {
+	if p.pending == nil {
+		p.pending = make(map[pb.JobOutput_Stream]*outputLine)
+	}
+	if chunk.Missing > 0 {
+		// the lines cut by the output dropped are printed as they are
+		if err := p.Flush(); err != nil {
+			return err
+		}
+		b, err := json.Marshal(map[string]int64{"dropped": chunk.Missing})
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(p.w, "%s\n", b)
+		return err
+	}
+	for len(data) > 0 {
+		line, ok := p.pending[chunk.Stream]
+		if !ok {
+			line = &outputLine{time: chunk.Time.AsTime(), number: chunk.Line, stream: chunk.Stream}
+			p.pending[chunk.Stream] = line
+		}
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			line.text = append(line.text, data...)
+			return nil
+		}
+		line.text = append(line.text, data[:i]...)
+		data = data[i+1:]
+		delete(p.pending, chunk.Stream)
+		if err := p.printLine(p.w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush prints the lines without a trailing new line, in the order they started
+func (p *linePrinter) Flush() error {
+	lines := slices.Collect(maps.Values(p.pending))
+	slices.SortFunc(lines, func(a, b *outputLine) int {
+		return a.time.Compare(b.time)
+	})
+	clear(p.pending)
+	for _, line := range lines {
+		if err := p.printLine(p.w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printJSONLine(w io.Writer, line *outputLine) error {
+	record := struct {
+		Time   time.Time `json:"time"`
+		Stream string    `json:"stream"`
+		Line   int64     `json:"line,omitempty"`
+		Text   string    `json:"text"`
+	}{
+		Time:   line.time.UTC(),
+		Stream: strings.ToLower(line.stream.String()),
+		Line:   line.number,
+		Text:   string(line.text),
+	}
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
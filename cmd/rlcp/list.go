@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/mhsantos/rlcp/cmd/cli"
+	"github.com/mhsantos/rlcp/cmd/internal/pb"
+)
+
+// listSorts maps the sort fields accepted by the list operation to the ones in the request
+var listSorts = map[string]pb.ListJobsRequest_SortBy{
+	"":          pb.ListJobsRequest_SUBMIT_TIME,
+	"submitted": pb.ListJobsRequest_SUBMIT_TIME,
+	"started":   pb.ListJobsRequest_START_TIME,
+	"ended":     pb.ListJobsRequest_END_TIME,
+	"owner":     pb.ListJobsRequest_OWNER,
+	"command":   pb.ListJobsRequest_COMMAND,
+}
+
+// maxCommandWidth limits the command line shown in the table
+const maxCommandWidth = 50
+
+func callListJobs(client pb.RemoteExecutorClient, option cli.Option) (*pb.ListJobsResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req := &pb.ListJobsRequest{
+		Owner:         option.Owner,
+		Command:       option.Command,
+		SortBy:        listSorts[option.SortBy],
+		Ascending:     option.Ascending,
+		PageSize:      int32(option.PageSize),
+		PageToken:     option.PageToken,
+		LabelSelector: option.Selector,
+	}
+	for _, status := range option.Statuses {
+		req.Statuses = append(req.Statuses, pb.JobDetails_Status(pb.JobDetails_Status_value[strings.ToUpper(status)]))
+	}
+	if !option.Since.IsZero() {
+		req.Since = timestamppb.New(option.Since)
+	}
+	if !option.Until.IsZero() {
+		req.Until = timestamppb.New(option.Until)
+	}
+	resp, err := client.ListJobs(ctx, req)
+	if err != nil {
+		slog.Error("call to client.ListJobs failed", slog.Any("error", err))
+		return nil, err
+	}
+	return resp, nil
+}
+
+// printJobs prints the jobs as a table, followed by the token for the next page, or as a JSON object
+func printJobs(w io.Writer, resp *pb.ListJobsResponse, format string) error {
+	if format == cli.FormatJSON {
+		// the default values are kept, so the jobs always have a status
+		data, err := protojson.MarshalOptions{Multiline: true, EmitDefaultValues: true}.Marshal(resp)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "JOB ID\tSTATUS\tOWNER\tSUBMITTED\tCOMMAND")
+	for _, job := range resp.Jobs {
+		submitted := "-"
+		if job.SubmitTime != nil {
+			submitted = job.SubmitTime.AsTime().Local().Format(time.DateTime)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", job.JobId, job.Status, job.Owner, submitted, shortCommand(job))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if resp.NextPageToken != "" {
+		_, err := fmt.Fprintf(w, "\nMore jobs available, list them with --page-token %s\n", resp.NextPageToken)
+		return err
+	}
+	return nil
+}
+
+// shortCommand returns the command line for the table, cut to maxCommandWidth, or - when it's unknown
+func shortCommand(job *pb.JobDetails) string {
+	var command string
+	switch {
+	case job.Interpreter != "":
+		command = "script: " + commandLine(append([]string{job.Interpreter}, job.Arguments...))
+	case job.Command != "":
+		command = commandLine(append([]string{job.Command}, job.Arguments...))
+	default:
+		return "-"
+	}
+	if runes := []rune(command); len(runes) > maxCommandWidth {
+		command = string(runes[:maxCommandWidth-3]) + "..."
+	}
+	return command
+}
@@ -1,22 +1,43 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"os"
+	"strings"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/mhsantos/rlcp/cmd/cli"
 	"github.com/mhsantos/rlcp/cmd/internal/pb"
 )
 
+// outputPolicies maps the output policies accepted by the cli to the ones in the request
+var outputPolicies = map[string]pb.OutputPolicy{
+	"":          pb.OutputPolicy_DEFAULT,
+	"stop":      pb.OutputPolicy_STOP,
+	"ring":      pb.OutputPolicy_RING,
+	"head-tail": pb.OutputPolicy_HEAD_TAIL,
+}
+
+const (
+	minReconnectBackoff = time.Second
+	maxReconnectBackoff = 30 * time.Second
+)
+
 type Command struct {
 }
 
@@ -84,49 +105,141 @@ func sendCommandToServer(option cli.Option) {
 
 	switch option.Op {
 	case cli.Run:
-		jobId, err := callRunCommand(client, option.Args)
+		jobId, err := callRunCommand(client, option)
 		if err != nil {
 			slog.Error("error scheduling command", slog.Any("error", err))
 			return
 		}
 		fmt.Printf("Job ID: %s\n", jobId)
 	case cli.Status:
-		status, err := callGetStatus(client, option.Args[0])
+		details, err := callGetStatus(client, option.Args[0])
 		if err != nil {
 			slog.Error("error getting status", slog.Any("error", err))
 			return
 		}
-		fmt.Printf("Job Status: %s\n", status)
+		printDetails(os.Stdout, details)
 	case cli.Output:
-		err := callGetOutput(client, option.Args[0])
+		err := callGetOutput(client, option)
 		if err != nil {
 			slog.Error("error getting output", slog.Any("error", err))
 			return
 		}
-	case cli.Stop:
+	case cli.Stop, cli.Signal, cli.Delete:
+		if option.Selector != "" {
+			resp, err := callBulk(client, option)
+			if err != nil {
+				slog.Error("error applying operation to the jobs", slog.Any("error", err))
+				return
+			}
+			printResults(os.Stdout, resp)
+			return
+		}
 		status, err := callStop(client, option.Args[0])
 		if err != nil {
 			slog.Error("error stopping command", slog.Any("error", err))
 			return
 		}
 		fmt.Printf("Job Status: %s\n", status)
+	case cli.Purge:
+		if err := callPurge(client, option.Args[0]); err != nil {
+			slog.Error("error purging job output", slog.Any("error", err))
+			return
+		}
+		fmt.Println("Job output purged")
+	case cli.Artifacts:
+		if err := callDownloadArtifacts(client, option.Args[0], option.Args[1]); err != nil {
+			slog.Error("error downloading artifacts", slog.Any("error", err))
+			return
+		}
+	case cli.Copy:
+		if err := callCopy(client, option); err != nil {
+			slog.Error("error copying file", slog.Any("error", err))
+			return
+		}
+	case cli.SecretSet:
+		if err := callSetSecret(client, option); err != nil {
+			slog.Error("error setting secret", slog.Any("error", err))
+			return
+		}
+		fmt.Printf("Secret %s set\n", option.Args[0])
+	case cli.SecretList:
+		list, err := callListSecrets(client)
+		if err != nil {
+			slog.Error("error listing secrets", slog.Any("error", err))
+			return
+		}
+		for _, secret := range list.Secrets {
+			fmt.Printf("%s\tupdated %s\tusers: %s\n", secret.Name, secret.Updated.AsTime().Format(time.RFC3339), strings.Join(secret.Users, ", "))
+		}
+	case cli.SecretDelete:
+		if err := callDeleteSecret(client, option.Args[0]); err != nil {
+			slog.Error("error deleting secret", slog.Any("error", err))
+			return
+		}
+		fmt.Printf("Secret %s deleted\n", option.Args[0])
+	case cli.List:
+		resp, err := callListJobs(client, option)
+		if err != nil {
+			slog.Error("error listing jobs", slog.Any("error", err))
+			return
+		}
+		if err := printJobs(os.Stdout, resp, option.Format); err != nil {
+			slog.Error("error printing jobs", slog.Any("error", err))
+		}
+	case cli.Wait:
+		code := waitErrorCode
+		event, err := callWait(client, option)
+		switch {
+		case errors.Is(err, errWaitTimeout):
+			fmt.Printf("Job %s is still running after %s\n", option.Args[0], option.Timeout)
+			code = waitTimeoutCode
+		case err != nil:
+			slog.Error("error waiting for the job", slog.Any("error", err))
+		default:
+			code = printExit(os.Stdout, event)
+		}
+		conn.Close()
+		os.Exit(code)
 	default:
-		slog.Error("invalid operation", slog.String("op", string(option.Op)))
+		slog.Error("invalid operation", slog.Any("op", option.Op))
 	}
 }
 
-func callRunCommand(client pb.RemoteExecutorClient, args []string) (string, error) {
+func callRunCommand(client pb.RemoteExecutorClient, option cli.Option) (string, error) {
 	ctx := context.Background()
 
-	var cmdArgs []string
-	if len(args) > 1 {
-		cmdArgs = args[1:]
-	}
-
-	req := &pb.CmdRequest{
-		Command:   args[0],
-		Arguments: cmdArgs,
+	var req *pb.CmdRequest
+	if len(option.Script) > 0 {
+		script, err := os.ReadFile(option.Script)
+		if err != nil {
+			slog.Error("error reading script", slog.String("path", option.Script), slog.Any("error", err))
+			return "", err
+		}
+		req = &pb.CmdRequest{
+			Arguments:   option.Args,
+			Script:      script,
+			Interpreter: option.Interpreter,
+			Artifacts:   option.Artifacts,
+		}
+	} else {
+		args := option.Args
+		var cmdArgs []string
+		if len(args) > 1 {
+			cmdArgs = args[1:]
+		}
+		req = &pb.CmdRequest{
+			Command:   args[0],
+			Arguments: cmdArgs,
+			Artifacts: option.Artifacts,
+		}
 	}
+	req.MaxOutputBytes = option.MaxOutput
+	req.OutputPolicy = outputPolicies[option.OutputPolicy]
+	req.SecretEnv = option.SecretEnv
+	req.Description = option.Description
+	req.Labels = option.Labels
+	req.Name = option.Name
+	req.Webhooks = option.Webhooks
 	resp, err := client.ExecCommand(ctx, req)
 	if err != nil {
 		slog.Error("error calling server", slog.Any("error", err))
@@ -135,34 +248,123 @@ func callRunCommand(client pb.RemoteExecutorClient, args []string) (string, erro
 	return resp.JobId, nil
 }
 
-func callGetOutput(client pb.RemoteExecutorClient, jobId string) error {
-	stream, err := client.GetOutput(context.Background(), &pb.GetRequest{JobId: jobId})
+// callGetOutput prints the job output. When following the output, the stream is resumed from the last offset
+// received after transport errors, so no output is duplicated or lost.
+func callGetOutput(client pb.RemoteExecutorClient, option cli.Option) error {
+	req := &pb.GetRequest{
+		JobId:     option.Args[0],
+		Mode:      pb.GetRequest_SNAPSHOT,
+		TailLines: option.TailLines,
+		TailBytes: option.TailBytes,
+		Include:   option.Grep,
+		Exclude:   option.Exclude,
+		MaxLines:  option.MaxLines,
+		Lines:     option.Lines || option.Timestamps || option.Format == cli.FormatNDJSON,
+	}
+	if !option.Since.IsZero() {
+		req.Since = timestamppb.New(option.Since)
+	}
+	if !option.Until.IsZero() {
+		req.Until = timestamppb.New(option.Until)
+	}
+	if option.Follow {
+		req.Mode = pb.GetRequest_FOLLOW
+	}
+
+	printer := newOutputPrinter(option, os.Stdout)
+	printed := make(map[int64]bool)
+	backoff := minReconnectBackoff
+	for {
+		received, err := streamOutput(client, req, printer, printed)
+		if err == nil {
+			return printer.Flush()
+		}
+		if !option.Follow || status.Code(err) != codes.Unavailable {
+			printer.Flush()
+			return err
+		}
+		if received {
+			// the tail was already resolved by the server, so the stream resumes from the offset
+			req.TailLines, req.TailBytes = 0, 0
+			backoff = minReconnectBackoff
+		}
+		slog.Warn("lost connection to the server, reconnecting", slog.Duration("backoff", backoff), slog.Int64("offset", req.Offset))
+		time.Sleep(backoff)
+		backoff = min(2*backoff, maxReconnectBackoff)
+	}
+}
+
+// streamOutput prints the job output starting at req.Offset, which is updated as the output is received.
+// It returns true if any output was received.
+// When streaming lines, printed has the offsets of the lines printed after req.Offset, which the server
+// sends again when resuming from it, and req.MaxLines is updated with the lines left to print.
+func streamOutput(client pb.RemoteExecutorClient, req *pb.GetRequest, printer outputPrinter, printed map[int64]bool) (bool, error) {
+	stream, err := client.GetOutput(context.Background(), req)
 	if err != nil {
 		slog.Error("call to client.GetResult failed", slog.Any("error", err))
-		return err
+		return false, err
 	}
+	received := false
 	for {
 		output, err := stream.Recv()
 		if err == io.EOF {
-			return nil
+			return received, nil
 		}
 		if err != nil {
 			slog.Error("client.GetResult stream iteration failed", slog.Any("error", err))
-			return err
+			return received, err
+		}
+		received = true
+		if streamsLines(req) {
+			if printed[output.Offset] {
+				continue
+			}
+			if err := printer.Print(output, output.Output); err != nil {
+				return received, err
+			}
+			printed[output.Offset] = true
+			req.Offset = max(req.Offset, output.NextOffset)
+			maps.DeleteFunc(printed, func(offset int64, _ bool) bool {
+				return offset < req.Offset
+			})
+			if req.MaxLines > 0 {
+				req.MaxLines--
+				if req.MaxLines == 0 {
+					return received, nil
+				}
+			}
+			continue
+		}
+
+		// skips anything already printed, in case the server resends it
+		if output.NextOffset <= req.Offset {
+			continue
+		}
+		data := output.Output
+		if skip := req.Offset - output.Offset; skip > 0 {
+			data = data[min(skip, int64(len(data))):]
 		}
-		fmt.Print(string(output.Output))
+		if err := printer.Print(output, data); err != nil {
+			return received, err
+		}
+		req.Offset = max(req.Offset, output.NextOffset)
 	}
 }
 
-func callGetStatus(client pb.RemoteExecutorClient, jobId string) (string, error) {
+// streamsLines returns true if the server sends the output line by line, when requested or when filtering it
+func streamsLines(req *pb.GetRequest) bool {
+	return req.Lines || req.Include != "" || req.Exclude != "" || req.Since != nil || req.Until != nil || req.MaxLines > 0
+}
+
+func callGetStatus(client pb.RemoteExecutorClient, jobId string) (*pb.JobDetails, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	status, err := client.GetStatus(ctx, &pb.GetRequest{JobId: jobId})
+	details, err := client.GetStatus(ctx, &pb.GetRequest{JobId: jobId})
 	if err != nil {
 		slog.Error("call to client.GetStatus failed", slog.Any("error", err))
-		return "", err
+		return nil, err
 	}
-	return status.Status.String(), nil
+	return details, nil
 }
 
 func callStop(client pb.RemoteExecutorClient, jobId string) (string, error) {
@@ -180,3 +382,60 @@ func callStop(client pb.RemoteExecutorClient, jobId string) (string, error) {
 	}
 	return status.Status.String(), nil
 }
+
+func callPurge(client pb.RemoteExecutorClient, jobId string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := client.PurgeJobOutput(ctx, &pb.PurgeRequest{JobId: jobId})
+	if err != nil {
+		slog.Error("call to client.PurgeJobOutput failed", slog.Any("error", err))
+		return err
+	}
+	return nil
+}
+
+// callSetSecret stores a secret on the server, with the value read from the standard input, so it doesn't
+// show up in the shell history. The trailing new line is not part of the value.
+func callSetSecret(client pb.RemoteExecutorClient, option cli.Option) error {
+	value, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		slog.Error("error reading the secret value", slog.Any("error", err))
+		return err
+	}
+	value = bytes.TrimSuffix(bytes.TrimSuffix(value, []byte("\n")), []byte("\r"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err = client.SetSecret(ctx, &pb.SecretRequest{
+		Name:  option.Args[0],
+		Value: string(value),
+		Users: option.Users,
+	})
+	if err != nil {
+		slog.Error("call to client.SetSecret failed", slog.Any("error", err))
+		return err
+	}
+	return nil
+}
+
+func callListSecrets(client pb.RemoteExecutorClient) (*pb.SecretList, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	list, err := client.ListSecrets(ctx, &emptypb.Empty{})
+	if err != nil {
+		slog.Error("call to client.ListSecrets failed", slog.Any("error", err))
+		return nil, err
+	}
+	return list, nil
+}
+
+func callDeleteSecret(client pb.RemoteExecutorClient, name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := client.DeleteSecret(ctx, &pb.SecretRequest{Name: name})
+	if err != nil {
+		slog.Error("call to client.DeleteSecret failed", slog.Any("error", err))
+		return err
+	}
+	return nil
+}
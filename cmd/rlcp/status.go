@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/mhsantos/rlcp/cmd/internal/pb"
+)
+
+// detailsLayout is the layout of the times shown by the status operation
+const detailsLayout = "2006-01-02 15:04:05.000 MST"
+
+// printDetails prints the details of a job as a block of labeled lines, skipping the ones not set
+func printDetails(w io.Writer, details *pb.JobDetails) {
+	line := func(label, format string, args ...any) {
+		fmt.Fprintf(w, "%-13s"+format+"\n", append([]any{label + ":"}, args...)...)
+	}
+	line("Job ID", "%s", details.JobId)
+	if details.Name != "" {
+		line("Name", "%s", details.Name)
+	}
+	line("Status", "%s", details.Status)
+	if details.StatusMessage != "" {
+		line("Reason", "%s", details.StatusMessage)
+	}
+	if details.Owner != "" {
+		line("Owner", "%s", details.Owner)
+	}
+	if details.Description != "" {
+		line("Description", "%s", details.Description)
+	}
+	switch {
+	case details.Interpreter != "" && len(details.Arguments) > 0:
+		line("Command", "script run by %s with %s", details.Interpreter, commandLine(details.Arguments))
+	case details.Interpreter != "":
+		line("Command", "script run by %s", details.Interpreter)
+	case details.Command != "":
+		line("Command", "%s", commandLine(append([]string{details.Command}, details.Arguments...)))
+	}
+	if len(details.Labels) > 0 {
+		labels := make([]string, 0, len(details.Labels))
+		for _, key := range slices.Sorted(maps.Keys(details.Labels)) {
+			labels = append(labels, key+"="+details.Labels[key])
+		}
+		line("Labels", "%s", strings.Join(labels, ","))
+	}
+	if details.Host != "" {
+		if details.Pid > 0 {
+			line("Host", "%s, pid %d", details.Host, details.Pid)
+		} else {
+			line("Host", "%s", details.Host)
+		}
+	}
+	if details.SubmitTime != nil {
+		line("Submitted", "%s", formatTime(details.SubmitTime))
+	}
+	if details.StartTime != nil {
+		line("Started", "%s", formatTime(details.StartTime))
+	}
+	switch {
+	case details.EndTime != nil && details.StartTime != nil:
+		line("Ended", "%s, after %s", formatTime(details.EndTime), duration(details.StartTime, details.EndTime))
+	case details.EndTime != nil:
+		line("Ended", "%s", formatTime(details.EndTime))
+	case details.StartTime != nil:
+		line("Running for", "%s", duration(details.StartTime, timestamppb.Now()))
+	}
+	if details.EndTime != nil && details.ExitCode >= 0 {
+		line("Exit code", "%d", details.ExitCode)
+	}
+	line("Output", "%d bytes, %d bytes stored", details.OutputBytes, details.StoredBytes)
+	if details.Truncation != pb.OutputPolicy_DEFAULT {
+		line("Truncated", "by the %s policy, %d bytes dropped", details.Truncation, details.DroppedBytes)
+	}
+	if details.Redactions > 0 {
+		line("Redacted", "%d secrets", details.Redactions)
+	}
+	for _, artifact := range details.Artifacts {
+		line("Artifact", "%s", artifact)
+	}
+	for _, delivery := range details.WebhookDeliveries {
+		result := fmt.Sprintf("status %d", delivery.StatusCode)
+		if delivery.Error != "" {
+			result = delivery.Error
+		}
+		line("Webhook", "%s, attempt %d at %s: %s", delivery.Url, delivery.Attempt, formatTime(delivery.Time), result)
+	}
+}
+
+// commandLine joins the words of a command line, quoting the ones which would be split by the shell
+func commandLine(words []string) string {
+	quoted := make([]string, 0, len(words))
+	for _, word := range words {
+		if word == "" || strings.ContainsAny(word, " \t\n'\"\\$`|&;<>()*?") {
+			word = "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+		}
+		quoted = append(quoted, word)
+	}
+	return strings.Join(quoted, " ")
+}
+
+func formatTime(t *timestamppb.Timestamp) string {
+	return t.AsTime().Local().Format(detailsLayout)
+}
+
+// duration returns the time between start and end, rounded for display
+func duration(start, end *timestamppb.Timestamp) time.Duration {
+	d := end.AsTime().Sub(start.AsTime())
+	if d >= time.Second {
+		return d.Round(time.Second / 10)
+	}
+	return d.Round(time.Millisecond)
+}
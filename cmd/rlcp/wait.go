@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mhsantos/rlcp/cmd/cli"
+	"github.com/mhsantos/rlcp/cmd/internal/pb"
+)
+
+// Exit codes of the wait operation, besides the exit code of the job. waitTimeoutCode is the one used by timeout(1).
+const (
+	waitErrorCode   = 1
+	waitTimeoutCode = 124
+)
+
+// errWaitTimeout is returned when the job is still running once the wait operation times out
+var errWaitTimeout = errors.New("the job is still running")
+
+// callWait waits for the job to end, and returns the event for its end. The job is watched again when the
+// connection to the server is lost, since the server sends the end of a finished job right away.
+func callWait(client pb.RemoteExecutorClient, option cli.Option) (*pb.JobEvent, error) {
+	ctx := context.Background()
+	if option.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, option.Timeout)
+		defer cancel()
+	}
+	req := &pb.WatchRequest{JobId: option.Args[0]}
+	backoff := minReconnectBackoff
+	for {
+		event, err := watchExit(ctx, client, req)
+		if err == nil {
+			return event, nil
+		}
+		if status.Code(err) == codes.DeadlineExceeded || ctx.Err() != nil {
+			return nil, errWaitTimeout
+		}
+		if status.Code(err) != codes.Unavailable {
+			return nil, err
+		}
+		slog.Warn("lost connection to the server, reconnecting", slog.Duration("backoff", backoff))
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, errWaitTimeout
+		}
+		backoff = min(2*backoff, maxReconnectBackoff)
+	}
+}
+
+// watchExit watches the job until the event for its end is received
+func watchExit(ctx context.Context, client pb.RemoteExecutorClient, req *pb.WatchRequest) (*pb.JobEvent, error) {
+	stream, err := client.WatchJobs(ctx, req)
+	if err != nil {
+		slog.Error("call to client.WatchJobs failed", slog.Any("error", err))
+		return nil, err
+	}
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			return nil, status.Errorf(codes.Unavailable, "the stream ended before the job")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if event.Type == pb.JobEvent_EXITED || event.Type == pb.JobEvent_STOPPED {
+			return event, nil
+		}
+	}
+}
+
+// printExit prints how the job ended, and returns the exit code for the wait operation
+func printExit(w io.Writer, event *pb.JobEvent) int {
+	job := event.Job
+	if job.ExitCode < 0 {
+		fmt.Fprintf(w, "Job %s %s, exit code unknown\n", job.JobId, job.Status)
+		return waitErrorCode
+	}
+	fmt.Fprintf(w, "Job %s %s, exit code %d\n", job.JobId, job.Status, job.ExitCode)
+	return int(job.ExitCode)
+}
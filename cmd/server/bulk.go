@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/mhsantos/rlcp/cmd/internal/pb"
+	"github.com/mhsantos/rlcp/cmd/server/internal/executor"
+	"github.com/mhsantos/rlcp/cmd/server/internal/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// signals maps the names of the signals accepted by SignalJobs, without the SIG prefix
+var signals = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+	"TERM": syscall.SIGTERM,
+	"CONT": syscall.SIGCONT,
+	"STOP": syscall.SIGSTOP,
+	"TSTP": syscall.SIGTSTP,
+}
+
+func (s *server) StopJobs(ctx context.Context, req *pb.SelectorRequest) (*pb.BulkResponse, error) {
+	jobs, err := s.selectJobs(ctx, storage.Stop, req.Selector)
+	if err != nil {
+		return nil, err
+	}
+	return bulkResults(jobs, stopJob), nil
+}
+
+func (s *server) SignalJobs(ctx context.Context, req *pb.SelectorRequest) (*pb.BulkResponse, error) {
+	sig, ok := signals[strings.TrimPrefix(strings.ToUpper(req.Signal), "SIG")]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid signal %q", req.Signal)
+	}
+	jobs, err := s.selectJobs(ctx, storage.Stop, req.Selector)
+	if err != nil {
+		return nil, err
+	}
+	return bulkResults(jobs, func(job *storage.Job) error {
+		if job.Status() != storage.Running || job.Cmd == nil || job.Cmd.Process == nil {
+			return status.Errorf(codes.FailedPrecondition, "The job is not running")
+		}
+		if err := executor.SignalJob(job, sig); err != nil {
+			return status.Errorf(codes.Unknown, "Error signaling the process: %v", err)
+		}
+		return nil
+	}), nil
+}
+
+func (s *server) DeleteJobs(ctx context.Context, req *pb.SelectorRequest) (*pb.BulkResponse, error) {
+	jobs, err := s.selectJobs(ctx, storage.Purge, req.Selector)
+	if err != nil {
+		return nil, err
+	}
+	return bulkResults(jobs, func(job *storage.Job) error {
+		if !job.Finished() {
+			return status.Errorf(codes.FailedPrecondition, "The job can only be deleted after it ends")
+		}
+		if err := job.Remove(); err != nil {
+			slog.Error("error removing job files", slog.String("jobid", job.Id.String()), slog.Any("error", err))
+			return status.Errorf(codes.Internal, "Error removing the job files: %v", err)
+		}
+		s.db.DeleteJob(job.Id.String())
+		return nil
+	}), nil
+}
+
+// selectJobs returns the jobs matching the selector, sorted by submit time, after checking the user is allowed
+// to execute the operation. Users other than admins only select their own jobs.
+// The selector is required, so a missing one doesn't select every job.
+func (s *server) selectJobs(ctx context.Context, op storage.Operation, text string) ([]*storage.Job, error) {
+	userId, err := s.authorize(ctx, op)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "the label selector must not be empty")
+	}
+	sel, err := parseSelector(text)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	owner := ""
+	if !s.db.Authorized(userId, storage.ListAll) {
+		owner = getRequesterEmail(ctx)
+	}
+
+	var jobs []*storage.Job
+	for _, job := range s.db.ListJobs() {
+		if (owner == "" || job.User == owner) && sel.matches(job.Labels) {
+			jobs = append(jobs, job)
+		}
+	}
+	slices.SortFunc(jobs, func(a, b *storage.Job) int {
+		return a.SubmitTime.Compare(b.SubmitTime)
+	})
+	return jobs, nil
+}
+
+// bulkResults applies the operation to each job, and returns the result for each of them
+func bulkResults(jobs []*storage.Job, op func(*storage.Job) error) *pb.BulkResponse {
+	resp := &pb.BulkResponse{}
+	for _, job := range jobs {
+		result := &pb.JobResult{JobId: job.Id.String(), Ok: true}
+		if err := op(job); err != nil {
+			result.Ok = false
+			result.Error = status.Convert(err).Message()
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	return resp
+}
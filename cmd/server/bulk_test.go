@@ -0,0 +1,176 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mhsantos/rlcp/cmd/internal/pb"
+	"github.com/mhsantos/rlcp/cmd/server/internal/executor"
+	"github.com/mhsantos/rlcp/cmd/server/internal/storage"
+)
+
+// runLabeledJob starts a job from the admin which runs until it's stopped, with the labels
+func runLabeledJob(t *testing.T, s *server, labels map[string]string) *storage.Job {
+	t.Helper()
+	details, err := s.ExecCommand(userContext(adminEmail), &pb.CmdRequest{
+		Command:   "sleep",
+		Arguments: []string{"60"},
+		Labels:    labels,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, ok := s.db.GetJob(details.JobId)
+	if !ok {
+		t.Fatal("the job was not saved")
+	}
+	t.Cleanup(func() {
+		executor.SignalJob(job, syscall.SIGKILL)
+		<-job.Exited()
+	})
+	return job
+}
+
+// results returns the job ids and the errors in the bulk response
+func results(resp *pb.BulkResponse) map[string]string {
+	res := make(map[string]string)
+	for _, result := range resp.GetResults() {
+		if result.Ok != (result.Error == "") {
+			return map[string]string{result.JobId: "ok and error: " + result.Error}
+		}
+		res[result.JobId] = result.Error
+	}
+	return res
+}
+
+func TestBulkOperations(t *testing.T) {
+	s := newTestServer(t)
+	first := runLabeledJob(t, s, map[string]string{"deploy": "42", "role": "migrate"})
+	second := runLabeledJob(t, s, map[string]string{"deploy": "42", "role": "web"})
+	other := runLabeledJob(t, s, map[string]string{"deploy": "43", "role": "web"})
+	finished := addJob(t, s, readerEmail, "00000000-0000-0000-0000-000000000001", "")
+	finished.Labels = map[string]string{"deploy": "42"}
+	admin := userContext(adminEmail)
+
+	tests := []struct {
+		name     string
+		op       func() (*pb.BulkResponse, error)
+		expected map[string]string
+		code     codes.Code
+	}{
+		{name: "signal", op: func() (*pb.BulkResponse, error) {
+			return s.SignalJobs(admin, &pb.SelectorRequest{Selector: "deploy=42,role=migrate", Signal: "sigcont"})
+		}, expected: map[string]string{first.Id.String(): ""}},
+		{name: "invalid signal", op: func() (*pb.BulkResponse, error) {
+			return s.SignalJobs(admin, &pb.SelectorRequest{Selector: "deploy=42", Signal: "SIGSEGV"})
+		}, code: codes.InvalidArgument},
+		{name: "empty selector", op: func() (*pb.BulkResponse, error) {
+			return s.StopJobs(admin, &pb.SelectorRequest{Selector: " "})
+		}, code: codes.InvalidArgument},
+		{name: "stop as reader", op: func() (*pb.BulkResponse, error) {
+			return s.StopJobs(userContext(readerEmail), &pb.SelectorRequest{Selector: "deploy=42"})
+		}, code: codes.PermissionDenied},
+		{name: "delete running jobs", op: func() (*pb.BulkResponse, error) {
+			return s.DeleteJobs(admin, &pb.SelectorRequest{Selector: "role=web"})
+		}, expected: map[string]string{
+			second.Id.String(): "The job can only be deleted after it ends",
+			other.Id.String():  "The job can only be deleted after it ends",
+		}},
+		{name: "stop", op: func() (*pb.BulkResponse, error) {
+			return s.StopJobs(admin, &pb.SelectorRequest{Selector: "deploy=42"})
+		}, expected: map[string]string{
+			first.Id.String():    "",
+			second.Id.String():   "",
+			finished.Id.String(): "The job is not running",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tt.op()
+			if status.Code(err) != tt.code {
+				t.Fatalf("the operation returned %v, expected code %s", err, tt.code)
+			}
+			if res := results(resp); len(res)+len(tt.expected) > 0 && !cmp.Equal(res, tt.expected) {
+				t.Fatalf("unexpected results: %v", cmp.Diff(tt.expected, res))
+			}
+		})
+	}
+
+	for _, job := range []*storage.Job{first, second} {
+		select {
+		case <-job.Exited():
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the stopped job to exit")
+		}
+		if job.Status() != storage.Stopped {
+			t.Fatalf("unexpected status for the stopped job: %s", job.Status())
+		}
+	}
+	if other.Status() != storage.Running {
+		t.Fatalf("the job from another deploy was stopped: %s", other.Status())
+	}
+
+	// the jobs which ended are deleted
+	resp, err := s.DeleteJobs(admin, &pb.SelectorRequest{Selector: "deploy=42"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := results(resp); len(res) != 3 || res[first.Id.String()] != "" || res[finished.Id.String()] != "" {
+		t.Fatalf("unexpected results: %v", res)
+	}
+	for _, job := range []*storage.Job{first, second, finished} {
+		if _, ok := s.db.GetJob(job.Id.String()); ok {
+			t.Fatalf("the job %s was not deleted", job.Id)
+		}
+	}
+}
+
+func TestStopJob(t *testing.T) {
+	s := newTestServer(t)
+	running := runLabeledJob(t, s, nil)
+	if filepath.Dir(running.Workspace) != s.cfg.workspaceRoot {
+		t.Fatalf("the workspace %s is not under the workspace root %s", running.Workspace, s.cfg.workspaceRoot)
+	}
+	finished := addJob(t, s, readerEmail, "00000000-0000-0000-0000-000000000001", "")
+	// a job whose process exited before its status was updated
+	exited := addJob(t, s, adminEmail, "00000000-0000-0000-0000-000000000002", "")
+	exited.Cmd = exec.Command("true")
+	if err := exited.Cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	exited.SetStatus(storage.Running, "")
+
+	tests := []struct {
+		name string
+		user string
+		job  *storage.Job
+		code codes.Code
+	}{
+		{name: "reader", user: readerEmail, job: finished, code: codes.PermissionDenied},
+		{name: "finished job", user: adminEmail, job: finished, code: codes.FailedPrecondition},
+		{name: "running job", user: adminEmail, job: running, code: codes.OK},
+		{name: "exited process", user: adminEmail, job: exited, code: codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.StopJob(userContext(tt.user), &pb.StopRequest{JobId: tt.job.Id.String()})
+			if status.Code(err) != tt.code {
+				t.Fatalf("StopJob returned %v, expected code %s", err, tt.code)
+			}
+		})
+	}
+
+	<-running.Exited()
+	for _, job := range []*storage.Job{running, exited} {
+		if job.Status() != storage.Stopped {
+			t.Fatalf("unexpected status for the stopped job: %s", job.Status())
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mhsantos/rlcp/cmd/server/internal/s3store"
+	"github.com/mhsantos/rlcp/cmd/server/internal/secrets"
+	"github.com/mhsantos/rlcp/cmd/server/internal/sink"
+	"github.com/mhsantos/rlcp/cmd/server/internal/storage"
+	"github.com/mhsantos/rlcp/cmd/server/internal/webhook"
+)
+
+// config has the server settings, parsed from the command line flags
+type config struct {
+	log           storage.LogOptions
+	retention     storage.RetentionPolicy
+	gcInterval    time.Duration
+	secrets       *secrets.Store
+	sinks         []sink.Sink
+	jobStore      string
+	jobDB         string
+	workspaceRoot string
+	webhooks      []string
+	webhook       webhook.Options
+	tailLines     int64
+}
+
+// outputPolicies maps the names accepted for the output policy
+var outputPolicies = map[string]storage.OutputPolicy{
+	"stop":      storage.StopJob,
+	"ring":      storage.DropOldest,
+	"head-tail": storage.KeepHeadTail,
+}
+
+func parseConfig(args []string) (config, error) {
+	var cfg config
+	flags := flag.NewFlagSet("server", flag.ContinueOnError)
+	var store storeFlags
+	store.register(flags)
+	flags.BoolVar(&cfg.log.Compress, "compress-logs", true, "compress the output files written to the output store")
+	flags.Int64Var(&cfg.log.MaxBytes, "max-output-bytes", 0, "maximum output stored for each job, 0 for no limit")
+	maxTotalOutput := flags.Int64("max-total-output-bytes", 0, "maximum output stored by all the running jobs together, 0 for no limit")
+	cfg.log.Policy = storage.StopJob
+	flags.Func("output-policy", "what happens once a job gets to the output limit: stop, ring or head-tail", func(value string) error {
+		policy, ok := outputPolicies[value]
+		if !ok {
+			return fmt.Errorf("invalid output policy %q", value)
+		}
+		cfg.log.Policy = policy
+		return nil
+	})
+	var redactPatterns []string
+	flags.Func("redact-pattern", "regular expression masked in the job output, can be repeated", func(value string) error {
+		redactPatterns = append(redactPatterns, value)
+		return nil
+	})
+	masterKey := flags.String("master-key", "", fmt.Sprintf("file with the %d bytes key wrapping the keys which encrypt the output files", storage.KeySize))
+	secretsFile := flags.String("redact-secrets", "", "file with secret values masked in the job output, one per line")
+	secretStore := flags.String("secrets-file", "", "file where the secrets injected into the jobs are stored, encrypted")
+	secretKey := flags.String("secrets-key", "", fmt.Sprintf("file with the %d bytes key used to encrypt the secrets file", secrets.KeySize))
+	flags.StringVar(&cfg.jobStore, "job-store", "memory", "where the jobs are kept: memory, or bolt to keep them across restarts")
+	flags.StringVar(&cfg.jobDB, "job-db", "rlcp.db", "database file for the bolt job store")
+	flags.StringVar(&cfg.workspaceRoot, "workspace-root", "workspaces", "directory where the job workspaces are created")
+	var sinks sinkFlags
+	sinks.register(flags)
+	flags.DurationVar(&cfg.retention.MaxAge, "retention-max-age", 0, "how long finished jobs are kept, 0 keeps them forever")
+	flags.Int64Var(&cfg.retention.MaxBytes, "retention-max-bytes", 0, "maximum size of the output kept for all jobs, 0 for no limit")
+	flags.IntVar(&cfg.retention.MaxJobs, "retention-max-jobs", 0, "maximum number of jobs kept, 0 for no limit")
+	flags.DurationVar(&cfg.gcInterval, "gc-interval", time.Minute, "how often the retention policy is enforced")
+	flags.Func("webhook", "url posted to once any job ends, can be repeated", func(value string) error {
+		// the webhooks set by the operator may call the hosts in private networks
+		if err := webhook.ValidateURL(value, true); err != nil {
+			return err
+		}
+		cfg.webhooks = append(cfg.webhooks, value)
+		return nil
+	})
+	webhookKey := flags.String("webhook-key", "", "file with the key signing the webhook notifications with HMAC-SHA256")
+	flags.IntVar(&cfg.webhook.MaxAttempts, "webhook-attempts", 5, "attempts to deliver each webhook notification")
+	flags.DurationVar(&cfg.webhook.Timeout, "webhook-timeout", 10*time.Second, "how long each attempt to call a webhook waits for the response")
+	flags.BoolVar(&cfg.webhook.AllowPrivate, "webhook-allow-private", false, "let the webhooks of the jobs call private, loopback and link-local addresses")
+	flags.Int64Var(&cfg.tailLines, "webhook-tail-lines", 20, "last lines of the job output sent to the webhooks")
+	if err := flags.Parse(args); err != nil {
+		return config{}, err
+	}
+	var err error
+	if cfg.log.Store, err = store.open(); err != nil {
+		return config{}, err
+	}
+	if cfg.sinks, err = sinks.sinks(); err != nil {
+		return config{}, err
+	}
+	if cfg.jobStore != "memory" && cfg.jobStore != "bolt" {
+		return config{}, fmt.Errorf("invalid job store %q", cfg.jobStore)
+	}
+	if cfg.log.MaxBytes < 0 || *maxTotalOutput < 0 {
+		return config{}, fmt.Errorf("max output bytes must not be negative")
+	}
+	cfg.log.Budget = storage.NewOutputBudget(*maxTotalOutput)
+	if cfg.webhook.MaxAttempts <= 0 || cfg.webhook.Timeout <= 0 || cfg.tailLines < 0 {
+		return config{}, fmt.Errorf("the webhook attempts and timeout must be positive, and the tail lines must not be negative")
+	}
+	if *webhookKey != "" {
+		if cfg.webhook.Key, err = readWebhookKey(*webhookKey); err != nil {
+			return config{}, err
+		}
+	}
+	var redactSecrets []string
+	if *secretsFile != "" {
+		if redactSecrets, err = readSecrets(*secretsFile); err != nil {
+			return config{}, err
+		}
+	}
+	redactor, err := storage.NewRedactor(redactPatterns, redactSecrets)
+	if err != nil {
+		return config{}, err
+	}
+	cfg.log.Redactor = redactor
+	if *masterKey != "" {
+		if cfg.log.MasterKey, err = storage.LoadMasterKey(*masterKey); err != nil {
+			return config{}, err
+		}
+	}
+
+	if (*secretStore == "") != (*secretKey == "") {
+		return config{}, fmt.Errorf("-secrets-file and -secrets-key must be informed together")
+	}
+	if *secretStore != "" {
+		if cfg.secrets, err = secrets.Open(*secretStore, *secretKey); err != nil {
+			return config{}, err
+		}
+	}
+	return cfg, nil
+}
+
+// storeFlags selects the backend where the job output is stored
+type storeFlags struct {
+	backend string
+	logDir  string
+	s3      s3store.Config
+}
+
+// register adds the flags for the output store. The S3 credentials are read from the standard AWS
+// environment variables, so they are not exposed on the command line.
+func (f *storeFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&f.backend, "output-store", "local", "where the job output is stored: local, memory or s3")
+	flags.StringVar(&f.logDir, "log-dir", "logs", "directory where the job output is stored by the local store, in a subdirectory per job")
+	flags.StringVar(&f.s3.Endpoint, "s3-endpoint", "https://s3.amazonaws.com", "URL of the S3 compatible object store")
+	flags.StringVar(&f.s3.Bucket, "s3-bucket", "", "bucket where the job output is stored")
+	flags.StringVar(&f.s3.Region, "s3-region", "us-east-1", "region of the bucket")
+	flags.StringVar(&f.s3.Prefix, "s3-prefix", "", "prefix for the objects with the job output")
+	flags.BoolVar(&f.s3.PathStyle, "s3-path-style", true, "address the bucket in the URL path instead of the host name")
+}
+
+// open returns the output store selected by the flags
+func (f *storeFlags) open() (storage.OutputStore, error) {
+	switch f.backend {
+	case "local":
+		return storage.NewLocalStore(f.logDir), nil
+	case "memory":
+		return storage.NewMemoryStore(), nil
+	case "s3":
+		f.s3.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+		f.s3.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		f.s3.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+		store, err := s3store.New(f.s3)
+		if err != nil {
+			return nil, fmt.Errorf("invalid s3 store: %w", err)
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("invalid output store %q", f.backend)
+	}
+}
+
+// sinkFlags selects the log sinks the job output is forwarded to, all of them sharing the same queue options
+type sinkFlags struct {
+	syslog       string
+	file         string
+	fileMaxBytes int64
+	fileMaxFiles int
+	http         string
+	queue        sink.QueueOptions
+}
+
+func (f *sinkFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&f.syslog, "sink-syslog", "", "forward the job output to a syslog server, at udp://host:port, tcp://host:port or unix:///path")
+	flags.StringVar(&f.file, "sink-file", "", "forward the job output to a local file, one JSON object per line")
+	flags.Int64Var(&f.fileMaxBytes, "sink-file-max-bytes", 100*1024*1024, "size at which the sink file is rotated")
+	flags.IntVar(&f.fileMaxFiles, "sink-file-max-files", 5, "number of rotated sink files kept")
+	flags.StringVar(&f.http, "sink-http", "", "forward the job output to an HTTP endpoint, posting batches as JSON arrays")
+	flags.IntVar(&f.queue.MaxRecords, "sink-queue-size", 100000, "lines queued for each sink, the oldest ones are dropped past that")
+	flags.IntVar(&f.queue.BatchSize, "sink-batch-size", 500, "most lines sent to a sink at once")
+	flags.DurationVar(&f.queue.FlushInterval, "sink-flush-interval", time.Second, "how long the lines wait for a batch to fill up")
+}
+
+// sinks returns the sinks selected by the flags
+func (f *sinkFlags) sinks() ([]sink.Sink, error) {
+	if f.queue.MaxRecords <= 0 || f.queue.BatchSize <= 0 || f.queue.FlushInterval <= 0 {
+		return nil, fmt.Errorf("the sink queue size, batch size and flush interval must be positive")
+	}
+	var sinks []sink.Sink
+	if f.syslog != "" {
+		backend, err := sink.NewSyslog(f.syslog)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink.Sink{Name: "syslog", Backend: backend, Queue: f.queue})
+	}
+	if f.file != "" {
+		sinks = append(sinks, sink.Sink{Name: "file", Backend: sink.NewFile(f.file, f.fileMaxBytes, f.fileMaxFiles), Queue: f.queue})
+	}
+	if f.http != "" {
+		u, err := url.Parse(f.http)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid sink url %q", f.http)
+		}
+		sinks = append(sinks, sink.Sink{Name: "http", Backend: sink.NewHTTP(f.http), Queue: f.queue})
+	}
+	return sinks, nil
+}
+
+// readWebhookKey reads the key signing the webhook notifications, ignoring the spaces around it
+func readWebhookKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the webhook key: %w", err)
+	}
+	key := bytes.TrimSpace(data)
+	if len(key) == 0 {
+		return nil, fmt.Errorf("the webhook key file %s is empty", path)
+	}
+	return key, nil
+}
+
+// readSecrets reads the secret values from a file, one per line, ignoring empty lines
+func readSecrets(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the secrets file: %w", err)
+	}
+	var secrets []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimRight(line, "\r"); line != "" {
+			secrets = append(secrets, line)
+		}
+	}
+	return secrets, nil
+}
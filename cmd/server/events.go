@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mhsantos/rlcp/cmd/internal/pb"
+	"github.com/mhsantos/rlcp/cmd/server/internal/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// watcherBuffer is the number of events queued for a watcher, which is dropped once it falls further behind
+const watcherBuffer = 256
+
+// jobEvent is an event sent to the watchers, along with the job it's about
+type jobEvent struct {
+	job   *storage.Job
+	event *pb.JobEvent
+}
+
+// watcher receives the events from the jobs it matches. lagged is closed when the watcher is dropped, because
+// it didn't keep up with the events.
+type watcher struct {
+	match  func(*storage.Job) bool
+	events chan jobEvent
+	lagged chan struct{}
+}
+
+// eventHub sends the lifecycle events from the jobs to the watchers. Publishing never waits for the watchers.
+type eventHub struct {
+	mu       sync.Mutex
+	watchers map[*watcher]struct{}
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{watchers: make(map[*watcher]struct{})}
+}
+
+// subscribe returns a watcher receiving the events from the jobs matched by match
+func (h *eventHub) subscribe(match func(*storage.Job) bool) *watcher {
+	w := &watcher{
+		match:  match,
+		events: make(chan jobEvent, watcherBuffer),
+		lagged: make(chan struct{}),
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.watchers[w] = struct{}{}
+	return w
+}
+
+func (h *eventHub) unsubscribe(w *watcher) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.watchers, w)
+}
+
+// publish sends the event from the job to the watchers matching it
+func (h *eventHub) publish(job *storage.Job, eventType pb.JobEvent_Type) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var event *pb.JobEvent
+	for w := range h.watchers {
+		if !w.match(job) {
+			continue
+		}
+		if event == nil {
+			event = newJobEvent(job, eventType)
+		}
+		select {
+		case w.events <- jobEvent{job, event}:
+		default:
+			delete(h.watchers, w)
+			close(w.lagged)
+		}
+	}
+}
+
+func newJobEvent(job *storage.Job, eventType pb.JobEvent_Type) *pb.JobEvent {
+	return &pb.JobEvent{
+		Type: eventType,
+		Time: timestamppb.Now(),
+		Job:  jobDetails(job),
+	}
+}
+
+// exitEvent returns the type of the event for the end of the job
+func exitEvent(job *storage.Job) pb.JobEvent_Type {
+	if job.Status() == storage.Stopped {
+		return pb.JobEvent_STOPPED
+	}
+	return pb.JobEvent_EXITED
+}
+
+// jobEnded waits for the job to exit, then sends the event for its end and calls the webhooks
+func (s *server) jobEnded(job *storage.Job) {
+	<-job.Exited()
+	s.events.publish(job, exitEvent(job))
+	s.notifyEnd(job)
+}
+
+// WatchJobs streams the events from a single job, until it exits, or from the jobs matching the label selector.
+// With an output threshold, the running jobs are also followed until their output gets to it.
+func (s *server) WatchJobs(req *pb.WatchRequest, stream grpc.ServerStreamingServer[pb.JobEvent]) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	userId, err := s.authorize(ctx, storage.Status)
+	if err != nil {
+		return err
+	}
+	if req.OutputThresholdBytes < 0 {
+		return status.Errorf(codes.InvalidArgument, "output threshold must not be negative")
+	}
+	// users other than admins only watch their own jobs
+	owner := ""
+	if !s.db.Authorized(userId, storage.ListAll) {
+		owner = getRequesterEmail(ctx)
+	}
+
+	var match func(*storage.Job) bool
+	var single *storage.Job
+	if req.JobId != "" {
+		if req.Selector != "" {
+			return status.Errorf(codes.InvalidArgument, "a job id and a label selector can't be watched together")
+		}
+		if single, err = s.findJob(ctx, req.JobId); err != nil {
+			return err
+		}
+		match = func(job *storage.Job) bool { return job == single }
+	} else {
+		sel, err := parseOptionalSelector(req.Selector)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		match = func(job *storage.Job) bool {
+			return (owner == "" || job.User == owner) && sel.matches(job.Labels)
+		}
+	}
+
+	// subscribed before looking at the jobs, so no event is missed in between
+	w := s.events.subscribe(match)
+	defer s.events.unsubscribe(w)
+	if single != nil && single.Finished() {
+		return stream.Send(newJobEvent(single, exitEvent(single)))
+	}
+
+	thresholds := make(chan *pb.JobEvent)
+	followed := make(map[*storage.Job]bool)
+	follow := func(job *storage.Job) {
+		if req.OutputThresholdBytes == 0 || followed[job] || job.Finished() {
+			return
+		}
+		followed[job] = true
+		go func() {
+			if !job.WaitOutput(ctx, req.OutputThresholdBytes) {
+				return
+			}
+			select {
+			case thresholds <- newJobEvent(job, pb.JobEvent_OUTPUT_THRESHOLD):
+			case <-ctx.Done():
+			}
+		}()
+	}
+	for _, job := range s.db.ListJobs() {
+		if match(job) {
+			follow(job)
+		}
+	}
+
+	for {
+		var event *pb.JobEvent
+		select {
+		case e := <-w.events:
+			event = e.event
+			if event.Type == pb.JobEvent_EXITED || event.Type == pb.JobEvent_STOPPED {
+				delete(followed, e.job)
+			} else {
+				follow(e.job)
+			}
+		case event = <-thresholds:
+		case <-w.lagged:
+			return status.Errorf(codes.ResourceExhausted, "the watcher fell behind the events, after %d were queued", watcherBuffer)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if err := stream.Send(event); err != nil {
+			return err
+		}
+		if single != nil && (event.Type == pb.JobEvent_EXITED || event.Type == pb.JobEvent_STOPPED) {
+			return nil
+		}
+	}
+}
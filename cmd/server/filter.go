@@ -0,0 +1,75 @@
+package main
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/mhsantos/rlcp/cmd/internal/pb"
+	"github.com/mhsantos/rlcp/cmd/server/internal/storage"
+)
+
+// outputFilter selects the lines of output sent for a GetOutput request. A nil filter selects all of them.
+type outputFilter struct {
+	include  *regexp.Regexp
+	exclude  *regexp.Regexp
+	since    time.Time
+	until    time.Time
+	maxLines int64
+}
+
+// newOutputFilter returns the filter for the request, or nil if the request doesn't filter the output
+func newOutputFilter(req *pb.GetRequest) (*outputFilter, error) {
+	if req.Include == "" && req.Exclude == "" && req.Since == nil && req.Until == nil && req.MaxLines == 0 {
+		return nil, nil
+	}
+	f := &outputFilter{maxLines: req.MaxLines}
+	var err error
+	if req.Include != "" {
+		if f.include, err = regexp.Compile(req.Include); err != nil {
+			return nil, err
+		}
+	}
+	if req.Exclude != "" {
+		if f.exclude, err = regexp.Compile(req.Exclude); err != nil {
+			return nil, err
+		}
+	}
+	if req.Since != nil {
+		f.since = req.Since.AsTime()
+	}
+	if req.Until != nil {
+		f.until = req.Until.AsTime()
+	}
+	return f, nil
+}
+
+// match returns true if the line should be sent. The text matched by the regular expressions
+// doesn't include the trailing new line.
+func (f *outputFilter) match(line storage.Line) bool {
+	if f == nil {
+		return true
+	}
+	if !f.since.IsZero() && line.Time.Before(f.since) {
+		return false
+	}
+	text := line.Data
+	if line.Complete() {
+		text = text[:len(text)-1]
+	}
+	if f.include != nil && !f.include.Match(text) {
+		return false
+	}
+	return f.exclude == nil || !f.exclude.Match(text)
+}
+
+// past returns true if the line was captured after until. The lines from a stream are read in the order they
+// are captured, so no other line from that stream is sent after that, but since lines are read once complete,
+// a line from the other stream captured before until may still follow.
+func (f *outputFilter) past(line storage.Line) bool {
+	return f != nil && !f.until.IsZero() && line.Time.After(f.until)
+}
+
+// full returns true once the number of lines sent got to the maximum
+func (f *outputFilter) full(sent int64) bool {
+	return f != nil && f.maxLines > 0 && sent >= f.maxLines
+}
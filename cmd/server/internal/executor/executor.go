@@ -1,79 +1,283 @@
 package executor
 
 import (
+	"errors"
 	"io"
 	"log/slog"
+	"os"
 	"os/exec"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/mhsantos/rlcp/cmd/server/internal/storage"
 )
 
-func RunCommand(job *storage.Job, command string, args []string) error {
-	cmd := exec.Command(command, args...)
+const (
+	// redactFlushDelay is how long the output held back by the redaction waits for the rest of a secret
+	redactFlushDelay = time.Second
+	// outputPollInterval is how often the process group of a job which exited is checked, while its output
+	// is still open
+	outputPollInterval = 100 * time.Millisecond
+	// outputGrace is how long the output is still read once every process in the group of the job exited,
+	// from the processes which left the group holding the output open, before it's closed
+	outputGrace = time.Second
+)
+
+// RunCommand runs the command for the job. env has the variables added to the server environment,
+// in the form key=value.
+func RunCommand(job *storage.Job, command string, args []string, env []string) error {
+	return start(job, exec.Command(command, args...), env, func() {})
+}
+
+// RunScript writes the script body to a temporary file, only accessible by the server user, and runs
+// it with the interpreter. The file is removed once the job ends.
+func RunScript(job *storage.Job, interpreter string, script []byte, args []string, env []string) error {
+	file, err := os.CreateTemp("", "rlcp-script-*")
+	if err != nil {
+		slog.Error("error creating script file", slog.Any("error", err))
+		return err
+	}
+	path := file.Name()
+	cleanup := func() {
+		if err := os.Remove(path); err != nil {
+			slog.Error("error removing script file", slog.String("path", path), slog.Any("error", err))
+		}
+	}
+
+	_, err = file.Write(script)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		slog.Error("error writing script file", slog.Any("error", err))
+		cleanup()
+		return err
+	}
 
+	err = start(job, exec.Command(interpreter, append([]string{path}, args...)...), env, cleanup)
+	if err != nil {
+		cleanup()
+	}
+	return err
+}
+
+// start launches cmd for the job, in the job workspace, with env added to the server environment,
+// and calls cleanup once the process exits
+func start(job *storage.Job, cmd *exec.Cmd, env []string, cleanup func()) error {
 	job.Cmd = cmd
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	if err := prepareWorkspace(job); err != nil {
+		slog.Error("error creating workspace", slog.String("workspace", job.Workspace), slog.Any("error", err))
+		return err
+	}
+	cmd.Dir = job.Workspace
+	// the command runs in its own process group, led by its process, so it can be signaled along with the
+	// processes it starts
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 
-	stdout, err := cmd.StdoutPipe()
+	// the pipes are created here, instead of by exec.Cmd, so the process can be waited for while the output is
+	// read, and the output can be closed when it's held open by processes which left the job
+	stdout, stdoutWriter, err := os.Pipe()
 	if err != nil {
-		slog.Error("error acquiring stdout pipe", slog.Any("error", err))
+		slog.Error("error creating stdout pipe", slog.Any("error", err))
 		return err
 	}
-	stderr, err := cmd.StderrPipe()
+	stderr, stderrWriter, err := os.Pipe()
 	if err != nil {
-		slog.Error("error acquiring stderr pipe", slog.Any("error", err))
+		slog.Error("error creating stderr pipe", slog.Any("error", err))
+		stdout.Close()
+		stdoutWriter.Close()
 		return err
 	}
+	cmd.Stdout, cmd.Stderr = stdoutWriter, stderrWriter
 
 	err = cmd.Start()
+	// the command has its own copies of the writers
+	stdoutWriter.Close()
+	stderrWriter.Close()
 	if err != nil {
 		slog.Error("error starting command", slog.Any("error", err))
+		stdout.Close()
+		stderr.Close()
+		collectArtifacts(job)
 		return err
 	}
+	job.StartTime = time.Now()
+	job.SetProcess(cmd.Process.Pid, ProcessStart(cmd.Process.Pid))
 
-	go ListenToCommandOutput(job, stdout, stderr)
-	go waitCommand(cmd)
+	outputRead := make(chan struct{})
+	go func() {
+		ListenToCommandOutput(job, stdout, stderr)
+		close(outputRead)
+	}()
+	go waitCommand(job, outputRead, cleanup, stdout, stderr)
 
 	return nil
 }
 
 // ListenToCommandOutput reads the output from stdout and stderr and sends it to
-// LogHandler.ProcessOutput, which is responsible for storing it and forwarding it to listeners.
+// LogHandler.ProcessOutput, which is responsible for storing it and making it available to readers.
+// Each stream is read on its own, so the output is stored in the order the command writes it, tagged
+// with the stream it was written to.
 func ListenToCommandOutput(job *storage.Job, stdout, stderr io.ReadCloser) {
-	defer stdout.Close()
-	defer stderr.Close()
-
 	slog.Debug("listening to command output")
 
-	stream := io.MultiReader(stdout, stderr)
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, pipe := range []io.ReadCloser{stdout, stderr} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = readOutput(job, storage.Stream(i), pipe)
+		}()
+	}
+	wg.Wait()
 
+	if err := errors.Join(errs...); err != nil {
+		slog.Error("error reading command output", slog.Any("error", err))
+		finishOutput(job, storage.Errored)
+		return
+	}
+	slog.Debug("ListenToCommandOutput EOF")
+	finishOutput(job, storage.Completed)
+}
+
+// readOutput reads a stream of the command output until it's closed. The command is killed if the
+// output can't be stored, or once it gets to its limit with the StopJob policy.
+// When the redaction holds back some output, it's stored once the stream is idle for redactFlushDelay.
+func readOutput(job *storage.Job, stream storage.Stream, pipe io.ReadCloser) error {
+	defer pipe.Close()
+
+	deadliner, _ := pipe.(interface{ SetReadDeadline(time.Time) error })
+	waiting := false
 	buff := make([]byte, 1024)
 	for {
-		n, err := stream.Read(buff)
+		if holding := job.Holding(stream); deadliner != nil && holding != waiting {
+			var deadline time.Time
+			if holding {
+				deadline = time.Now().Add(redactFlushDelay)
+			}
+			if err := deadliner.SetReadDeadline(deadline); err != nil {
+				deadliner = nil
+			}
+			waiting = holding
+		}
+
+		n, err := pipe.Read(buff)
 		if n > 0 {
-			err = job.ProcessOutput(buff[:n])
-			if err != nil {
-				slog.Error("error processing output", slog.Any("error", err))
-				if err := job.Cmd.Process.Kill(); err != nil {
-					slog.Error("error killing process", slog.Any("error", err))
-					return
-				}
+			if err := storeOutput(job, stream, job.ProcessOutput(stream, buff[:n])); err != nil {
+				return err
 			}
-		} else {
-			if err != nil {
-				slog.Error("finished reading?", slog.Any("err", err), slog.Bool("io.EOF", err == io.EOF))
-				if err == io.EOF {
-					slog.Debug("ListenToCommandOutput EOF")
-					job.Status = storage.Completed
-					return
-				}
-				job.Status = storage.Errored
-				return
+		}
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			if err := storeOutput(job, stream, job.FlushOutput(stream)); err != nil {
+				return err
 			}
+			continue
+		}
+		// the output is closed by waitOutput once only processes which left the job hold it
+		if err == io.EOF || errors.Is(err, os.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// storeOutput handles the error storing the output from the stream, killing the command when it can't
+// be stored or when it gets to its limit. It returns the error when the output can't be stored.
+func storeOutput(job *storage.Job, stream storage.Stream, err error) error {
+	if errors.Is(err, storage.ErrOutputLimit) {
+		slog.Info("job output got to its limit, stopping the job", slog.String("jobid", job.Id.String()))
+		if err := SignalJob(job, syscall.SIGKILL); err != nil {
+			slog.Error("error killing process", slog.Any("error", err))
 		}
+		return nil
 	}
+	if err != nil {
+		slog.Error("error processing output", slog.String("stream", stream.String()), slog.Any("error", err))
+		if err := SignalJob(job, syscall.SIGKILL); err != nil {
+			slog.Error("error killing process", slog.Any("error", err))
+		}
+		return err
+	}
+	return nil
+}
+
+// finishOutput sets the final status for the job, unless it was already set when stopping it,
+// and closes the output so the readers following it stop once they read it all.
+func finishOutput(job *storage.Job, status storage.JobStatus) {
+	job.UpdateStatus(storage.Running, status)
+	job.CloseOutput()
 }
 
-// waitCommand calls exec.Cmd.Wait(), which is required to start processing the command
-func waitCommand(cmd *exec.Cmd) {
-	_ = cmd.Wait()
+// waitCommand waits for the command to exit and for its output to be read. Once both are done, it runs the
+// cleanup for the command and collects the artifacts from the workspace.
+func waitCommand(job *storage.Job, outputRead chan struct{}, cleanup func(), pipes ...*os.File) {
+	_ = job.Cmd.Wait()
+	waitOutput(job, outputRead, pipes)
+	cleanup()
+	collectArtifacts(job)
+	job.MarkExited(exitCode(job.Cmd.ProcessState))
+}
+
+// waitOutput waits until the output of a job which exited is read. The output is open while any process holds
+// the pipes, so it's read as long as there are processes in the group of the job. Once they all exit, the
+// processes which left the group are given outputGrace to close the output, and it's closed after that.
+func waitOutput(job *storage.Job, outputRead chan struct{}, pipes []*os.File) {
+	pgid := job.Cmd.Process.Pid
+	ticker := time.NewTicker(outputPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-outputRead:
+			return
+		case <-ticker.C:
+		}
+		if err := syscall.Kill(-pgid, 0); !errors.Is(err, syscall.ESRCH) {
+			continue
+		}
+		select {
+		case <-outputRead:
+			return
+		case <-time.After(outputGrace):
+		}
+		slog.Warn("processes which left the job hold its output open, closing it", slog.String("jobid", job.Id.String()))
+		for _, pipe := range pipes {
+			pipe.Close()
+		}
+		<-outputRead
+		return
+	}
+}
+
+// SignalJob sends the signal to the process group of the job, so the processes started by the command get it as
+// well. It returns os.ErrProcessDone once they all exited.
+func SignalJob(job *storage.Job, sig syscall.Signal) error {
+	pid, _ := job.Process()
+	if pid == 0 || job.Finished() {
+		return os.ErrProcessDone
+	}
+	err := syscall.Kill(-pid, sig)
+	if errors.Is(err, syscall.ESRCH) {
+		return os.ErrProcessDone
+	}
+	return err
+}
+
+// exitCode returns the exit status of the process or, as shells do, 128 plus the signal number when it was
+// killed by a signal
+func exitCode(state *os.ProcessState) int {
+	if state == nil {
+		return storage.UnknownExitCode
+	}
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return 128 + int(ws.Signal())
+	}
+	return state.ExitCode()
 }
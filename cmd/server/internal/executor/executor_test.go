@@ -0,0 +1,135 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mhsantos/rlcp/cmd/server/internal/storage"
+)
+
+// jobOutput waits for the job to exit and returns its whole output
+func jobOutput(t *testing.T, job *storage.Job, timeout time.Duration) string {
+	t.Helper()
+	select {
+	case <-job.Exited():
+	case <-time.After(timeout):
+		t.Fatal("timed out waiting for the job to exit")
+	}
+	reader := job.NewReader(0, false)
+	defer reader.Close()
+	var out []byte
+	for {
+		chunk, err := reader.Next(context.Background())
+		if errors.Is(err, io.EOF) {
+			return string(out)
+		}
+		if err != nil {
+			t.Fatalf("error reading the output: %v", err)
+		}
+		out = append(out, chunk.Data...)
+	}
+}
+
+func TestSignalJobStopsDescendants(t *testing.T) {
+	job, err := storage.NewJob(storage.LogOptions{Store: storage.NewMemoryStore()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the shell exits right away, and the process it started keeps the output open
+	if err := RunCommand(job, "sh", []string{"-c", "sleep 60 & echo started"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if job.Finished() {
+		t.Fatal("the job ended while a process it started was running")
+	}
+
+	if err := SignalJob(job, syscall.SIGKILL); err != nil {
+		t.Fatal(err)
+	}
+	if out := jobOutput(t, job, 5*time.Second); out != "started\n" {
+		t.Fatalf("unexpected output: %q", out)
+	}
+	if err := SignalJob(job, syscall.SIGKILL); !errors.Is(err, os.ErrProcessDone) {
+		t.Fatalf("signaling the finished job returned %v", err)
+	}
+}
+
+func TestOutputClosedWhenProcessLeavesJob(t *testing.T) {
+	job, err := storage.NewJob(storage.LogOptions{Store: storage.NewMemoryStore()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the process started in a new session leaves the group of the job, but still holds its output
+	if err := RunCommand(job, "sh", []string{"-c", "setsid sleep 60 & echo $!"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	out := jobOutput(t, job, outputGrace+5*time.Second)
+	pid, err := strconv.Atoi(strings.TrimSpace(out))
+	if err != nil {
+		t.Fatalf("unexpected output: %q", out)
+	}
+	defer syscall.Kill(pid, syscall.SIGKILL)
+	if job.ExitCode() != 0 || job.Status() != storage.Completed {
+		t.Fatalf("unexpected job: status %s, exit code %d", job.Status(), job.ExitCode())
+	}
+	if err := syscall.Kill(pid, 0); err != nil {
+		t.Fatalf("the process which left the job was stopped: %v", err)
+	}
+}
+
+func TestOutputStores(t *testing.T) {
+	tests := []struct {
+		name  string
+		store storage.OutputStore
+	}{
+		{name: "memory", store: storage.NewMemoryStore()},
+		{name: "local files", store: storage.NewLocalStore(t.TempDir())},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job, err := storage.NewJob(storage.LogOptions{Store: tt.store})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the output is several segments long, so most of it is read back from the store
+			script := `i=0; while [ $i -lt 3000 ]; do printf '%01000d\n' $i; i=$((i+1)); done`
+			if err := RunCommand(job, "sh", []string{"-c", script}, nil); err != nil {
+				t.Fatal(err)
+			}
+			var handler LogHandler = job
+			out := jobOutput(t, job, 10*time.Second)
+			if len(out) != 3000*1001 || !strings.HasSuffix(out, strconv.Itoa(2999)+"\n") {
+				t.Fatalf("unexpected output with %d bytes", len(out))
+			}
+			jobs, err := tt.store.Jobs()
+			if err != nil || len(jobs) != 1 || jobs[0] != job.Id.String() {
+				t.Fatalf("the output was not written to the store: %v, %v", jobs, err)
+			}
+			// the output read back from the store is the same read through the LogHandler
+			reader := handler.NewReader(int64(len(out)-1001), false)
+			defer reader.Close()
+			var last []byte
+			for {
+				chunk, err := reader.Next(context.Background())
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				last = append(last, chunk.Data...)
+			}
+			if string(last) != out[len(out)-1001:] {
+				t.Fatalf("unexpected last line: %q", last)
+			}
+		})
+	}
+}
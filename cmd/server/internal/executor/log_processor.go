@@ -1,7 +1,15 @@
 package executor
 
+import "github.com/mhsantos/rlcp/cmd/server/internal/storage"
+
+// LogHandler stores the output from a command, which is read through OutputReaders. Each reader keeps
+// its own position in the output, so ProcessOutput never waits for them.
+// storage.Job is the LogHandler for every output backend: its log keeps the recent output in memory and
+// writes the full segments to the storage.OutputStore selected in the server config.
 type LogHandler interface {
-	ProcessOutput([]byte) error
-	RegisterListener(chan []byte)
-	CloseListeners()
+	ProcessOutput(storage.Stream, []byte) error
+	NewReader(offset int64, follow bool) *storage.OutputReader
+	CloseOutput()
 }
+
+var _ LogHandler = (*storage.Job)(nil)
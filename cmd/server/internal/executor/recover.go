@@ -0,0 +1,148 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mhsantos/rlcp/cmd/server/internal/storage"
+)
+
+const (
+	// termTimeout is how long an orphaned process is given to exit after SIGTERM, before it's killed
+	termTimeout = 5 * time.Second
+	// killTimeout is how long an orphaned process is waited for after it's killed
+	killTimeout  = 5 * time.Second
+	killInterval = 10 * time.Millisecond
+)
+
+// RecoverJobs reconciles the jobs which were running when the server stopped with their processes.
+// The output was read through pipes by the previous server, so it can't be re-attached: the jobs are
+// marked as Lost, keeping the output stored before the restart. A process still running is stopped, along
+// with the processes in its group, since nobody reads its output and it can't be stopped otherwise. The
+// process is only stopped if its start time matches the one recorded for the job, so a process reusing the
+// pid is left alone. The workspaces are then cleaned up, keeping the artifacts, and the jobs are marked as exited.
+func RecoverJobs(jobs []*storage.Job) {
+	for _, job := range jobs {
+		reason := recoverProcess(job)
+		job.SetStatus(storage.Lost, reason)
+		slog.Warn("job lost by the server restart", slog.String("jobid", job.Id.String()), slog.String("reason", reason))
+		collectArtifacts(job)
+		job.MarkExited(storage.UnknownExitCode)
+	}
+}
+
+// recoverProcess stops the process for the job, if it's still running, and returns what happened to it
+func recoverProcess(job *storage.Job) string {
+	pid, start := job.Process()
+	if pid == 0 || start == 0 {
+		return "the server stopped while the job was starting"
+	}
+	if !processRunning(pid, start) {
+		return fmt.Sprintf("the server stopped while the job was running, and its process %d exited in the meantime, "+
+			"so its exit status and any output after the restart are unknown", pid)
+	}
+	if err := killProcess(pid, start); err != nil {
+		slog.Error("error killing orphaned process", slog.Int("pid", pid), slog.Any("error", err))
+		return fmt.Sprintf("the server stopped while the job was running, and its process %d could not be killed: %v",
+			pid, err)
+	}
+	return fmt.Sprintf("the server stopped while the job was running, and its process %d was killed, "+
+		"since its output could no longer be captured", pid)
+}
+
+// killProcess stops the process group led by the process, as long as the process is still the one started at
+// start: it's sent SIGTERM, and SIGKILL if the process is still running after termTimeout. Once the process
+// exits, its start time can no longer be checked, so the processes it left behind in the group are not signaled.
+func killProcess(pid int, start uint64) error {
+	if err := signalGroup(pid, start, syscall.SIGTERM); err != nil {
+		return err
+	}
+	// the process is not a child of this server, so it can't be waited for
+	if waitProcess(pid, start, termTimeout) {
+		return nil
+	}
+	if err := signalGroup(pid, start, syscall.SIGKILL); err != nil {
+		return err
+	}
+	if !waitProcess(pid, start, killTimeout) {
+		return fmt.Errorf("the process is still running %s after being killed", killTimeout)
+	}
+	return nil
+}
+
+// signalGroup sends the signal to the process group led by the process, unless the process is no longer the one
+// started at start, or the group is already gone. The start time is checked right before the signal, but a small
+// race remains: the process could exit, and its pid be reused by the leader of another group, in between.
+func signalGroup(pgid int, start uint64, sig syscall.Signal) error {
+	if !processRunning(pgid, start) {
+		return nil
+	}
+	if err := syscall.Kill(-pgid, sig); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return err
+	}
+	return nil
+}
+
+// waitProcess waits up to timeout for the process to exit, and returns true if it did
+func waitProcess(pid int, start uint64, timeout time.Duration) bool {
+	for deadline := time.Now().Add(timeout); processRunning(pid, start); {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(killInterval)
+	}
+	return true
+}
+
+// processRunning returns true if the process with the pid is running, and was started at start
+func processRunning(pid int, start uint64) bool {
+	started, state, err := processStat(pid)
+	if err != nil {
+		return false
+	}
+	// zombies already exited, they are only waiting for their parent
+	return started == start && state != "Z" && state != "X"
+}
+
+// ProcessStart returns the time the process started, in clock ticks since the boot, or 0 if it's unknown
+func ProcessStart(pid int) uint64 {
+	start, _, err := processStat(pid)
+	if err != nil {
+		return 0
+	}
+	return start
+}
+
+// processStat returns the start time and state of the process, from /proc/<pid>/stat
+func processStat(pid int) (uint64, string, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, "", err
+	}
+	return parseStat(pid, data)
+}
+
+// parseStat returns the start time and state of the process from the contents of its stat file
+func parseStat(pid int, data []byte) (uint64, string, error) {
+	// the command name may have spaces and parentheses, so the fields are counted after the last )
+	i := strings.LastIndexByte(string(data), ')')
+	if i < 0 {
+		return 0, "", fmt.Errorf("invalid stat for process %d", pid)
+	}
+	// fields from the state, the 3rd one, on
+	fields := strings.Fields(string(data[i+1:]))
+	if len(fields) < 20 {
+		return 0, "", fmt.Errorf("invalid stat for process %d", pid)
+	}
+	start, err := strconv.ParseUint(fields[19], 10, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid start time for process %d: %w", pid, err)
+	}
+	return start, fields[0], nil
+}
@@ -0,0 +1,122 @@
+package executor
+
+import (
+	"bufio"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/mhsantos/rlcp/cmd/server/internal/storage"
+)
+
+func TestParseStat(t *testing.T) {
+	// the fields after the state, up to the start time, which is the 22nd field
+	rest := " 1 12 12 0 -1 4194560 100 0 0 0 0 0 0 0 20 0 1 0 98765 1234 56"
+	tests := []struct {
+		name  string
+		stat  string
+		start uint64
+		state string
+		valid bool
+	}{
+		{name: "plain command", stat: "12 (sleep) S" + rest, start: 98765, state: "S", valid: true},
+		{name: "spaces in the command", stat: "12 (my job) R" + rest, start: 98765, state: "R", valid: true},
+		{name: "parentheses in the command", stat: "12 (a) (b) c)) Z" + rest, start: 98765, state: "Z", valid: true},
+		{name: "no command", stat: "12 sleep S" + rest},
+		{name: "missing fields", stat: "12 (sleep) S 1 12 12"},
+		{name: "invalid start time", stat: "12 (sleep) S" + strings.Replace(rest, "98765", "x", 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, state, err := parseStat(12, []byte(tt.stat))
+			if (err == nil) != tt.valid {
+				t.Fatalf("parseStat(%q) returned %v", tt.stat, err)
+			}
+			if tt.valid && (start != tt.start || state != tt.state) {
+				t.Fatalf("parseStat(%q) = %d, %s, expected %d, %s", tt.stat, start, state, tt.start, tt.state)
+			}
+		})
+	}
+}
+
+// startGroup starts a shell leading its own process group, like the jobs, which starts a child and prints its pid
+func startGroup(t *testing.T) (*exec.Cmd, int) {
+	t.Helper()
+	cmd := exec.Command("sh", "-c", "sleep 60 & echo $!; wait")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		cmd.Wait()
+	})
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	child, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cmd, child
+}
+
+// recoveredJob returns a job interrupted while running the process, after it's recovered
+func recoveredJob(t *testing.T, pid int, start uint64) *storage.Job {
+	t.Helper()
+	job, err := storage.NewJob(storage.LogOptions{Store: storage.NewMemoryStore()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.SetProcess(pid, start)
+	RecoverJobs([]*storage.Job{job})
+	if job.Status() != storage.Lost || !job.Finished() || job.ExitCode() != storage.UnknownExitCode {
+		t.Fatalf("unexpected recovered job: status %s, finished %t, exit code %d", job.Status(), job.Finished(),
+			job.ExitCode())
+	}
+	return job
+}
+
+func TestRecoverJobsStopsProcessGroup(t *testing.T) {
+	cmd, child := startGroup(t)
+	leaderStart, childStart := ProcessStart(cmd.Process.Pid), ProcessStart(child)
+
+	job := recoveredJob(t, cmd.Process.Pid, leaderStart)
+	if !strings.Contains(job.StatusMessage(), "was killed") {
+		t.Fatalf("unexpected status message: %s", job.StatusMessage())
+	}
+	if !waitProcess(child, childStart, killTimeout) {
+		t.Fatal("the process started by the job is still running")
+	}
+	if err := cmd.Wait(); err == nil {
+		t.Fatal("the process of the job exited normally")
+	} else if ws := cmd.ProcessState.Sys().(syscall.WaitStatus); ws.Signal() != syscall.SIGTERM {
+		t.Fatalf("the process of the job was not stopped with SIGTERM: %v", err)
+	}
+}
+
+func TestRecoverJobsLeavesReusedPid(t *testing.T) {
+	cmd, _ := startGroup(t)
+	start := ProcessStart(cmd.Process.Pid)
+
+	// a different start time means the pid was reused by another process
+	job := recoveredJob(t, cmd.Process.Pid, start+1)
+	if !strings.Contains(job.StatusMessage(), "exited in the meantime") {
+		t.Fatalf("unexpected status message: %s", job.StatusMessage())
+	}
+	if !processRunning(cmd.Process.Pid, start) {
+		t.Fatal("the process reusing the pid was stopped")
+	}
+
+	job = recoveredJob(t, 0, 0)
+	if !strings.Contains(job.StatusMessage(), "while the job was starting") {
+		t.Fatalf("unexpected status message: %s", job.StatusMessage())
+	}
+}
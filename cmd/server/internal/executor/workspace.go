@@ -0,0 +1,164 @@
+package executor
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/mhsantos/rlcp/cmd/server/internal/storage"
+)
+
+// ValidateArtifactGlobs returns an error if any of the globs is malformed or points outside of the workspace
+func ValidateArtifactGlobs(globs []string) error {
+	for _, glob := range globs {
+		if _, err := filepath.Match(glob, ""); err != nil {
+			return fmt.Errorf("invalid artifact glob %q: %w", glob, err)
+		}
+		if filepath.IsAbs(glob) || glob == ".." || strings.HasPrefix(glob, "../") {
+			return fmt.Errorf("artifact glob %q must be relative to the workspace", glob)
+		}
+	}
+	return nil
+}
+
+// prepareWorkspace creates the private working directory for the job
+func prepareWorkspace(job *storage.Job) error {
+	if len(job.Workspace) == 0 {
+		return nil
+	}
+	return os.MkdirAll(job.Workspace, 0700)
+}
+
+// collectArtifacts removes every file from the job workspace, except the regular files matching the
+// artifact globs, and records the ones kept on the job. The workspace is removed if nothing is kept.
+func collectArtifacts(job *storage.Job) {
+	if len(job.Workspace) == 0 {
+		return
+	}
+
+	artifacts := make([]string, 0)
+	dirs := make([]string, 0)
+	err := filepath.WalkDir(job.Workspace, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			dirs = append(dirs, path)
+			return nil
+		}
+		rel, err := filepath.Rel(job.Workspace, path)
+		if err != nil {
+			return err
+		}
+		if entry.Type().IsRegular() && matchArtifact(job.ArtifactGlobs, rel) {
+			artifacts = append(artifacts, rel)
+			return nil
+		}
+		return os.Remove(path)
+	})
+	if err != nil {
+		slog.Error("error collecting artifacts", slog.String("workspace", job.Workspace), slog.Any("error", err))
+	}
+
+	if len(artifacts) == 0 {
+		if err := os.RemoveAll(job.Workspace); err != nil {
+			slog.Error("error removing workspace", slog.String("workspace", job.Workspace), slog.Any("error", err))
+		}
+	} else {
+		// removes the directories left empty, starting from the deepest ones
+		for i := len(dirs) - 1; i > 0; i-- {
+			_ = os.Remove(dirs[i])
+		}
+	}
+	job.SetArtifacts(artifacts)
+}
+
+// matchArtifact returns true if the path relative to the workspace matches any of the globs.
+// Globs without a path separator are also matched against the file name, so *.log matches logs
+// in any directory of the workspace.
+func matchArtifact(globs []string, rel string) bool {
+	for _, glob := range globs {
+		if ok, _ := filepath.Match(glob, rel); ok {
+			return true
+		}
+		if !strings.Contains(glob, "/") {
+			if ok, _ := filepath.Match(glob, filepath.Base(rel)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WriteArtifacts writes the artifacts kept in the job workspace to w as a tar.gz archive. The files are opened
+// within the workspace, so a process left behind by the job can't swap them for links to other files.
+func WriteArtifacts(job *storage.Job, w io.Writer) error {
+	artifacts := job.Artifacts()
+	var root *os.Root
+	if len(artifacts) > 0 {
+		var err error
+		if root, err = os.OpenRoot(job.Workspace); err != nil {
+			return err
+		}
+		defer root.Close()
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	for _, rel := range artifacts {
+		if err := addToArchive(tw, root, rel); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// addToArchive adds the file from root to the archive, as long as it's a regular file. Symbolic links, which
+// are not followed, any other files and the files which can't be opened are skipped.
+func addToArchive(tw *tar.Writer, root *os.Root, name string) error {
+	// O_NONBLOCK keeps a fifo from blocking the open
+	file, err := root.OpenFile(name, os.O_RDONLY|syscall.O_NOFOLLOW|syscall.O_NONBLOCK, 0)
+	if errors.Is(err, fs.ErrNotExist) {
+		slog.Warn("artifact removed from workspace", slog.String("name", name))
+		return nil
+	}
+	if err != nil {
+		// links, and paths through links leaving the workspace, can't be opened
+		slog.Warn("artifact can't be opened, skipping it", slog.String("name", name), slog.Any("error", err))
+		return nil
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		slog.Warn("artifact is not a regular file, skipping it", slog.String("name", name))
+		return nil
+	}
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = filepath.ToSlash(name)
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	// the file may still grow, so only the size in the header is copied
+	_, err = io.CopyN(tw, file, info.Size())
+	return err
+}
@@ -0,0 +1,128 @@
+package executor
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/mhsantos/rlcp/cmd/server/internal/storage"
+)
+
+// writeFiles creates the files under dir, with their names as contents
+func writeFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readArchive returns the files in the tar.gz archive, by name
+func readArchive(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	files := make(map[string]string)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[header.Name] = string(content)
+	}
+}
+
+func TestCollectArtifacts(t *testing.T) {
+	workspace := filepath.Join(t.TempDir(), "job")
+	writeFiles(t, workspace, "heap.hprof", "logs/app.hprof", "reports/q1.csv", "reports/deep/q2.csv", "tmp/scratch.txt")
+	if err := os.Symlink(filepath.Join(workspace, "heap.hprof"), filepath.Join(workspace, "link.hprof")); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := storage.NewJob(storage.LogOptions{Store: storage.NewMemoryStore()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.Workspace = workspace
+	job.ArtifactGlobs = []string{"*.hprof", "reports/*.csv"}
+	collectArtifacts(job)
+
+	artifacts := job.Artifacts()
+	slices.Sort(artifacts)
+	expected := []string{"heap.hprof", "logs/app.hprof", "reports/q1.csv"}
+	if !cmp.Equal(artifacts, expected) {
+		t.Fatalf("unexpected artifacts: %v", cmp.Diff(expected, artifacts))
+	}
+	for _, removed := range []string{"link.hprof", "reports/deep", "tmp"} {
+		if _, err := os.Lstat(filepath.Join(workspace, removed)); !os.IsNotExist(err) {
+			t.Fatalf("%s was left in the workspace: %v", removed, err)
+		}
+	}
+
+	job.Workspace = filepath.Join(t.TempDir(), "empty")
+	writeFiles(t, job.Workspace, "out.txt")
+	collectArtifacts(job)
+	if _, err := os.Stat(job.Workspace); !os.IsNotExist(err) || len(job.Artifacts()) != 0 {
+		t.Fatalf("the workspace without artifacts was kept: %v, %v", err, job.Artifacts())
+	}
+}
+
+func TestWriteArtifactsOnlyRegularFiles(t *testing.T) {
+	dir := t.TempDir()
+	workspace := filepath.Join(dir, "job")
+	writeFiles(t, workspace, "report.csv", "linked/data.csv")
+	writeFiles(t, dir, "secret", "outside/data.csv")
+
+	// a process left behind swaps the artifacts collected for links, a fifo or removes them
+	if err := os.Symlink(filepath.Join(dir, "secret"), filepath.Join(workspace, "link.csv")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(filepath.Join(workspace, "linked")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "outside"), filepath.Join(workspace, "linked")); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Mkfifo(filepath.Join(workspace, "fifo.csv"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := storage.NewJob(storage.LogOptions{Store: storage.NewMemoryStore()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.Workspace = workspace
+	job.SetArtifacts([]string{"report.csv", "link.csv", "linked/data.csv", "fifo.csv", "removed.csv"})
+	var buf bytes.Buffer
+	if err := WriteArtifacts(job, &buf); err != nil {
+		t.Fatal(err)
+	}
+	files := readArchive(t, buf.Bytes())
+	expected := map[string]string{"report.csv": "report.csv"}
+	if !cmp.Equal(files, expected) {
+		t.Fatalf("unexpected archive: %v", cmp.Diff(expected, files))
+	}
+}
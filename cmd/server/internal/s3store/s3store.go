@@ -0,0 +1,250 @@
+// Package s3store stores the job output in an S3 compatible object store, like AWS S3 or MinIO, so the output
+// of big installations can be offloaded from the server disk. It implements the few operations the server needs
+// over plain HTTP, with requests signed by AWS Signature Version 4.
+package s3store
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// requestTimeout limits each request to the object store
+const requestTimeout = time.Minute
+
+// Config has the location of the bucket storing the output and the credentials to access it
+type Config struct {
+	// Endpoint is the URL of the object store, like https://s3.us-east-1.amazonaws.com or http://localhost:9000
+	Endpoint string
+	Bucket   string
+	Region   string
+	// Prefix is prepended to the name of every object, so the bucket can be shared
+	Prefix       string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	// PathStyle addresses the bucket in the URL path instead of the host name, as required by most
+	// S3 compatible stores
+	PathStyle bool
+}
+
+// Store keeps the output of each job in the objects named prefix/<job id>/<name> in the bucket
+type Store struct {
+	cfg    Config
+	base   *url.URL
+	client *http.Client
+	now    func() time.Time
+}
+
+// New returns a store for the bucket in cfg. No request is made until the store is used.
+func New(cfg Config) (*Store, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("the bucket is required")
+	}
+	if cfg.Region == "" {
+		return nil, errors.New("the region is required")
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, errors.New("the access key and secret key are required")
+	}
+	base, err := url.Parse(cfg.Endpoint)
+	if err != nil || base.Host == "" || (base.Scheme != "http" && base.Scheme != "https") {
+		return nil, fmt.Errorf("invalid endpoint %q", cfg.Endpoint)
+	}
+	if cfg.PathStyle {
+		base.Path = "/" + cfg.Bucket
+	} else {
+		base.Host = cfg.Bucket + "." + base.Host
+		base.Path = ""
+	}
+	if cfg.Prefix != "" && !strings.HasSuffix(cfg.Prefix, "/") {
+		cfg.Prefix += "/"
+	}
+	return &Store{
+		cfg:    cfg,
+		base:   base,
+		client: &http.Client{},
+		now:    time.Now,
+	}, nil
+}
+
+func (s *Store) Put(jobId, name string, data []byte) error {
+	resp, err := s.do(http.MethodPut, s.key(jobId, name), nil, data)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Get reads the whole object before returning, so the request is not left open by the readers
+func (s *Store) Get(jobId, name string) (io.ReadCloser, error) {
+	resp, err := s.do(http.MethodGet, s.key(jobId, name), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *Store) Delete(jobId, name string) error {
+	resp, err := s.do(http.MethodDelete, s.key(jobId, name), nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// DeleteJob removes the objects for the job one by one, since deleting multiple objects in a request is
+// not supported by every S3 compatible store
+func (s *Store) DeleteJob(jobId string) error {
+	keys, _, err := s.list(s.key(jobId, ""), "")
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		resp, err := s.do(http.MethodDelete, key, nil, nil)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+	}
+	return nil
+}
+
+func (s *Store) Jobs() ([]string, error) {
+	_, prefixes, err := s.list(s.cfg.Prefix, "/")
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]string, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		jobs = append(jobs, strings.TrimSuffix(strings.TrimPrefix(prefix, s.cfg.Prefix), "/"))
+	}
+	return jobs, nil
+}
+
+// key returns the name of the object for the job
+func (s *Store) key(jobId, name string) string {
+	return s.cfg.Prefix + jobId + "/" + name
+}
+
+// listResult is the response to a ListObjectsV2 request
+type listResult struct {
+	Contents []struct {
+		Key string
+	}
+	CommonPrefixes []struct {
+		Prefix string
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+// list returns the keys of the objects starting with prefix, and the common prefixes up to the delimiter
+// when informed, following the continuation tokens until all of them are listed
+func (s *Store) list(prefix, delimiter string) ([]string, []string, error) {
+	var keys, prefixes []string
+	query := url.Values{}
+	query.Set("list-type", "2")
+	query.Set("prefix", prefix)
+	if delimiter != "" {
+		query.Set("delimiter", delimiter)
+	}
+	for {
+		resp, err := s.do(http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		var result listResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid list response: %w", err)
+		}
+		for _, content := range result.Contents {
+			keys = append(keys, content.Key)
+		}
+		for _, common := range result.CommonPrefixes {
+			prefixes = append(prefixes, common.Prefix)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return keys, prefixes, nil
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+}
+
+// s3Error is the error returned by the object store
+type s3Error struct {
+	Code    string
+	Message string
+}
+
+// do sends a signed request for the object with the key, or for the bucket when the key is empty.
+// It returns an error for any response other than 2xx, matching fs.ErrNotExist when the object doesn't exist.
+func (s *Store) do(method, key string, query url.Values, body []byte) (*http.Response, error) {
+	u := *s.base
+	if key != "" {
+		u.Path += "/" + key
+	} else if u.Path == "" {
+		u.Path = "/"
+	}
+	u.RawQuery = canonicalQuery(query)
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	s.sign(req, body, s.now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	var s3err s3Error
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if xml.Unmarshal(data, &s3err) != nil || s3err.Code == "" {
+		s3err.Code = resp.Status
+	}
+	err = fmt.Errorf("s3 %s %s: %s %s", method, u.Path, s3err.Code, s3err.Message)
+	if resp.StatusCode == http.StatusNotFound && key != "" {
+		return nil, fmt.Errorf("%w: %w", fs.ErrNotExist, err)
+	}
+	return nil, err
+}
+
+// cancelBody releases the request context once the response body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,222 @@
+// Package secrets keeps the secrets injected into the jobs as environment variables, so they don't need to be
+// informed on the command line. The secrets are stored in a file encrypted with AES-GCM, using a key read from
+// a key file, and every secret has the list of users allowed to use it.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// KeySize is the size of the key read from the key file, for AES-256
+const KeySize = 32
+
+var (
+	// ErrNotFound is returned when a secret doesn't exist
+	ErrNotFound = errors.New("secret not found")
+	// ErrNotAllowed is returned when a user is not in the list of users allowed to use a secret
+	ErrNotAllowed = errors.New("user not allowed to use the secret")
+)
+
+// namePattern restricts the names of the secrets, so they can be safely shown and logged
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// Secret is a value stored in the store, with the emails of the users allowed to use it
+type Secret struct {
+	Value   string    `json:"value"`
+	Users   []string  `json:"users"`
+	Updated time.Time `json:"updated"`
+}
+
+// Info describes a secret without its value
+type Info struct {
+	Name    string
+	Users   []string
+	Updated time.Time
+}
+
+// Store keeps the secrets in memory, and writes them encrypted to its file on every change
+type Store struct {
+	mu      sync.RWMutex
+	path    string
+	aead    cipher.AEAD
+	secrets map[string]Secret
+}
+
+// Open reads the secrets from the file at path, encrypted with the key in keyPath. The file is created on the
+// first change when it doesn't exist.
+func Open(path, keyPath string) (*Store, error) {
+	key, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the secrets key: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("the secrets key must have %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{
+		path:    path,
+		aead:    aead,
+		secrets: make(map[string]Secret),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ValidName returns an error if name can't be used for a secret
+func ValidName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid secret name %q, only letters, digits, '_', '.' and '-' are allowed", name)
+	}
+	return nil
+}
+
+// Set adds or replaces the secret with the name, allowed to the users
+func (s *Store) Set(name, value string, users []string) error {
+	if err := ValidName(name); err != nil {
+		return err
+	}
+	if value == "" {
+		return errors.New("the secret value must not be empty")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous, existed := s.secrets[name]
+	s.secrets[name] = Secret{
+		Value:   value,
+		Users:   slices.Compact(slices.Sorted(slices.Values(users))),
+		Updated: time.Now().UTC(),
+	}
+	if err := s.save(); err != nil {
+		if existed {
+			s.secrets[name] = previous
+		} else {
+			delete(s.secrets, name)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes the secret with the name
+func (s *Store) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous, ok := s.secrets[name]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(s.secrets, name)
+	if err := s.save(); err != nil {
+		s.secrets[name] = previous
+		return err
+	}
+	return nil
+}
+
+// List returns the secrets sorted by name, without their values
+func (s *Store) List() []Info {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	infos := make([]Info, 0, len(s.secrets))
+	for name, secret := range s.secrets {
+		infos = append(infos, Info{
+			Name:    name,
+			Users:   slices.Clone(secret.Users),
+			Updated: secret.Updated,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// Resolve returns the value of the secret with the name, as long as the user with the email is allowed to use it
+func (s *Store) Resolve(email, name string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	secret, ok := s.secrets[name]
+	if !ok {
+		return "", ErrNotFound
+	}
+	if !slices.Contains(secret.Users, email) {
+		return "", ErrNotAllowed
+	}
+	return secret.Value, nil
+}
+
+// load decrypts the secrets from the file, which is the nonce followed by the encrypted secrets in JSON
+func (s *Store) load() error {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not read the secrets file: %w", err)
+	}
+	nonceSize := s.aead.NonceSize()
+	if len(data) < nonceSize {
+		return errors.New("the secrets file is corrupted")
+	}
+	plain, err := s.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return errors.New("could not decrypt the secrets file, the key doesn't match")
+	}
+	if err := json.Unmarshal(plain, &s.secrets); err != nil {
+		return fmt.Errorf("the secrets file is corrupted: %w", err)
+	}
+	return nil
+}
+
+// save encrypts the secrets with a new nonce and replaces the file, only readable by the server user
+func (s *Store) save() error {
+	plain, err := json.Marshal(s.secrets)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	data := s.aead.Seal(nonce, nonce, plain, nil)
+
+	// the file is replaced by a rename, so a failed write doesn't lose the secrets
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("could not write the secrets file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+package secrets_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mhsantos/rlcp/cmd/server/internal/secrets"
+)
+
+// writeKey writes a random key with size bytes to a file in dir
+func writeKey(t *testing.T, dir, name string, size int) string {
+	t.Helper()
+	key := make([]byte, size)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, key, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secrets")
+	keyPath := writeKey(t, dir, "secrets.key", secrets.KeySize)
+	store, err := secrets.Open(path, keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tcs := []struct {
+		name          string
+		op            string
+		secret        string
+		value         string
+		users         []string
+		email         string
+		expectedValue string
+		expectedError error
+	}{
+		{
+			name:   "set secret",
+			op:     "set",
+			secret: "db.password",
+			value:  "hunter2",
+			users:  []string{"bob@email.com", "alice@email.com", "bob@email.com"},
+		},
+		{
+			name:          "get secret as allowed user",
+			op:            "get",
+			secret:        "db.password",
+			email:         "alice@email.com",
+			expectedValue: "hunter2",
+		},
+		{
+			name:          "get secret as another user",
+			op:            "get",
+			secret:        "db.password",
+			email:         "eve@email.com",
+			expectedError: secrets.ErrNotAllowed,
+		},
+		{
+			name:          "get unexisting secret",
+			op:            "get",
+			secret:        "api-token",
+			email:         "alice@email.com",
+			expectedError: secrets.ErrNotFound,
+		},
+		{
+			name:   "replace secret",
+			op:     "set",
+			secret: "db.password",
+			value:  "correct horse",
+			users:  []string{"eve@email.com"},
+		},
+		{
+			name:          "get replaced secret",
+			op:            "get",
+			secret:        "db.password",
+			email:         "eve@email.com",
+			expectedValue: "correct horse",
+		},
+		{
+			name:          "get replaced secret as user removed",
+			op:            "get",
+			secret:        "db.password",
+			email:         "alice@email.com",
+			expectedError: secrets.ErrNotAllowed,
+		},
+		{
+			name:   "set another secret",
+			op:     "set",
+			secret: "api-token",
+			value:  "t0k3n",
+			users:  []string{"alice@email.com"},
+		},
+		{
+			name:   "delete secret",
+			op:     "delete",
+			secret: "db.password",
+		},
+		{
+			name:          "get deleted secret",
+			op:            "get",
+			secret:        "db.password",
+			email:         "eve@email.com",
+			expectedError: secrets.ErrNotFound,
+		},
+		{
+			name:          "delete unexisting secret",
+			op:            "delete",
+			secret:        "db.password",
+			expectedError: secrets.ErrNotFound,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+			var value string
+			switch tc.op {
+			case "set":
+				err = store.Set(tc.secret, tc.value, tc.users)
+			case "get":
+				value, err = store.Resolve(tc.email, tc.secret)
+			case "delete":
+				err = store.Delete(tc.secret)
+			}
+			if !errors.Is(err, tc.expectedError) {
+				t.Fatalf("%s %s returned %v, expected %v", tc.op, tc.secret, err, tc.expectedError)
+			}
+			if value != tc.expectedValue {
+				t.Fatalf("%s %s returned %q, expected %q", tc.op, tc.secret, value, tc.expectedValue)
+			}
+		})
+	}
+
+	// the secrets are read back from the file, which doesn't have their values in plain text
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "t0k3n") || strings.Contains(string(data), "api-token") {
+		t.Fatal("the secrets file is not encrypted")
+	}
+	reopened, err := secrets.Open(path, keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, err := reopened.Resolve("alice@email.com", "api-token"); err != nil || value != "t0k3n" {
+		t.Fatalf("the reopened store returned %q, %v", value, err)
+	}
+	list := reopened.List()
+	if len(list) != 1 || list[0].Name != "api-token" || !cmp.Equal(list[0].Users, []string{"alice@email.com"}) {
+		t.Fatalf("unexpected secrets listed: %v", list)
+	}
+}
+
+func TestStoreRejectsInvalidSecrets(t *testing.T) {
+	dir := t.TempDir()
+	store, err := secrets.Open(filepath.Join(dir, "secrets"), writeKey(t, dir, "secrets.key", secrets.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tcs := []struct {
+		name          string
+		secret        string
+		value         string
+		expectedError string
+	}{
+		{
+			name:          "empty name",
+			secret:        "",
+			value:         "value",
+			expectedError: "invalid secret name",
+		},
+		{
+			name:          "name with spaces",
+			secret:        "db password",
+			value:         "value",
+			expectedError: "invalid secret name",
+		},
+		{
+			name:          "name with a path",
+			secret:        "../db",
+			value:         "value",
+			expectedError: "invalid secret name",
+		},
+		{
+			name:          "empty value",
+			secret:        "db",
+			value:         "",
+			expectedError: "must not be empty",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			err := store.Set(tc.secret, tc.value, []string{"alice@email.com"})
+			if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+				t.Fatalf("Set(%q) returned %v, expected %q", tc.secret, err, tc.expectedError)
+			}
+		})
+	}
+	if list := store.List(); len(list) != 0 {
+		t.Fatalf("invalid secrets were stored: %v", list)
+	}
+}
+
+func TestOpenRejectsCorruptedFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secrets")
+	keyPath := writeKey(t, dir, "secrets.key", secrets.KeySize)
+	store, err := secrets.Open(path, keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("db", "hunter2", []string{"alice@email.com"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flipped := bytes.Clone(data)
+	flipped[len(flipped)-1] ^= 1
+
+	tcs := []struct {
+		name          string
+		data          []byte
+		keyPath       string
+		expectedError string
+	}{
+		{
+			name:          "flipped bit",
+			data:          flipped,
+			keyPath:       keyPath,
+			expectedError: "could not decrypt",
+		},
+		{
+			name:          "truncated file",
+			data:          data[:len(data)/2],
+			keyPath:       keyPath,
+			expectedError: "could not decrypt",
+		},
+		{
+			name:          "shorter than the nonce",
+			data:          data[:4],
+			keyPath:       keyPath,
+			expectedError: "corrupted",
+		},
+		{
+			name:          "wrong key",
+			data:          data,
+			keyPath:       writeKey(t, dir, "other.key", secrets.KeySize),
+			expectedError: "could not decrypt",
+		},
+		{
+			name:          "short key",
+			data:          data,
+			keyPath:       writeKey(t, dir, "short.key", 16),
+			expectedError: "must have 32 bytes",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := os.WriteFile(path, tc.data, 0600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := secrets.Open(path, tc.keyPath)
+			if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+				t.Fatalf("Open returned %v, expected %q", err, tc.expectedError)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// File writes the records to a local file, one JSON object per line. Once the file gets to maxBytes, it's
+// rotated: the file is renamed with the .1 suffix, the previous ones are shifted to the next suffix, and only
+// maxFiles rotated files are kept.
+type File struct {
+	path     string
+	maxBytes int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+func NewFile(path string, maxBytes int64, maxFiles int) *File {
+	return &File{
+		path:     path,
+		maxBytes: maxBytes,
+		maxFiles: maxFiles,
+	}
+}
+
+func (f *File) Write(_ context.Context, records []Record) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return err
+		}
+	}
+	if f.maxBytes > 0 && f.size > 0 && f.size+int64(buf.Len()) > f.maxBytes {
+		if err := f.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := f.file.Write(buf.Bytes())
+	f.size += int64(n)
+	return err
+}
+
+func (f *File) Close() error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// open opens the file for appending, only readable by the server user
+func (f *File) open() error {
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+// rotate shifts the rotated files, dropping the oldest one, and starts a new file
+func (f *File) rotate() error {
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if f.maxFiles <= 0 {
+		if err := os.Remove(f.path); err != nil {
+			return err
+		}
+		return f.open()
+	}
+	for i := f.maxFiles - 1; i >= 1; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", f.path, i), fmt.Sprintf("%s.%d", f.path, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(f.path, f.path+".1"); err != nil {
+		return err
+	}
+	return f.open()
+}
@@ -0,0 +1,55 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// httpTimeout limits each request to the HTTP endpoint
+const httpTimeout = 30 * time.Second
+
+// HTTP posts the records in batches to an HTTP endpoint, as a JSON array. Any response other than 2xx is
+// retried.
+type HTTP struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTP(url string) *HTTP {
+	return &HTTP{
+		url:    url,
+		client: &http.Client{Timeout: httpTimeout},
+	}
+}
+
+func (h *HTTP) Write(ctx context.Context, records []Record) error {
+	body, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected response from %s: %s", h.url, resp.Status)
+	}
+	return nil
+}
+
+func (h *HTTP) Close() error {
+	h.client.CloseIdleConnections()
+	return nil
+}
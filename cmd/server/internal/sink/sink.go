@@ -0,0 +1,359 @@
+// Package sink forwards the output from the jobs to external logging systems, as it's stored by the server.
+// The output is split in lines, and every line is sent as a record to each sink, which has a queue of its own,
+// sending the records in batches and retrying when the sink is unavailable. The queues never block the jobs:
+// once a queue is full, its oldest records are dropped.
+package sink
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/mhsantos/rlcp/cmd/server/internal/storage"
+)
+
+const (
+	// maxLineSize is the most output sent in a record, longer lines are split
+	maxLineSize = 16 * 1024 // 16KB
+	// partialFlushDelay is how long a line still missing the new line waits before it's sent as is
+	partialFlushDelay = time.Second
+	minRetryBackoff   = time.Second
+	maxRetryBackoff   = 30 * time.Second
+	drainRetryBackoff = 100 * time.Millisecond
+	// closeTimeout limits how long the records left are sent for once the forwarder is closed
+	closeTimeout = 5 * time.Second
+)
+
+// Record is a line of output from a job
+type Record struct {
+	Time   time.Time `json:"time"`
+	JobId  string    `json:"job_id"`
+	User   string    `json:"user"`
+	Stream string    `json:"stream"`
+	Text   string    `json:"text"`
+}
+
+// Backend sends the records to an external logging system
+type Backend interface {
+	// Write sends a batch of records. When it fails, the whole batch is retried.
+	Write(ctx context.Context, records []Record) error
+	// Close releases the connections or files used by the backend
+	Close() error
+}
+
+// QueueOptions defines the buffering of the records for a sink
+type QueueOptions struct {
+	// MaxRecords is the most records waiting to be sent, the oldest ones are dropped past that
+	MaxRecords int
+	// BatchSize is the most records sent at once
+	BatchSize int
+	// FlushInterval is how long the records wait for a batch to fill up
+	FlushInterval time.Duration
+}
+
+// Forwarder splits the output from the jobs in lines and sends them to the sinks. It implements
+// storage.OutputSink.
+type Forwarder struct {
+	mu      sync.Mutex
+	queues  []*queue
+	partial map[partialKey]*partialLine
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// partialKey identifies the stream of a job with a line still missing the new line
+type partialKey struct {
+	jobId  string
+	stream storage.Stream
+}
+
+// partialLine is the start of a line, held until the rest of it is written
+type partialLine struct {
+	user string
+	time time.Time
+	data []byte
+}
+
+// Sink is a backend with the name used in the logs, and its queue options
+type Sink struct {
+	Name    string
+	Backend Backend
+	Queue   QueueOptions
+}
+
+// NewForwarder starts forwarding the output to the sinks, until it's closed
+func NewForwarder(sinks []Sink) *Forwarder {
+	f := &Forwarder{
+		partial: make(map[partialKey]*partialLine),
+		done:    make(chan struct{}),
+	}
+	for _, sink := range sinks {
+		q := newQueue(sink)
+		f.queues = append(f.queues, q)
+		f.wg.Add(1)
+		go func() {
+			defer f.wg.Done()
+			q.run(f.done)
+		}()
+	}
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		f.flushPartial(f.done)
+	}()
+	return f
+}
+
+// Send splits the output in lines, holding the last one until it's complete
+func (f *Forwarder) Send(jobId, user string, stream storage.Stream, t time.Time, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := partialKey{jobId, stream}
+	var records []Record
+	for len(data) > 0 {
+		p := f.partial[key]
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			if p == nil {
+				p = &partialLine{user: user, time: t}
+				f.partial[key] = p
+			}
+			p.data = append(p.data, data...)
+			for len(p.data) >= maxLineSize {
+				records = append(records, newRecord(key, p, p.data[:maxLineSize]))
+				p.data = p.data[maxLineSize:]
+			}
+			if len(p.data) == 0 {
+				delete(f.partial, key)
+			}
+			break
+		}
+		line := data[:i]
+		data = data[i+1:]
+		if p != nil {
+			line = append(p.data, line...)
+			delete(f.partial, key)
+		} else {
+			p = &partialLine{user: user, time: t}
+		}
+		for len(line) > maxLineSize {
+			records = append(records, newRecord(key, p, line[:maxLineSize]))
+			line = line[maxLineSize:]
+		}
+		records = append(records, newRecord(key, p, line))
+	}
+	f.enqueue(records)
+}
+
+// CloseJob sends the last lines from the job, even if they are missing the new line
+func (f *Forwarder) CloseJob(jobId string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var records []Record
+	for _, stream := range []storage.Stream{storage.Stdout, storage.Stderr} {
+		key := partialKey{jobId, stream}
+		if p, ok := f.partial[key]; ok {
+			records = append(records, newRecord(key, p, p.data))
+			delete(f.partial, key)
+		}
+	}
+	f.enqueue(records)
+}
+
+// Close sends the records left, for up to closeTimeout, and closes the backends
+func (f *Forwarder) Close() {
+	f.mu.Lock()
+	var records []Record
+	for key, p := range f.partial {
+		records = append(records, newRecord(key, p, p.data))
+	}
+	f.partial = make(map[partialKey]*partialLine)
+	f.enqueue(records)
+	f.mu.Unlock()
+
+	close(f.done)
+	f.wg.Wait()
+}
+
+// flushPartial sends the lines held for longer than partialFlushDelay, so the output from commands waiting for
+// input is not held back
+func (f *Forwarder) flushPartial(done <-chan struct{}) {
+	ticker := time.NewTicker(partialFlushDelay / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			f.mu.Lock()
+			var records []Record
+			for key, p := range f.partial {
+				if now.Sub(p.time) >= partialFlushDelay {
+					records = append(records, newRecord(key, p, p.data))
+					delete(f.partial, key)
+				}
+			}
+			f.enqueue(records)
+			f.mu.Unlock()
+		}
+	}
+}
+
+// enqueue adds the records to the queue of every sink
+func (f *Forwarder) enqueue(records []Record) {
+	if len(records) == 0 {
+		return
+	}
+	for _, q := range f.queues {
+		q.add(records)
+	}
+}
+
+// newRecord returns the record for a line of output. The line captured in multiple pieces has the time of the first one.
+func newRecord(key partialKey, p *partialLine, line []byte) Record {
+	return Record{
+		Time:   p.time,
+		JobId:  key.jobId,
+		User:   p.user,
+		Stream: key.stream.String(),
+		Text:   string(bytes.TrimSuffix(line, []byte("\r"))),
+	}
+}
+
+// queue buffers the records for a sink, which are sent by run
+type queue struct {
+	name    string
+	backend Backend
+	opts    QueueOptions
+	mu      sync.Mutex
+	records []Record
+	dropped int64
+	ready   chan struct{}
+}
+
+func newQueue(sink Sink) *queue {
+	return &queue{
+		name:    sink.Name,
+		backend: sink.Backend,
+		opts:    sink.Queue,
+		ready:   make(chan struct{}, 1),
+	}
+}
+
+// add appends the records to the queue, dropping the oldest ones once it's full
+func (q *queue) add(records []Record) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.records = append(q.records, records...)
+	if over := len(q.records) - q.opts.MaxRecords; over > 0 {
+		q.records = q.records[over:]
+		q.dropped += int64(over)
+	}
+	if len(q.records) >= q.opts.BatchSize {
+		select {
+		case q.ready <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// run sends the records in batches, once a batch is full or every flush interval, until done is closed.
+// A batch which can't be sent is retried with an exponential backoff, while the new records keep being queued.
+func (q *queue) run(done <-chan struct{}) {
+	defer func() {
+		if err := q.backend.Close(); err != nil {
+			slog.Error("error closing log sink", slog.String("sink", q.name), slog.Any("error", err))
+		}
+	}()
+	ticker := time.NewTicker(q.opts.FlushInterval)
+	defer ticker.Stop()
+	var backoff time.Duration
+	var retry <-chan time.Time
+	for {
+		select {
+		case <-done:
+			q.drain()
+			return
+		case <-q.ready:
+		case <-ticker.C:
+		case <-retry:
+			retry = nil
+		}
+		if retry != nil {
+			// waiting to retry the last batch
+			continue
+		}
+
+		for {
+			sent, err := q.send(context.Background())
+			if err != nil {
+				backoff = min(max(2*backoff, minRetryBackoff), maxRetryBackoff)
+				retry = time.After(backoff)
+				slog.Warn("error sending output to log sink, retrying", slog.String("sink", q.name),
+					slog.Duration("backoff", backoff), slog.Any("error", err))
+				break
+			}
+			backoff = 0
+			if !sent {
+				break
+			}
+		}
+	}
+}
+
+// send sends the next batch, returning false when the queue is empty. The batch is only removed from the
+// queue once it's sent.
+func (q *queue) send(ctx context.Context) (bool, error) {
+	q.mu.Lock()
+	batch := q.records[:min(len(q.records), q.opts.BatchSize)]
+	dropped := q.dropped
+	q.dropped = 0
+	q.mu.Unlock()
+	if dropped > 0 {
+		slog.Warn("log sink queue full, dropped the oldest output", slog.String("sink", q.name), slog.Int64("records", dropped))
+	}
+	if len(batch) == 0 {
+		return false, nil
+	}
+	if err := q.backend.Write(ctx, batch); err != nil {
+		return false, err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	// the oldest records may have been dropped while sending the batch
+	sentLeft := len(batch) - int(q.dropped)
+	if sentLeft > 0 {
+		q.records = q.records[sentLeft:]
+	}
+	return true, nil
+}
+
+// drain sends the records left, retrying for up to closeTimeout
+func (q *queue) drain() {
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+	backoff := drainRetryBackoff
+	for {
+		sent, err := q.send(ctx)
+		if err == nil && !sent {
+			return
+		}
+		if err == nil {
+			continue
+		}
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			q.mu.Lock()
+			left := len(q.records)
+			q.mu.Unlock()
+			slog.Error("could not send the output left to log sink", slog.String("sink", q.name),
+				slog.Int("records", left), slog.Any("error", err))
+			return
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package sink
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mhsantos/rlcp/cmd/server/internal/storage"
+)
+
+// fakeBackend records the batches written, failing the first ones
+type fakeBackend struct {
+	mu      sync.Mutex
+	fail    int
+	records []Record
+}
+
+func (b *fakeBackend) Write(_ context.Context, records []Record) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.fail > 0 {
+		b.fail--
+		return errors.New("unavailable")
+	}
+	b.records = append(b.records, records...)
+	return nil
+}
+
+func (b *fakeBackend) Close() error {
+	return nil
+}
+
+func (b *fakeBackend) texts() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var texts []string
+	for _, record := range b.records {
+		texts = append(texts, record.Stream+":"+record.Text)
+	}
+	return texts
+}
+
+func TestForwarder(t *testing.T) {
+	backend := &fakeBackend{fail: 1}
+	f := NewForwarder([]Sink{{
+		Name:    "fake",
+		Backend: backend,
+		Queue:   QueueOptions{MaxRecords: 100, BatchSize: 2, FlushInterval: 10 * time.Millisecond},
+	}})
+
+	now := time.Now()
+	f.Send("job", "user@email.com", storage.Stdout, now, []byte("first\nsec"))
+	f.Send("job", "user@email.com", storage.Stderr, now, []byte("error\r\n"))
+	f.Send("job", "user@email.com", storage.Stdout, now, []byte("ond\nlast"))
+	f.CloseJob("job")
+	f.Close()
+
+	expected := []string{"stdout:first", "stderr:error", "stdout:second", "stdout:last"}
+	if texts := backend.texts(); !slices.Equal(texts, expected) {
+		t.Fatalf("unexpected records: %v, expected %v", texts, expected)
+	}
+	for _, record := range backend.records {
+		if record.JobId != "job" || record.User != "user@email.com" || !record.Time.Equal(now) {
+			t.Fatalf("unexpected record: %+v", record)
+		}
+	}
+}
+
+func TestForwarderQueueFull(t *testing.T) {
+	backend := &fakeBackend{fail: 1000}
+	f := NewForwarder([]Sink{{
+		Name:    "fake",
+		Backend: backend,
+		Queue:   QueueOptions{MaxRecords: 3, BatchSize: 10, FlushInterval: time.Hour},
+	}})
+	for i := range 10 {
+		f.Send("job", "user", storage.Stdout, time.Now(), []byte(strconv.Itoa(i)+"\n"))
+	}
+	backend.mu.Lock()
+	backend.fail = 0
+	backend.mu.Unlock()
+	f.Close()
+
+	expected := []string{"stdout:7", "stdout:8", "stdout:9"}
+	if texts := backend.texts(); !slices.Equal(texts, expected) {
+		t.Fatalf("unexpected records: %v, expected %v", texts, expected)
+	}
+}
+
+func TestSyslogTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	received := make(chan []string)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		var msgs []string
+		for range 2 {
+			size, _ := r.ReadString(' ')
+			n, _ := strconv.Atoi(strings.TrimSpace(size))
+			msg := make([]byte, n)
+			if _, err := io.ReadFull(r, msg); err != nil {
+				break
+			}
+			msgs = append(msgs, string(msg))
+		}
+		received <- msgs
+	}()
+
+	s, err := NewSyslog("tcp://" + ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.hostname = "host"
+	at := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	err = s.Write(context.Background(), []Record{
+		{Time: at, JobId: "job", User: `a"b`, Stream: "stdout", Text: "hello"},
+		{Time: at, JobId: "job", User: "c", Stream: "stderr", Text: "world"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := <-received
+	expected := []string{
+		`<14>1 2024-05-01T10:00:00Z host rlcp - stdout [rlcp@32473 job="job" user="a\"b"] hello`,
+		`<14>1 2024-05-01T10:00:00Z host rlcp - stderr [rlcp@32473 job="job" user="c"] world`,
+	}
+	if !slices.Equal(msgs, expected) {
+		t.Fatalf("unexpected messages: %q", msgs)
+	}
+	s.Close()
+}
@@ -0,0 +1,148 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// facilityUser and severityInfo make up the priority of the syslog messages
+	facilityUser = 1
+	severityInfo = 6
+	// sdId identifies the structured data with the job details, using the enterprise number reserved
+	// for documentation, since it's not registered
+	sdId      = "rlcp@32473"
+	syslogApp = "rlcp"
+	// syslogTimeout limits connecting and writing to the syslog server
+	syslogTimeout = 10 * time.Second
+)
+
+// Syslog sends the records as RFC 5424 messages to a syslog server, over a unix socket, UDP or TCP.
+// TCP messages are framed by octet counting, as in RFC 6587. The connection is opened again after a failure.
+type Syslog struct {
+	network  string
+	address  string
+	hostname string
+	conn     net.Conn
+}
+
+// NewSyslog returns a sink for the syslog server at address, in the form udp://host:port, tcp://host:port
+// or unix:///path. The connection is opened once the first records are sent.
+func NewSyslog(address string) (*Syslog, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid syslog address %q: %w", address, err)
+	}
+	s := &Syslog{network: u.Scheme}
+	switch u.Scheme {
+	case "udp", "tcp":
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid syslog address %q: missing host", address)
+		}
+		s.address = u.Host
+	case "unix":
+		if u.Path == "" {
+			return nil, fmt.Errorf("invalid syslog address %q: missing path", address)
+		}
+		s.address = u.Path
+	default:
+		return nil, fmt.Errorf("invalid syslog address %q: the protocol must be udp, tcp or unix", address)
+	}
+	if s.hostname, err = os.Hostname(); err != nil || s.hostname == "" {
+		s.hostname = "-"
+	}
+	return s, nil
+}
+
+func (s *Syslog) Write(ctx context.Context, records []Record) error {
+	if s.conn == nil {
+		if err := s.dial(ctx); err != nil {
+			return err
+		}
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		s.conn.SetWriteDeadline(deadline)
+	} else {
+		s.conn.SetWriteDeadline(time.Now().Add(syslogTimeout))
+	}
+	if s.network == "tcp" {
+		// the stream is framed, so the whole batch is written at once
+		var buf bytes.Buffer
+		for _, record := range records {
+			msg := s.format(record)
+			buf.WriteString(strconv.Itoa(len(msg)) + " ")
+			buf.Write(msg)
+		}
+		return s.write(buf.Bytes())
+	}
+	for _, record := range records {
+		if err := s.write(s.format(record)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write sends the data, closing the connection on failure
+func (s *Syslog) write(data []byte) error {
+	if _, err := s.conn.Write(data); err != nil {
+		s.Close()
+		return err
+	}
+	return nil
+}
+
+func (s *Syslog) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// dial connects to the syslog server. Unix sockets are datagram sockets on most systems, falling back to
+// stream sockets.
+func (s *Syslog) dial(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: syslogTimeout}
+	var err error
+	if s.network != "unix" {
+		s.conn, err = dialer.DialContext(ctx, s.network, s.address)
+		return err
+	}
+	if s.conn, err = dialer.DialContext(ctx, "unixgram", s.address); err == nil {
+		return nil
+	}
+	s.conn, err = dialer.DialContext(ctx, "unix", s.address)
+	return err
+}
+
+// format returns the RFC 5424 message for the record, with the job id and user as structured data,
+// and the stream as the message id
+func (s *Syslog) format(record Record) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<%d>1 %s %s %s - %s [%s job=\"%s\" user=\"%s\"] ",
+		facilityUser*8+severityInfo,
+		record.Time.UTC().Format(time.RFC3339Nano),
+		s.hostname,
+		syslogApp,
+		record.Stream,
+		sdId,
+		escapeParam(record.JobId),
+		escapeParam(record.User),
+	)
+	buf.WriteString(record.Text)
+	return buf.Bytes()
+}
+
+// escapeParam escapes the characters not allowed in structured data parameter values
+func escapeParam(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
@@ -0,0 +1,262 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	usersBucket = []byte("users")
+	jobsBucket  = []byte("jobs")
+)
+
+// BoltStorage keeps the users and jobs in a bbolt database, so the jobs survive a server restart.
+// All of them are also kept in memory, and the database is only read when the storage is opened.
+// A job is written when it's saved, and again once it finishes, along with the output left in its buffer
+// and its marks. Jobs found running when the storage is opened were interrupted by the restart, and are
+// listed by Interrupted, so they can be reconciled with their processes.
+type BoltStorage struct {
+	mu          sync.RWMutex
+	db          *bolt.DB
+	users       map[string]User
+	jobs        map[string]*Job
+	interrupted []*Job
+	// watching has the jobs to write again once they finish. Once closed is set, Close writes the ones
+	// already finished, after waiting for the ones being written, counted by finishing. The jobs finishing
+	// later are left to be reconciled on the next start.
+	watching  map[*Job]bool
+	finishing sync.WaitGroup
+	closed    bool
+}
+
+// userRecord is the user as stored in the database, keyed by the user id
+type userRecord struct {
+	Email string     `json:"email"`
+	Role  Permission `json:"role"`
+}
+
+// NewBoltStorage opens the database at path, creating it if needed, and restores the jobs from it.
+// opts has the store with the output from the jobs, and the master key for the encrypted output.
+func NewBoltStorage(path string, opts LogOptions) (*BoltStorage, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("could not open the job database %s: %w", path, err)
+	}
+	s := &BoltStorage{
+		db:       db,
+		users:    make(map[string]User),
+		jobs:     make(map[string]*Job),
+		watching: make(map[*Job]bool),
+	}
+	if err := s.load(opts); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("could not load the job database %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Close closes the database, once the final records of the jobs which already finished are written
+func (b *BoltStorage) Close() error {
+	b.mu.Lock()
+	b.closed = true
+	var finished []*Job
+	for job := range b.watching {
+		if job.Finished() {
+			finished = append(finished, job)
+		}
+	}
+	b.mu.Unlock()
+	b.finishing.Wait()
+	for _, job := range finished {
+		b.finish(job)
+	}
+	return b.db.Close()
+}
+
+// load reads the users and jobs from the database, adding the default users when there are none.
+// A job which can't be restored is logged and skipped, so it doesn't keep the server from starting.
+func (b *BoltStorage) load(opts LogOptions) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		users, err := tx.CreateBucketIfNotExists(usersBucket)
+		if err != nil {
+			return err
+		}
+		if users.Stats().KeyN == 0 {
+			for _, usr := range defaultUsers() {
+				data, err := json.Marshal(userRecord{Email: usr.email, Role: usr.role})
+				if err != nil {
+					return err
+				}
+				if err := users.Put([]byte(usr.id), data); err != nil {
+					return err
+				}
+			}
+		}
+		err = users.ForEach(func(k, v []byte) error {
+			var rec userRecord
+			if err := json.Unmarshal(v, &rec); err != nil {
+				return fmt.Errorf("invalid user %s: %w", k, err)
+			}
+			b.users[string(k)] = User{id: string(k), email: rec.Email, role: rec.Role}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		jobs, err := tx.CreateBucketIfNotExists(jobsBucket)
+		if err != nil {
+			return err
+		}
+		return jobs.ForEach(func(k, v []byte) error {
+			var rec JobRecord
+			if err := json.Unmarshal(v, &rec); err != nil {
+				slog.Error("error reading job record", slog.String("jobid", string(k)), slog.Any("error", err))
+				return nil
+			}
+			job, err := RestoreJob(rec, opts)
+			if err != nil {
+				slog.Error("error restoring job", slog.String("jobid", string(k)), slog.Any("error", err))
+				return nil
+			}
+			if !rec.Finished {
+				b.interrupted = append(b.interrupted, job)
+			}
+			b.jobs[string(k)] = job
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, job := range b.interrupted {
+		b.watch(job)
+	}
+	return nil
+}
+
+// Interrupted returns the jobs which were running when the server stopped. They are written again
+// once they are marked as exited.
+func (b *BoltStorage) Interrupted() []*Job {
+	return b.interrupted
+}
+
+func (b *BoltStorage) GetUserId(email string) (string, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, usr := range b.users {
+		if usr.email == email {
+			return usr.id, true
+		}
+	}
+	return "", false
+}
+
+func (b *BoltStorage) Authorized(userId string, op Operation) bool {
+	b.mu.RLock()
+	usr, ok := b.users[userId]
+	b.mu.RUnlock()
+	return ok && usr.allowed(op)
+}
+
+// SaveJob writes the job to the database. The first time a running job is saved, it's written again
+// once it finishes.
+func (b *BoltStorage) SaveJob(jobId string, job *Job) {
+	b.mu.Lock()
+	_, saved := b.jobs[jobId]
+	b.jobs[jobId] = job
+	b.mu.Unlock()
+
+	b.write(jobId, job)
+	if !saved && !job.Finished() {
+		b.watch(job)
+	}
+}
+
+func (b *BoltStorage) GetJob(jobId string) (*Job, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	job, ok := b.jobs[jobId]
+	return job, ok
+}
+
+func (b *BoltStorage) ListJobs() []*Job {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	jobs := make([]*Job, 0, len(b.jobs))
+	for _, job := range b.jobs {
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+func (b *BoltStorage) DeleteJob(jobId string) {
+	b.mu.Lock()
+	delete(b.jobs, jobId)
+	b.mu.Unlock()
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).Delete([]byte(jobId))
+	})
+	if err != nil {
+		slog.Error("error deleting job record", slog.String("jobid", jobId), slog.Any("error", err))
+	}
+}
+
+// watch writes the job again once it finishes, unless the storage is closed before that
+func (b *BoltStorage) watch(job *Job) {
+	b.mu.Lock()
+	b.watching[job] = true
+	b.mu.Unlock()
+	go func() {
+		<-job.Exited()
+		b.mu.Lock()
+		if b.closed {
+			b.mu.Unlock()
+			return
+		}
+		delete(b.watching, job)
+		b.finishing.Add(1)
+		b.mu.Unlock()
+		defer b.finishing.Done()
+		b.finish(job)
+	}()
+}
+
+// finish stores the output left for a finished job, and writes its final record, unless it was
+// removed in the meantime
+func (b *BoltStorage) finish(job *Job) {
+	if err := job.Persist(); err != nil {
+		slog.Error("error storing the job output", slog.String("jobid", job.Id.String()), slog.Any("error", err))
+	}
+	b.write(job.Id.String(), job)
+}
+
+// write writes the record for the job, unless it was removed or replaced in the meantime. The record is
+// taken inside the transaction, and bolt runs a single write transaction at a time, so the records are
+// written in the order they are taken, and an earlier record never replaces the final one. The storage
+// interface doesn't return errors, so they are logged.
+func (b *BoltStorage) write(jobId string, job *Job) {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		b.mu.RLock()
+		current, ok := b.jobs[jobId]
+		b.mu.RUnlock()
+		if !ok || current != job {
+			return nil
+		}
+		data, err := json.Marshal(job.Record())
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(jobsBucket).Put([]byte(jobId), data)
+	})
+	if err != nil {
+		slog.Error("error writing job record", slog.String("jobid", jobId), slog.Any("error", err))
+	}
+}
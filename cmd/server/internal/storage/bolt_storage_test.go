@@ -0,0 +1,283 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// readOutput reads the whole output from a finished job
+func readOutput(t *testing.T, job *Job) string {
+	t.Helper()
+	reader := job.NewReader(0, false)
+	defer reader.Close()
+	var out []byte
+	for {
+		chunk, err := reader.Next(context.Background())
+		if errors.Is(err, io.EOF) {
+			return string(out)
+		}
+		if err != nil {
+			t.Fatalf("error reading the output: %v", err)
+		}
+		out = append(out, chunk.Data...)
+	}
+}
+
+// waitFinishedRecord waits until the record stored for the job is the one of the finished job
+func waitFinishedRecord(t *testing.T, b *BoltStorage, jobId string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		var rec JobRecord
+		err := b.db.View(func(tx *bolt.Tx) error {
+			data := tx.Bucket(jobsBucket).Get([]byte(jobId))
+			if data == nil {
+				return nil
+			}
+			return json.Unmarshal(data, &rec)
+		})
+		if err != nil {
+			t.Fatalf("error reading the job record: %v", err)
+		}
+		if rec.Finished {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for the final job record")
+}
+
+func TestBoltStorageRestoresFinishedJob(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "jobs.db")
+	opts := LogOptions{Store: NewLocalStore(filepath.Join(dir, "output"))}
+
+	db, err := NewBoltStorage(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := NewJob(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.Command = "echo"
+	jobId := job.Id.String()
+	db.SaveJob(jobId, job)
+	if err := job.ProcessOutput(Stdout, []byte("hello\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := job.ProcessOutput(Stderr, []byte("world\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the job is saved again while it finishes, like the server does after starting it and for the
+	// webhook deliveries, and none of those writes may replace the final record
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				db.SaveJob(jobId, job)
+			}
+		}()
+	}
+	job.SetStatus(Completed, "")
+	job.CloseOutput()
+	job.MarkExited(3)
+	wg.Wait()
+	waitFinishedRecord(t, db, jobId)
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = NewBoltStorage(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if interrupted := db.Interrupted(); len(interrupted) != 0 {
+		t.Fatalf("the finished job was restored as interrupted: %v", interrupted)
+	}
+	restored, ok := db.GetJob(jobId)
+	if !ok {
+		t.Fatal("the job was not restored")
+	}
+	if !restored.Finished() || restored.Status() != Completed || restored.ExitCode() != 3 ||
+		restored.Command != "echo" {
+		t.Fatalf("unexpected job restored: status %s, exit code %d, finished %t", restored.Status(),
+			restored.ExitCode(), restored.Finished())
+	}
+	if out := readOutput(t, restored); out != "hello\nworld\n" {
+		t.Fatalf("unexpected output restored: %q", out)
+	}
+}
+
+func TestBoltStorageDeletedJobIsNotWrittenAgain(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "jobs.db")
+	opts := LogOptions{Store: NewMemoryStore()}
+
+	db, err := NewBoltStorage(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := NewJob(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobId := job.Id.String()
+	db.SaveJob(jobId, job)
+	db.DeleteJob(jobId)
+	job.CloseOutput()
+	job.MarkExited(0)
+	// finish runs in the background, so it's called here as well to write the job, if it would
+	db.finish(job)
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = NewBoltStorage(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, ok := db.GetJob(jobId); ok {
+		t.Fatal("the deleted job was restored")
+	}
+}
+
+func TestBoltStorageRestoresInterruptedJobMarks(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "jobs.db")
+	opts := LogOptions{Store: NewLocalStore(filepath.Join(dir, "output"))}
+
+	db, err := NewBoltStorage(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := NewJob(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.log.segmentSize = len("stdout 1\n")
+	jobId := job.Id.String()
+	db.SaveJob(jobId, job)
+	for _, out := range []struct {
+		stream Stream
+		data   string
+	}{{Stdout, "stdout 1\n"}, {Stderr, "stderr 2\n"}, {Stdout, "lost"}} {
+		if err := job.ProcessOutput(out.stream, []byte(out.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the server stops while the job is running, with the last output still in its buffer
+	waitStored(t, job)
+	db.SaveJob(jobId, job)
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = NewBoltStorage(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	interrupted := db.Interrupted()
+	if len(interrupted) != 1 {
+		t.Fatalf("unexpected interrupted jobs: %v", interrupted)
+	}
+	interrupted[0].SetStatus(Lost, "")
+	interrupted[0].MarkExited(UnknownExitCode)
+	waitFinishedRecord(t, db, jobId)
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = NewBoltStorage(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	restored, _ := db.GetJob(jobId)
+	reader := restored.NewReader(0, false)
+	defer reader.Close()
+	var chunks []Chunk
+	for {
+		chunk, err := reader.Next(context.Background())
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	if len(chunks) != 2 || string(chunks[0].Data) != "stdout 1\n" || string(chunks[1].Data) != "stderr 2\n" {
+		t.Fatalf("unexpected output restored: %+v", chunks)
+	}
+	if chunks[0].Stream != Stdout || chunks[1].Stream != Stderr || chunks[0].Time.IsZero() || chunks[1].Time.IsZero() {
+		t.Fatalf("the output restored lost its marks: %+v", chunks)
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "output", jobId))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".marks") {
+			t.Fatalf("the marks of the segments were not removed: %s", entry.Name())
+		}
+	}
+}
+
+func TestBoltStorageCloseWritesFinishedJob(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "jobs.db")
+	opts := LogOptions{Store: NewLocalStore(filepath.Join(dir, "output"))}
+
+	db, err := NewBoltStorage(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := NewJob(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobId := job.Id.String()
+	db.SaveJob(jobId, job)
+	if err := job.ProcessOutput(Stdout, []byte("done\n")); err != nil {
+		t.Fatal(err)
+	}
+	// the job exits while the server stops
+	job.SetStatus(Completed, "")
+	job.CloseOutput()
+	job.MarkExited(0)
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = NewBoltStorage(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if interrupted := db.Interrupted(); len(interrupted) != 0 {
+		t.Fatal("the job which exited while the storage was closed was restored as interrupted")
+	}
+	restored, ok := db.GetJob(jobId)
+	if !ok || restored.Status() != Completed || restored.ExitCode() != 0 {
+		t.Fatalf("unexpected job restored: %t", ok)
+	}
+	if out := readOutput(t, restored); out != "done\n" {
+		t.Fatalf("unexpected output restored: %q", out)
+	}
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// stuckTimeout is how long a job is kept in a final status without its process exiting, before the collector
+// considers it ended. A process stopped while in uninterruptible sleep may never exit, and its job would
+// never be removed otherwise.
+const stuckTimeout = 10 * time.Minute
+
+// RetentionPolicy limits how long and how much of the finished jobs is kept.
+// A zero value disables the respective limit.
+type RetentionPolicy struct {
+	// MaxAge is how long a job is kept after it finishes
+	MaxAge time.Duration
+	// MaxBytes is the maximum space used to store the output for all the jobs, after compression
+	MaxBytes int64
+	// MaxJobs is the maximum number of jobs kept
+	MaxJobs int
+}
+
+// Collector periodically removes the finished jobs exceeding the retention policy, starting from the oldest ones.
+// Removing a job deletes its record from the storage, its output files and its artifacts. Running jobs are never
+// removed, even if the limits are exceeded, but a job left in a final status by a process which never exits is
+// collected once it's been in that status for stuckTimeout, as if it ended when the status was set.
+type Collector struct {
+	db       JobStorage
+	policy   RetentionPolicy
+	interval time.Duration
+}
+
+func NewCollector(db JobStorage, policy RetentionPolicy, interval time.Duration) *Collector {
+	return &Collector{
+		db:       db,
+		policy:   policy,
+		interval: interval,
+	}
+}
+
+// Run enforces the retention policy every interval, until ctx is done
+func (c *Collector) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if removed := c.Collect(); removed > 0 {
+				slog.Info("removed jobs exceeding the retention policy", slog.Int("jobs", removed))
+			}
+		}
+	}
+}
+
+// Collect removes the finished jobs exceeding the retention policy and returns how many were removed
+func (c *Collector) Collect() int {
+	jobs := c.db.ListJobs()
+	total := len(jobs)
+	var totalBytes int64
+	type endedJob struct {
+		job *Job
+		end time.Time
+	}
+	now := time.Now()
+	ended := make([]endedJob, 0, len(jobs))
+	for _, job := range jobs {
+		totalBytes += job.StoredSize()
+		if end, ok := jobEnd(job, now); ok {
+			ended = append(ended, endedJob{job, end})
+		}
+	}
+	slices.SortFunc(ended, func(a, b endedJob) int {
+		return a.end.Compare(b.end)
+	})
+
+	removed := 0
+	for _, e := range ended {
+		job := e.job
+		expired := c.policy.MaxAge > 0 && now.Sub(e.end) > c.policy.MaxAge
+		tooMany := c.policy.MaxJobs > 0 && total > c.policy.MaxJobs
+		tooBig := c.policy.MaxBytes > 0 && totalBytes > c.policy.MaxBytes
+		if !expired && !tooMany && !tooBig {
+			// the remaining jobs finished later, so they don't exceed the age limit either
+			break
+		}
+
+		size := job.StoredSize()
+		if !job.Finished() {
+			slog.Warn("removing job whose process never exited", slog.String("jobid", job.Id.String()),
+				slog.String("status", job.Status().String()))
+			// the readers following the output stop, since the process won't write anything else
+			job.CloseOutput()
+		}
+		if err := job.Remove(); err != nil {
+			slog.Error("error removing job files", slog.String("jobid", job.Id.String()), slog.Any("error", err))
+			continue
+		}
+		c.db.DeleteJob(job.Id.String())
+		total--
+		totalBytes -= size
+		removed++
+	}
+	return removed
+}
+
+// jobEnd returns when the job ended, and false if it's still running. A job ends once its process exits, or
+// once it's been in a final status for stuckTimeout, for a process which never exits.
+func jobEnd(job *Job, now time.Time) (time.Time, bool) {
+	if job.Finished() {
+		return job.EndTime(), true
+	}
+	changed := job.StatusTime()
+	if job.Status() == Running || changed.IsZero() || now.Sub(changed) < stuckTimeout {
+		return time.Time{}, false
+	}
+	return changed, true
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCollectorRemovesEndedJobs(t *testing.T) {
+	db := NewMemStorage()
+	now := time.Now()
+	tests := []struct {
+		name string
+		// status is the final status of the job, set at statusTime, and end is when its process exited, if it did
+		status     JobStatus
+		statusTime time.Time
+		end        time.Time
+		removed    bool
+	}{
+		{name: "expired", status: Completed, end: now.Add(-2 * time.Hour), removed: true},
+		{name: "recent", status: Completed, end: now.Add(-time.Minute)},
+		{name: "running", status: Running},
+		{name: "running for long", status: Running, statusTime: now.Add(-3 * time.Hour)},
+		{name: "stuck after stopped", status: Stopped, statusTime: now.Add(-2 * time.Hour), removed: true},
+		{name: "stuck after lost", status: Lost, statusTime: now.Add(-2 * time.Hour), removed: true},
+		{name: "stopped while exiting", status: Stopped, statusTime: now.Add(-time.Second)},
+		{name: "stuck recently", status: Errored, statusTime: now.Add(-stuckTimeout - time.Minute)},
+	}
+	jobs := make(map[string]*Job)
+	for _, tt := range tests {
+		job, err := NewJob(LogOptions{Store: NewMemoryStore()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := job.ProcessOutput(Stdout, []byte(tt.name+"\n")); err != nil {
+			t.Fatal(err)
+		}
+		job.SetStatus(tt.status, "")
+		job.statusTime = tt.statusTime
+		if !tt.end.IsZero() {
+			job.CloseOutput()
+			job.MarkExited(0)
+			job.endTime = tt.end
+		}
+		db.SaveJob(job.Id.String(), job)
+		jobs[tt.name] = job
+	}
+
+	reader := jobs["stuck after stopped"].NewReader(0, true)
+	defer reader.Close()
+	collector := NewCollector(db, RetentionPolicy{MaxAge: time.Hour}, time.Minute)
+	if removed := collector.Collect(); removed != 3 {
+		t.Fatalf("%d jobs removed, expected 3", removed)
+	}
+	for _, tt := range tests {
+		if _, ok := db.GetJob(jobs[tt.name].Id.String()); ok == tt.removed {
+			t.Errorf("job %s: kept %t, expected %t", tt.name, ok, !tt.removed)
+		}
+	}
+	// the reader following the output of the stuck job stops
+	for {
+		if _, err := reader.Next(t.Context()); err != nil {
+			break
+		}
+	}
+
+	// once there are too many jobs, the ones which ended are removed oldest first, the stuck ones by their status time
+	collector = NewCollector(db, RetentionPolicy{MaxJobs: 4}, time.Minute)
+	if removed := collector.Collect(); removed != 1 {
+		t.Fatalf("%d jobs removed, expected 1", removed)
+	}
+	var names []string
+	for _, tt := range tests {
+		if _, ok := db.GetJob(jobs[tt.name].Id.String()); ok {
+			names = append(names, tt.name)
+		}
+	}
+	expected := []string{"recent", "running", "running for long", "stopped while exiting"}
+	if !slices.Equal(names, expected) {
+		t.Fatalf("unexpected jobs kept: %v", names)
+	}
+}
@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+)
+
+const (
+	// KeySize is the size of the master key and the data keys, for AES-256
+	KeySize = 32
+	// keyFileName is the object in the store with the job data key, wrapped by the master key
+	keyFileName = "key"
+)
+
+// MasterKey wraps the data keys of the jobs. Every job encrypts its output files with a data key of its own,
+// which is stored next to them, encrypted by the master key, so rotating the master key only rewrites the
+// data keys, and not the output.
+type MasterKey struct {
+	aead cipher.AEAD
+}
+
+// LoadMasterKey reads the master key from a file with KeySize random bytes
+func LoadMasterKey(path string) (*MasterKey, error) {
+	key, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the master key: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("the master key must have %d bytes, got %d", KeySize, len(key))
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &MasterKey{aead: aead}, nil
+}
+
+// newDataKey generates a data key for the job, returning the cipher for it and the key wrapped by the master key
+func (k *MasterKey) newDataKey(jobId string) (cipher.AEAD, []byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, nil, fmt.Errorf("could not generate the data key: %w", err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	wrapped, err := seal(k.aead, key, []byte(jobId))
+	if err != nil {
+		return nil, nil, err
+	}
+	return aead, wrapped, nil
+}
+
+// unwrap decrypts the data key for the job
+func (k *MasterKey) unwrap(wrapped []byte, jobId string) ([]byte, error) {
+	return open(k.aead, wrapped, []byte(jobId))
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts data with a random nonce, which is prepended to the result. aad binds the result to where
+// it's stored, so encrypted files can't be swapped between jobs or positions in the log.
+func seal(aead cipher.AEAD, data, aad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(data)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("could not generate the nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, data, aad), nil
+}
+
+// open decrypts the result of seal
+func open(aead cipher.AEAD, data, aad []byte) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("encrypted data is too short")
+	}
+	return aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], aad)
+}
+
+// segmentAAD identifies the segment with index in the job log
+func (c *CmdLog) segmentAAD(index int) []byte {
+	return []byte(c.id + "/" + strconv.Itoa(index))
+}
+
+// writeKey stores the wrapped data key along with the output
+func (c *CmdLog) writeKey() error {
+	return c.store.Put(c.id, keyFileName, c.wrappedKey)
+}
+
+// readKey reads the wrapped data key stored along with the output
+func (c *CmdLog) readKey() ([]byte, error) {
+	rc, err := c.store.Get(c.id, keyFileName)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// RewrapKeys re-encrypts the data keys of all the jobs in the store, wrapped by oldKey, with newKey,
+// and returns the number of keys rewrapped. Keys already wrapped by newKey are skipped, so a rotation
+// which failed midway can be run again.
+func RewrapKeys(store OutputStore, oldKey, newKey *MasterKey) (int, error) {
+	jobs, err := store.Jobs()
+	if err != nil {
+		return 0, err
+	}
+	rewrapped := 0
+	for _, jobId := range jobs {
+		rc, err := store.Get(jobId, keyFileName)
+		if errors.Is(err, fs.ErrNotExist) {
+			// the output from the job is not encrypted
+			continue
+		}
+		if err != nil {
+			return rewrapped, err
+		}
+		wrapped, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return rewrapped, err
+		}
+		key, err := oldKey.unwrap(wrapped, jobId)
+		if err != nil {
+			if _, newErr := newKey.unwrap(wrapped, jobId); newErr == nil {
+				continue
+			}
+			return rewrapped, fmt.Errorf("could not unwrap the key for job %s: %w", jobId, err)
+		}
+		rewrappedKey, err := seal(newKey.aead, key, []byte(jobId))
+		if err != nil {
+			return rewrapped, err
+		}
+		if err := store.Put(jobId, keyFileName, rewrappedKey); err != nil {
+			return rewrapped, err
+		}
+		rewrapped++
+	}
+	return rewrapped, nil
+}
@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newMasterKey returns a random master key, loaded from a file like the server does
+func newMasterKey(t *testing.T) *MasterKey {
+	t.Helper()
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "master.key")
+	if err := os.WriteFile(path, key, 0600); err != nil {
+		t.Fatal(err)
+	}
+	master, err := LoadMasterKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return master
+}
+
+// finishedJob runs a job which writes the output to stdout and stderr, in files with segmentSize bytes, and
+// returns it once it's finished and persisted
+func finishedJob(t *testing.T, opts LogOptions, segmentSize int, output ...string) *Job {
+	t.Helper()
+	job, err := NewJob(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.log.segmentSize = segmentSize
+	for i, out := range output {
+		if err := job.ProcessOutput(Stream(i%2), []byte(out)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	job.SetStatus(Completed, "")
+	job.CloseOutput()
+	job.MarkExited(0)
+	if err := job.Persist(); err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+// readStreams reads the whole output from the job, prefixing each chunk with the stream it was written to
+func readStreams(t *testing.T, job *Job) string {
+	t.Helper()
+	reader := job.NewReader(0, false)
+	defer reader.Close()
+	var out strings.Builder
+	for {
+		chunk, err := reader.Next(t.Context())
+		if errors.Is(err, io.EOF) {
+			return out.String()
+		}
+		if err != nil {
+			t.Fatalf("error reading the output: %v", err)
+		}
+		out.WriteString(chunk.Stream.String() + ":" + string(chunk.Data) + ";")
+	}
+}
+
+func TestEncryptedOutputRoundTrip(t *testing.T) {
+	output := []string{"first line\n", "an error\n", "second line\n", "done\n"}
+	for _, compress := range []bool{false, true} {
+		t.Run(map[bool]string{false: "plain", true: "compressed"}[compress], func(t *testing.T) {
+			store := NewMemoryStore()
+			opts := LogOptions{Store: store, Compress: compress, MasterKey: newMasterKey(t)}
+			job := finishedJob(t, opts, 16, output...)
+			expected := readStreams(t, job)
+
+			objects := store.objects[job.Id.String()]
+			if len(objects) < 3 {
+				t.Fatalf("expected the key, the marks and the output files, got %d objects", len(objects))
+			}
+			for name, data := range objects {
+				for _, out := range output {
+					if bytes.Contains(data, []byte(strings.TrimSpace(out))) {
+						t.Fatalf("%s has the output unencrypted", name)
+					}
+				}
+			}
+
+			restored, err := RestoreJob(job.Record(), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out := readStreams(t, restored); out != expected {
+				t.Fatalf("unexpected output restored: %q, expected %q", out, expected)
+			}
+		})
+	}
+}
+
+func TestDataKeyWrapping(t *testing.T) {
+	master := newMasterKey(t)
+	aead, wrapped, err := master.newDataKey("job-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := master.unwrap(wrapped, "job-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unwrapped, err := newAEAD(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := seal(aead, []byte("output"), []byte("job-1/0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain, err := open(unwrapped, sealed, []byte("job-1/0")); err != nil || string(plain) != "output" {
+		t.Fatalf("the unwrapped key decrypted %q, %v", plain, err)
+	}
+
+	if _, err := master.unwrap(wrapped, "job-2"); err == nil {
+		t.Fatal("the key of a job was unwrapped for another one")
+	}
+	if _, err := newMasterKey(t).unwrap(wrapped, "job-1"); err == nil {
+		t.Fatal("the key was unwrapped by another master key")
+	}
+	if _, err := open(aead, sealed, []byte("job-1/1")); err == nil {
+		t.Fatal("a file was decrypted at another position of the log")
+	}
+}
+
+func TestRewrapKeys(t *testing.T) {
+	store := NewMemoryStore()
+	oldKey, newKey := newMasterKey(t), newMasterKey(t)
+	encrypted := LogOptions{Store: store, MasterKey: oldKey}
+	jobs := []*Job{
+		finishedJob(t, encrypted, 8, "output of ", "the first job\n"),
+		finishedJob(t, encrypted, 8, "output of ", "the second job\n"),
+	}
+	finishedJob(t, LogOptions{Store: store}, 8, "unencrypted output\n")
+	records := make([]JobRecord, 0)
+	expected := make([]string, 0)
+	for _, job := range jobs {
+		records = append(records, job.Record())
+		expected = append(expected, readStreams(t, job))
+	}
+
+	rewrapped, err := RewrapKeys(store, oldKey, newKey)
+	if err != nil || rewrapped != 2 {
+		t.Fatalf("RewrapKeys returned %d, %v", rewrapped, err)
+	}
+	// the records kept by the server still have the keys wrapped by the old master key
+	for i, rec := range records {
+		restored, err := RestoreJob(rec, LogOptions{Store: store, MasterKey: newKey})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out := readStreams(t, restored); out != expected[i] {
+			t.Fatalf("unexpected output restored: %q, expected %q", out, expected[i])
+		}
+		if _, err := RestoreJob(rec, LogOptions{Store: store, MasterKey: oldKey}); err == nil {
+			t.Fatal("the job was restored with the old master key")
+		}
+	}
+
+	// a rotation which is run again skips the keys already rewrapped
+	if rewrapped, err := RewrapKeys(store, oldKey, newKey); err != nil || rewrapped != 0 {
+		t.Fatalf("RewrapKeys run again returned %d, %v", rewrapped, err)
+	}
+	if _, err := RewrapKeys(store, newMasterKey(t), newMasterKey(t)); err == nil {
+		t.Fatal("the keys were rewrapped from the wrong master key")
+	}
+}
+
+func TestRestoreEncryptedJobWithWrongKey(t *testing.T) {
+	store := NewMemoryStore()
+	master := newMasterKey(t)
+	job := finishedJob(t, LogOptions{Store: store, MasterKey: master}, 8, "secret output\n")
+	rec := job.Record()
+
+	if _, err := RestoreJob(rec, LogOptions{Store: store, MasterKey: newMasterKey(t)}); err == nil ||
+		!strings.Contains(err.Error(), "could not unwrap") {
+		t.Fatalf("restoring with the wrong master key returned %v", err)
+	}
+	if _, err := RestoreJob(rec, LogOptions{Store: store}); err == nil {
+		t.Fatal("the encrypted job was restored without a master key")
+	}
+
+	// a file swapped for another one of the same job can't be decrypted
+	objects := store.objects[job.Id.String()]
+	objects["0.log.enc"], objects["1.log.enc"] = objects["1.log.enc"], objects["0.log.enc"]
+	restored, err := RestoreJob(rec, LogOptions{Store: store, MasterKey: master})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader := restored.NewReader(0, false)
+	defer reader.Close()
+	if _, err := reader.Next(t.Context()); err == nil || !strings.Contains(err.Error(), "could not decrypt") {
+		t.Fatalf("reading the swapped file returned %v", err)
+	}
+}
@@ -0,0 +1,201 @@
+package storage
+
+import (
+	"errors"
+	"slices"
+	"sync"
+)
+
+// OutputPolicy defines what happens once the output from a job gets to its limit
+type OutputPolicy uint8
+
+const (
+	// NoPolicy is used when the output didn't get to the limit
+	NoPolicy OutputPolicy = iota
+	// StopJob drops the output past the limit and stops the job
+	StopJob
+	// DropOldest keeps the job running, dropping the oldest output
+	DropOldest
+	// KeepHeadTail keeps the job running, keeping the start and the end of the output and dropping the middle
+	KeepHeadTail
+)
+
+// ErrOutputLimit is returned when the output gets to the limit, and the job has to be stopped
+var ErrOutputLimit = errors.New("the job output got to its limit")
+
+func (p OutputPolicy) String() string {
+	switch p {
+	case NoPolicy:
+		return "None"
+	case StopJob:
+		return "Stop"
+	case DropOldest:
+		return "Ring"
+	case KeepHeadTail:
+		return "HeadTail"
+	default:
+		return "Undefined"
+	}
+}
+
+// OutputBudget limits the output stored by all the running jobs together, on top of the limit of each job.
+// Every job takes the output it stores from the budget, and gives it back once the output is dropped, or once
+// the job ends, when the output kept is left to the retention policy. The job whose output gets past the
+// budget is stopped, whatever its policy, since dropping its own output wouldn't leave room for the others.
+type OutputBudget struct {
+	mu   sync.Mutex
+	max  int64
+	used int64
+}
+
+// NewOutputBudget returns a budget for max bytes of output, or nil for no limit when max is 0
+func NewOutputBudget(max int64) *OutputBudget {
+	if max == 0 {
+		return nil
+	}
+	return &OutputBudget{max: max}
+}
+
+// Used returns the output stored by the running jobs
+func (b *OutputBudget) Used() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.used
+}
+
+// take takes up to n bytes from the budget, and returns the number of bytes taken
+func (b *OutputBudget) take(n int64) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n = max(min(n, b.max-b.used), 0)
+	b.used += n
+	return n
+}
+
+// release gives n bytes back to the budget
+func (b *OutputBudget) release(n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.used -= n
+}
+
+// takeBudget takes the output appended to the log from the server budget, and returns the number of bytes
+// which can be stored
+func (c *CmdLog) takeBudget(n int64) int64 {
+	if c.budget == nil {
+		return n
+	}
+	n = c.budget.take(n)
+	c.taken += n
+	return n
+}
+
+// releaseBudget gives the output no longer kept back to the server budget
+func (c *CmdLog) releaseBudget(n int64) {
+	if c.budget == nil {
+		return
+	}
+	n = min(n, c.taken)
+	c.budget.release(n)
+	c.taken -= n
+}
+
+// segmentSize returns the size of the files for a log. When dropping output, it's dropped a file at a time,
+// so the files for logs with a limit, and the buffer, are at most a quarter of the limit: dropping files always
+// gets the output kept under the limit, while keeping most of the output allowed.
+func segmentSize(opts LogOptions) int {
+	if opts.MaxBytes == 0 || opts.Policy == StopJob {
+		return logFileSize
+	}
+	return max(min(logFileSize, int(opts.MaxBytes/4)), 1)
+}
+
+// Truncation returns the policy applied once the output got to its limit, or NoPolicy if it didn't,
+// and the number of bytes of output dropped, either from the log or before getting to it
+func (j *Job) Truncation() (OutputPolicy, int64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.log.truncation, j.log.dropped + j.log.refused
+}
+
+// Available returns the first offset, from offset on, where the output is available
+func (j *Job) Available(offset int64) int64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.log.available(offset)
+}
+
+// enforceLimit drops files from the log while the output kept is over the limit, for the DropOldest and
+// KeepHeadTail policies. KeepHeadTail never drops the files starting in the first half of the limit.
+func (c *CmdLog) enforceLimit() error {
+	if c.maxBytes == 0 || (c.policy != DropOldest && c.policy != KeepHeadTail) {
+		return nil
+	}
+	removed := false
+	for c.size-c.dropped > c.maxBytes {
+		i := 0
+		if c.policy == KeepHeadTail {
+			for i < len(c.segments) && c.segments[i].offset < c.maxBytes/2 {
+				i++
+			}
+		}
+		if i == len(c.segments) {
+			return nil
+		}
+		size := c.segments[i].size
+		if err := c.remove(i); err != nil {
+			return err
+		}
+		c.dropped += size
+		c.releaseBudget(size)
+		c.truncation = c.policy
+		removed = true
+	}
+	if removed {
+		c.pruneMarks()
+	}
+	return nil
+}
+
+// pruneMarks removes the marks for the output dropped
+func (c *CmdLog) pruneMarks() {
+	gaps := c.gaps()
+	c.marks = slices.DeleteFunc(c.marks, func(m mark) bool {
+		return slices.ContainsFunc(gaps, func(g gap) bool {
+			return m.offset >= g.start && m.offset < g.end
+		})
+	})
+}
+
+// gap is a range of output dropped from the log
+type gap struct {
+	start int64
+	end   int64
+}
+
+// gaps returns the ranges of output dropped from the log, in order
+func (c *CmdLog) gaps() []gap {
+	var gaps []gap
+	var end int64
+	for _, seg := range c.segments {
+		if seg.offset > end {
+			gaps = append(gaps, gap{end, seg.offset})
+		}
+		end = seg.offset + seg.size
+	}
+	if bufferStart := c.size - int64(len(*c.buffer)); bufferStart > end {
+		gaps = append(gaps, gap{end, bufferStart})
+	}
+	return gaps
+}
+
+// available returns the first offset, from offset on, where the output is available. It's offset
+// unless the output at offset was dropped.
+func (c *CmdLog) available(offset int64) int64 {
+	for _, g := range c.gaps() {
+		if offset >= g.start && offset < g.end {
+			return g.end
+		}
+	}
+	return offset
+}
@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// writeLines writes count lines of 1KB to the job stdout, numbered from 0, and returns the output written and
+// the first error returned
+func writeLines(t *testing.T, job *Job, count int) ([]byte, error) {
+	t.Helper()
+	var out []byte
+	var limitErr error
+	for i := range count {
+		line := []byte(fmt.Sprintf("%04d%s\n", i, bytes.Repeat([]byte("x"), 1019)))
+		out = append(out, line...)
+		if err := job.ProcessOutput(Stdout, line); err != nil && limitErr == nil {
+			limitErr = err
+		}
+	}
+	return out, limitErr
+}
+
+// readRanges reads the output of the job, returning the data and the number of bytes reported missing, in order
+func readRanges(t *testing.T, job *Job) ([]byte, []int64) {
+	t.Helper()
+	reader := job.NewReader(0, false)
+	defer reader.Close()
+	var data []byte
+	var missing []int64
+	for {
+		chunk, err := reader.Next(context.Background())
+		if errors.Is(err, io.EOF) {
+			return data, missing
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if chunk.Missing > 0 {
+			missing = append(missing, int64(len(data)), chunk.Missing)
+		}
+		data = append(data, chunk.Data...)
+	}
+}
+
+func TestOutputLimitPolicies(t *testing.T) {
+	const maxBytes = 16 * 1024
+	tests := []struct {
+		name   string
+		policy OutputPolicy
+		// head and tail are the number of bytes expected from the start and the end of the output
+		head   int
+		tail   int
+		err    error
+		status JobStatus
+	}{
+		{name: "stop", policy: StopJob, head: maxBytes, err: ErrOutputLimit, status: Stopped},
+		{name: "ring", policy: DropOldest, tail: maxBytes, status: Running},
+		{name: "head and tail", policy: KeepHeadTail, head: maxBytes / 2, tail: maxBytes / 2, status: Running},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job, err := NewJob(LogOptions{Store: NewMemoryStore(), MaxBytes: maxBytes, Policy: tt.policy})
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := writeLines(t, job, 64)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("writing the output returned %v, expected %v", err, tt.err)
+			}
+			if job.Status() != tt.status {
+				t.Fatalf("unexpected status %s", job.Status())
+			}
+			job.CloseOutput()
+
+			truncation, dropped := job.Truncation()
+			if truncation != tt.policy || dropped == 0 {
+				t.Fatalf("unexpected truncation %s with %d bytes dropped", truncation, dropped)
+			}
+			data, missing := readRanges(t, job)
+			if !bytes.Equal(data[:tt.head], out[:tt.head]) {
+				t.Fatal("the start of the output was not kept")
+			}
+			if !bytes.Equal(data[len(data)-tt.tail:], out[len(out)-tt.tail:]) {
+				t.Fatal("the end of the output was not kept")
+			}
+			if kept := int64(len(data)); kept < int64(tt.head+tt.tail) || kept+dropped != int64(len(out)) {
+				t.Fatalf("%d bytes kept and %d dropped from %d", kept, dropped, len(out))
+			}
+			// the output dropped from the middle is reported to the readers where it's missing
+			if tt.policy != StopJob && (len(missing) != 2 || missing[0] != int64(tt.head) || missing[1] != dropped) {
+				t.Fatalf("unexpected missing output: %v", missing)
+			}
+		})
+	}
+}
+
+func TestSmallOutputLimit(t *testing.T) {
+	// a limit smaller than a line, which is split over several segments
+	const maxBytes = 1000
+	for _, policy := range []OutputPolicy{DropOldest, KeepHeadTail} {
+		t.Run(policy.String(), func(t *testing.T) {
+			job, err := NewJob(LogOptions{Store: NewMemoryStore(), MaxBytes: maxBytes, Policy: policy})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out []byte
+			for i := range 8 {
+				line, err := writeLines(t, job, 1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				out = append(out, line...)
+				if stored := job.StoredSize(); stored > maxBytes {
+					t.Fatalf("%d bytes stored after %d lines", stored, i+1)
+				}
+			}
+			job.CloseOutput()
+
+			data, _ := readRanges(t, job)
+			_, dropped := job.Truncation()
+			if kept := int64(len(data)); kept > maxBytes || kept+dropped != int64(len(out)) {
+				t.Fatalf("%d bytes kept and %d dropped from %d", kept, dropped, len(out))
+			}
+			if tail := data[len(data)-maxBytes/4:]; !bytes.Equal(tail, out[len(out)-len(tail):]) {
+				t.Fatal("the end of the output was not kept")
+			}
+		})
+	}
+}
+
+func TestOutputBudget(t *testing.T) {
+	budget := NewOutputBudget(100 * 1024)
+	opts := LogOptions{Store: NewMemoryStore(), Budget: budget}
+	first, err := NewJob(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewJob(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := writeLines(t, first, 60); err != nil {
+		t.Fatal(err)
+	}
+	// the second job gets to the server limit, and is stopped even though it has no limit of its own
+	if _, err := writeLines(t, second, 60); !errors.Is(err, ErrOutputLimit) {
+		t.Fatalf("writing past the server limit returned %v", err)
+	}
+	if second.Status() != Stopped || second.StatusMessage() == "" {
+		t.Fatalf("unexpected status %s: %s", second.Status(), second.StatusMessage())
+	}
+	if truncation, dropped := second.Truncation(); truncation != StopJob || dropped != 20*1024 {
+		t.Fatalf("unexpected truncation %s with %d bytes dropped", truncation, dropped)
+	}
+	if second.Size() != 40*1024 || budget.Used() != 100*1024 {
+		t.Fatalf("%d bytes stored, and %d taken from the budget", second.Size(), budget.Used())
+	}
+
+	// the output of the jobs which ended is left to the retention policy
+	first.CloseOutput()
+	second.CloseOutput()
+	if budget.Used() != 0 {
+		t.Fatalf("%d bytes still taken from the budget", budget.Used())
+	}
+	third, err := NewJob(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writeLines(t, third, 100); err != nil {
+		t.Fatalf("the budget given back could not be used: %v", err)
+	}
+}
+
+func TestOutputBudgetReleasedByRing(t *testing.T) {
+	budget := NewOutputBudget(32 * 1024)
+	job, err := NewJob(LogOptions{Store: NewMemoryStore(), Budget: budget, MaxBytes: 16 * 1024, Policy: DropOldest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the output dropped by the ring gives its budget back, so the job keeps running
+	if _, err := writeLines(t, job, 256); err != nil {
+		t.Fatalf("the job with a ring got to the server limit: %v", err)
+	}
+	if used, size := budget.Used(), job.Size(); used >= 32*1024 || size != 256*1024 {
+		t.Fatalf("%d bytes taken from the budget for %d bytes of output", used, size)
+	}
+	job.CloseOutput()
+	if budget.Used() != 0 {
+		t.Fatalf("%d bytes still taken from the budget", budget.Used())
+	}
+}
@@ -0,0 +1,301 @@
+package storage
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"time"
+	"unicode/utf8"
+)
+
+// maxLineSize is the most output kept for a line still missing the new line. A longer line is returned in parts
+// of up to this size, like the partial lines flushed, so a command writing without new lines can't make the
+// reader keep all its output in memory.
+const maxLineSize = 64 * 1024 // 64KB
+
+// Line is a line of the command output, including its trailing new line. Offset is the position of its
+// first byte in the output and Next the position following its last byte. Since stdout and stderr are
+// stored interleaved, a line may be split by output from the other stream, which is between Offset and Next.
+// Number is the position of the line in the output, counting the lines in the order they start, and Time
+// is the time the start of the line was captured.
+// A line returned before it's complete, once the flush timeout passes or once it gets to maxLineSize, is
+// followed by the rest of the line with the same Number.
+// For output dropped from the log, a line with no data and the number of bytes dropped in Missing is returned.
+type Line struct {
+	Offset  int64
+	Next    int64
+	Number  int64
+	Data    []byte
+	Time    time.Time
+	Stream  Stream
+	Missing int64
+	start   int64
+}
+
+// Complete returns true if the line ends with a new line
+func (l Line) Complete() bool {
+	return len(l.Data) > 0 && l.Data[len(l.Data)-1] == '\n'
+}
+
+// LineReader reads the output from a job line by line, keeping a separate partial line for each stream,
+// so lines from stdout and stderr are never mixed. Lines are returned in the order they are completed,
+// and the lines without a trailing new line are returned once the end of the output is reached, or once
+// they wait for longer than flushAfter, when it's set. Lines started before the reader offset are skipped,
+// and so are the ends of the lines cut by output dropped from the log, whose start is returned as a partial line.
+type LineReader struct {
+	job        *Job
+	reader     *OutputReader
+	flushAfter time.Duration
+	next       int64
+	skip       [2]bool
+	pending    map[Stream]*pendingLine
+	ready      []Line
+}
+
+// pendingLine is a line waiting for the rest of its output, received at received
+type pendingLine struct {
+	Line
+	received time.Time
+}
+
+// cut returns the first n bytes of the partial line as a line of their own, keeping the rest of it pending
+// as received at now
+func (p *pendingLine) cut(n int, now time.Time) Line {
+	part := p.Line
+	part.Data = p.Data[:n:n]
+	part.Next = p.Next - int64(len(p.Data)-n)
+	p.Data = bytes.Clone(p.Data[n:])
+	p.Offset, p.received = part.Next, now
+	return part
+}
+
+// NewLineReader returns a line reader for the output starting at offset. follow works as in NewReader.
+// When flushAfter is set, partial lines are returned once they wait for that long.
+func (j *Job) NewLineReader(offset int64, follow bool, flushAfter time.Duration) (*LineReader, error) {
+	line, open, err := j.lineState(j.Available(offset))
+	if err != nil {
+		return nil, err
+	}
+	return &LineReader{
+		job:        j,
+		reader:     j.NewReader(offset, follow),
+		flushAfter: flushAfter,
+		next:       line,
+		skip:       open,
+		pending:    make(map[Stream]*pendingLine),
+	}, nil
+}
+
+// Next returns the next line, waiting for new output like OutputReader.Next. It returns
+// io.EOF once all the lines were returned.
+func (l *LineReader) Next(ctx context.Context) (Line, error) {
+	for len(l.ready) == 0 {
+		waitCtx, cancel := l.flushContext(ctx)
+		chunk, err := l.reader.Next(waitCtx)
+		cancel()
+		switch {
+		case err == io.EOF:
+			l.flushAll()
+			if len(l.ready) == 0 {
+				return Line{}, io.EOF
+			}
+		case errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil:
+			l.flushExpired(time.Now())
+		case err != nil:
+			return Line{}, err
+		case chunk.Missing > 0:
+			if err := l.skipMissing(chunk); err != nil {
+				return Line{}, err
+			}
+		default:
+			l.split(chunk)
+			l.flushExpired(time.Now())
+		}
+	}
+	line := l.ready[0]
+	l.ready = l.ready[1:]
+	return line, nil
+}
+
+// Resume returns the offset from where a new reader returns the lines not returned yet. Reading from it
+// may return again some of the lines already returned, which are interleaved with the ones not returned.
+func (l *LineReader) Resume() int64 {
+	offset := l.reader.offset
+	for _, line := range l.ready {
+		offset = min(offset, line.start)
+	}
+	for _, line := range l.pending {
+		offset = min(offset, line.start)
+	}
+	return offset
+}
+
+// Close releases the log file opened by the reader
+func (l *LineReader) Close() error {
+	return l.reader.Close()
+}
+
+// flushContext returns the context to wait for output, which is done once the oldest partial line
+// has to be flushed
+func (l *LineReader) flushContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	var oldest time.Time
+	for _, line := range l.pending {
+		if len(line.Data) > 0 && (oldest.IsZero() || line.received.Before(oldest)) {
+			oldest = line.received
+		}
+	}
+	if l.flushAfter == 0 || oldest.IsZero() {
+		return ctx, func() {}
+	}
+	return context.WithDeadline(ctx, oldest.Add(l.flushAfter))
+}
+
+// skipMissing returns the partial lines, followed by a line for the output missing, and starts counting
+// the lines again from the end of the output missing
+func (l *LineReader) skipMissing(chunk Chunk) error {
+	l.flushAll()
+	next := chunk.Offset + chunk.Missing
+	l.ready = append(l.ready, Line{
+		Offset:  chunk.Offset,
+		Next:    next,
+		Missing: chunk.Missing,
+		start:   chunk.Offset,
+	})
+	line, open, err := l.job.lineState(next)
+	if err != nil {
+		return err
+	}
+	l.next, l.skip = line, open
+	return nil
+}
+
+// split appends the chunk to the partial line from its stream, moving every line completed to ready
+func (l *LineReader) split(chunk Chunk) {
+	data := chunk.Data
+	offset := chunk.Offset
+	for len(data) > 0 {
+		n := len(data)
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			n = i + 1
+		}
+		l.appendPiece(chunk, offset, data[:n])
+		data = data[n:]
+		offset += int64(n)
+	}
+}
+
+// appendPiece appends a piece of a chunk, starting at offset, to the line from its stream. The piece
+// is either the end of the line, with the new line, or the rest of the chunk. A line getting to maxLineSize
+// is returned up to there, without splitting a rune, and the rest of it is kept pending.
+func (l *LineReader) appendPiece(chunk Chunk, offset int64, piece []byte) {
+	complete := piece[len(piece)-1] == '\n'
+	if l.skip[chunk.Stream] {
+		l.skip[chunk.Stream] = !complete
+		return
+	}
+	line, ok := l.pending[chunk.Stream]
+	if !ok {
+		l.next++
+		line = &pendingLine{Line: Line{Number: l.next, Stream: chunk.Stream, start: offset}}
+		l.pending[chunk.Stream] = line
+	}
+	if len(line.Data) == 0 {
+		line.Offset, line.Time, line.received = offset, chunk.Time, time.Now()
+	}
+	line.Data = append(line.Data, piece...)
+	line.Next = offset + int64(len(piece))
+	if complete {
+		delete(l.pending, chunk.Stream)
+		l.ready = append(l.ready, line.Line)
+		return
+	}
+	for len(line.Data) >= maxLineSize {
+		n := maxLineSize - incompleteRune(line.Data[:maxLineSize])
+		l.ready = append(l.ready, line.cut(n, time.Now()))
+	}
+}
+
+// flushExpired moves the partial lines waiting for longer than flushAfter to ready. A rune split by
+// the end of the output received so far is kept for the rest of the line.
+func (l *LineReader) flushExpired(now time.Time) {
+	if l.flushAfter == 0 {
+		return
+	}
+	start := len(l.ready)
+	for _, line := range l.pending {
+		if len(line.Data) == 0 || now.Sub(line.received) < l.flushAfter {
+			continue
+		}
+		n := len(line.Data) - incompleteRune(line.Data)
+		if n == 0 {
+			continue
+		}
+		l.ready = append(l.ready, line.cut(n, now))
+	}
+	sortLines(l.ready[start:])
+}
+
+// flushAll moves all the partial lines to ready, once the end of the output is reached
+func (l *LineReader) flushAll() {
+	start := len(l.ready)
+	for stream, line := range l.pending {
+		if len(line.Data) > 0 {
+			l.ready = append(l.ready, line.Line)
+		}
+		delete(l.pending, stream)
+	}
+	sortLines(l.ready[start:])
+}
+
+// sortLines sorts the lines in the order they start
+func sortLines(lines []Line) {
+	slices.SortFunc(lines, func(a, b Line) int {
+		return cmp.Compare(a.Offset, b.Offset)
+	})
+}
+
+// incompleteRune returns the number of bytes at the end of data which start a rune without finishing it
+func incompleteRune(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if utf8.FullRune(data[i:]) {
+				return 0
+			}
+			return len(data) - i
+		}
+	}
+	return 0
+}
+
+// lineState returns the number of lines started before offset, and for each stream, if it has a line
+// started before offset still missing the new line
+func (j *Job) lineState(offset int64) (int64, [2]bool, error) {
+	j.mu.Lock()
+	m, _ := j.log.markAt(offset)
+	j.mu.Unlock()
+	if offset <= m.offset {
+		return m.line, m.open, nil
+	}
+
+	// counts the lines in the output from the mark to the offset, which was all written to the mark stream
+	reader := &OutputReader{job: j, offset: m.offset, end: offset}
+	defer reader.Close()
+	line, open := m.line, m.open
+	for {
+		chunk, err := reader.Next(context.Background())
+		if err == io.EOF {
+			return line, open, nil
+		}
+		if err != nil {
+			return 0, open, err
+		}
+		if len(chunk.Data) == 0 {
+			continue
+		}
+		line += lineStarts(chunk.Data, open[m.stream])
+		open[m.stream] = chunk.Data[len(chunk.Data)-1] != '\n'
+	}
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestLineReaderLongLine(t *testing.T) {
+	job, err := NewJob(LogOptions{Store: NewMemoryStore()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a line much longer than the limit, written without new lines, with a rune crossing the limit
+	long := strings.Repeat("a", maxLineSize-1) + "€" + strings.Repeat("b", 3*maxLineSize)
+	for _, out := range []string{"first\n", long[:maxLineSize/2], long[maxLineSize/2:], "\nlast\n"} {
+		if err := job.ProcessOutput(Stdout, []byte(out)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reader, err := job.NewLineReader(0, true, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	var lines []Line
+	for len(lines) == 0 || lines[len(lines)-1].Number < 3 {
+		line, err := reader.Next(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+
+	if string(lines[0].Data) != "first\n" || lines[0].Number != 1 {
+		t.Fatalf("unexpected first line: %d %q", lines[0].Number, lines[0].Data)
+	}
+	parts := lines[1 : len(lines)-1]
+	if len(parts) < 4 {
+		t.Fatalf("the long line was returned in %d parts", len(parts))
+	}
+	var data []byte
+	for i, part := range parts {
+		if part.Number != 2 || len(part.Data) > maxLineSize || !utf8.Valid(part.Data) {
+			t.Fatalf("unexpected part %d: line %d with %d bytes", i, part.Number, len(part.Data))
+		}
+		if part.Complete() != (i == len(parts)-1) {
+			t.Fatalf("part %d of %d complete: %t", i, len(parts), part.Complete())
+		}
+		if part.Offset != int64(len("first\n")+len(data)) {
+			t.Fatalf("part %d at offset %d", i, part.Offset)
+		}
+		data = append(data, part.Data...)
+	}
+	if !bytes.Equal(data, []byte(long+"\n")) {
+		t.Fatalf("the parts returned have %d bytes, expected %d", len(data), len(long)+1)
+	}
+	if last := lines[len(lines)-1]; string(last.Data) != "last\n" || last.Number != 3 {
+		t.Fatalf("unexpected last line: %d %q", last.Number, last.Data)
+	}
+
+	job.CloseOutput()
+	if _, err := reader.Next(context.Background()); !errors.Is(err, io.EOF) {
+		t.Fatalf("reading past the end returned %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"log/slog"
+	"sync"
 
 	"github.com/google/uuid"
 )
@@ -11,6 +12,7 @@ type Permission uint
 const (
 	Read Permission = iota
 	Write
+	Admin
 )
 
 type User struct {
@@ -20,6 +22,7 @@ type User struct {
 }
 
 type MemStorage struct {
+	mu    sync.RWMutex
 	users map[string]User
 	jobs  map[string]*Job
 }
@@ -34,6 +37,8 @@ func NewMemStorage() JobStorage {
 }
 
 func (m *MemStorage) GetUserId(email string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	for _, usr := range m.users {
 		if usr.email == email {
 			return usr.id, true
@@ -44,40 +49,98 @@ func (m *MemStorage) GetUserId(email string) (string, bool) {
 
 func (m *MemStorage) Authorized(userId string, op Operation) bool {
 	slog.Debug("authorizing", slog.String("userid", userId), slog.Any("operation", op))
+	m.mu.RLock()
 	usr, ok := m.users[userId]
+	m.mu.RUnlock()
 	if !ok {
 		return false
 	}
 	slog.Debug("authorizing", slog.Any("user role", usr.role))
-	if (op == Run || op == Stop) && usr.role == Read {
+	return usr.allowed(op)
+}
+
+// allowed returns true if the role of the user allows the operation
+func (u User) allowed(op Operation) bool {
+	switch u.role {
+	case Read:
+		return !writeOperation(op) && !adminOperation(op)
+	case Write:
+		return !adminOperation(op)
+	default:
+		return true
+	}
+}
+
+// writeOperation returns true for operations which are only allowed to users with the Write role.
+// Transferring files is restricted the same way as running commands, since both give access to the server.
+func writeOperation(op Operation) bool {
+	switch op {
+	case Run, Stop, Upload, Download:
+		return true
+	default:
 		return false
 	}
-	return true
+}
+
+// adminOperation returns true for operations which are only allowed to users with the Admin role
+func adminOperation(op Operation) bool {
+	return op == Purge || op == ManageSecrets || op == ListAll
 }
 
 func (m *MemStorage) SaveJob(jobId string, job *Job) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.jobs[jobId] = job
 }
 
 func (m *MemStorage) GetJob(jobId string) (*Job, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	job, ok := m.jobs[jobId]
 	return job, ok
 }
 
+func (m *MemStorage) ListJobs() []*Job {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	jobs := make([]*Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+func (m *MemStorage) DeleteJob(jobId string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.jobs, jobId)
+}
+
 // init is a temporary method to populate the database with test data
 // TODO: remove this and add methods to insert/remove users and test data
 func (m *MemStorage) init() {
-	userId := uuid.NewString()
-	m.users[userId] = User{
-		id:    userId,
-		email: "marcel+client@email.com",
-		role:  Write,
+	for _, usr := range defaultUsers() {
+		m.users[usr.id] = usr
 	}
+}
 
-	userId2 := uuid.NewString()
-	m.users[userId2] = User{
-		id:    userId2,
-		email: "marcel+client2@email.com",
-		role:  Read,
+// defaultUsers returns the test users the storages start with
+func defaultUsers() []User {
+	return []User{
+		{
+			id:    uuid.NewString(),
+			email: "marcel+client@email.com",
+			role:  Write,
+		},
+		{
+			id:    uuid.NewString(),
+			email: "marcel+client2@email.com",
+			role:  Read,
+		},
+		{
+			id:    uuid.NewString(),
+			email: "marcel+admin@email.com",
+			role:  Admin,
+		},
 	}
 }
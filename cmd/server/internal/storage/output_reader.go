@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+const readChunkSize int64 = 32 * 1024 // 32KB
+
+// OutputReader reads the output from a job keeping its own position in the log, so each reader proceeds
+// at its own pace, without slowing down the command or the other readers. A reader lagging behind catches up
+// by reading the output already flushed to disk from the log files, and the rest from the log buffer.
+// segmentIndex is the index of the file open in segment.
+type OutputReader struct {
+	job          *Job
+	offset       int64
+	end          int64
+	segment      io.ReadCloser
+	segmentIndex int
+	segmentPos   int64
+}
+
+// Next returns the next chunk of output from the reader position. When the reader got to the end of the
+// output available, it waits for new output or for ctx to be done. It returns io.EOF once the end of the
+// output is reached. A chunk never spans output captured at different times or from different streams.
+// When the output at the reader position was dropped, it returns a chunk with the number of bytes missing.
+func (r *OutputReader) Next(ctx context.Context) (Chunk, error) {
+	for {
+		if r.end >= 0 && r.offset >= r.end {
+			return Chunk{}, io.EOF
+		}
+
+		r.job.mu.Lock()
+		log := r.job.log
+		if log.purged {
+			r.job.mu.Unlock()
+			return Chunk{}, ErrOutputPurged
+		}
+		m, markEnd := log.markAt(r.offset)
+		if r.end >= 0 {
+			markEnd = min(markEnd, r.end)
+		}
+		if available := log.available(r.offset); available > r.offset {
+			r.job.mu.Unlock()
+			chunk := Chunk{Offset: r.offset, Missing: available - r.offset}
+			if r.end >= 0 {
+				chunk.Missing = min(chunk.Missing, r.end-r.offset)
+			}
+			r.offset += chunk.Missing
+			return chunk, nil
+		}
+		var segment *segment
+		for _, seg := range log.segments {
+			if r.offset >= seg.offset && r.offset < seg.offset+seg.size {
+				segment = &seg
+				break
+			}
+		}
+		if segment != nil {
+			r.job.mu.Unlock()
+			chunk, err := r.readSegment(*segment, markEnd)
+			if errors.Is(err, fs.ErrNotExist) && r.job.dropped(segment.index) {
+				// the file was dropped since the log was checked
+				continue
+			}
+			chunk.Time, chunk.Stream = time.Unix(0, m.time), m.stream
+			return chunk, err
+		}
+
+		start := log.size - int64(len(*log.buffer))
+		if r.offset < log.size {
+			buffer := *log.buffer
+			from := r.offset - start
+			to := min(int64(len(buffer)), from+readChunkSize, markEnd-start)
+			// the data is copied, since the buffer is reused once flushed to disk
+			chunk := Chunk{
+				Offset: r.offset,
+				Data:   bytes.Clone(buffer[from:to]),
+				Time:   time.Unix(0, m.time),
+				Stream: m.stream,
+			}
+			r.job.mu.Unlock()
+			r.offset += int64(len(chunk.Data))
+			return chunk, nil
+		}
+
+		if log.closed {
+			r.job.mu.Unlock()
+			return Chunk{}, io.EOF
+		}
+		changed := log.changed
+		r.job.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return Chunk{}, ctx.Err()
+		}
+	}
+}
+
+// dropped returns true if the file with the index is no longer in the log
+func (j *Job) dropped(index int) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return !slices.ContainsFunc(j.log.segments, func(seg segment) bool {
+		return seg.index == index
+	})
+}
+
+// Close releases the log file opened by the reader
+func (r *OutputReader) Close() error {
+	if r.segment == nil {
+		return nil
+	}
+	err := r.segment.Close()
+	r.segment = nil
+	return err
+}
+
+// readSegment reads the next chunk from the log file for the segment, up to the offset end.
+// The file is kept open between calls, and since compressed files can only be read sequentially, it's only
+// reopened when the reader moves to another file or to a position other than the one following the last read.
+func (r *OutputReader) readSegment(seg segment, end int64) (Chunk, error) {
+	pos := r.offset - seg.offset
+	if r.segment == nil || r.segmentIndex != seg.index || r.segmentPos != pos {
+		if err := r.Close(); err != nil {
+			return Chunk{}, err
+		}
+		rc, err := r.job.log.openSegment(seg)
+		if err != nil {
+			return Chunk{}, err
+		}
+		r.segment, r.segmentIndex = rc, seg.index
+		if _, err := io.CopyN(io.Discard, rc, pos); err != nil {
+			if truncated(err) {
+				return r.missingSegment(seg, end), nil
+			}
+			return Chunk{}, err
+		}
+		r.segmentPos = pos
+	}
+
+	n := min(readChunkSize, seg.size-pos, end-r.offset)
+	data := make([]byte, n)
+	read, err := io.ReadFull(r.segment, data)
+	if read == 0 && truncated(err) {
+		return r.missingSegment(seg, end), nil
+	}
+	// the data read from a file shorter than expected is returned, and the rest is reported as missing
+	// on the next read
+	if err != nil && !truncated(err) {
+		return Chunk{}, err
+	}
+	chunk := Chunk{
+		Offset: r.offset,
+		Data:   data[:read],
+	}
+	r.offset += int64(read)
+	r.segmentPos += int64(read)
+	return chunk, nil
+}
+
+// truncated returns true if the error is from a file which ended before its expected size
+func truncated(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// missingSegment returns a chunk with the output missing from the file for the segment, which is shorter than
+// expected, from the reader position up to the end of the segment or to the offset end
+func (r *OutputReader) missingSegment(seg segment, end int64) Chunk {
+	slog.Warn("output file is shorter than expected", slog.String("jobid", r.job.log.id), slog.Int("segment", seg.index))
+	r.Close()
+	chunk := Chunk{Offset: r.offset, Missing: min(seg.offset+seg.size, end) - r.offset}
+	r.offset += chunk.Missing
+	return chunk
+}
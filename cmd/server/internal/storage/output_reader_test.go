@@ -0,0 +1,248 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOutputReaderTruncatedSegment(t *testing.T) {
+	store := NewMemoryStore()
+	job, err := NewJob(LogOptions{Store: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := bytes.Repeat([]byte("0123456789abcdef"), 64)
+	var out []byte
+	for range 2 * logFileSize / len(line) {
+		if err := job.ProcessOutput(Stdout, line); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, line...)
+	}
+	job.CloseOutput()
+	waitStored(t, job)
+	size := job.log.segments[0].size
+
+	// the first file lost its end, like after a crash while it was written
+	if err := store.Put(job.Id.String(), "0.log", out[:100]); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := job.NewReader(0, false)
+	defer reader.Close()
+	var data []byte
+	var missing int64
+	for {
+		chunk, err := reader.Next(context.Background())
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("error reading the output: %v", err)
+		}
+		if chunk.Offset != int64(len(data))+missing {
+			t.Fatalf("chunk at offset %d, expected %d", chunk.Offset, int64(len(data))+missing)
+		}
+		if chunk.Missing > 0 && len(data) != 100 {
+			t.Fatalf("%d bytes missing after %d bytes of output", chunk.Missing, len(data))
+		}
+		data = append(data, chunk.Data...)
+		missing += chunk.Missing
+	}
+
+	if missing != size-100 {
+		t.Fatalf("%d bytes missing, expected %d", missing, size-100)
+	}
+	expected := append(bytes.Clone(out[:100]), out[size:]...)
+	if !bytes.Equal(data, expected) {
+		t.Fatalf("unexpected output: %d bytes, expected %d", len(data), len(expected))
+	}
+}
+
+// readAll reads the chunks from the reader until the end of the output
+func readAll(t *testing.T, reader *OutputReader) []byte {
+	t.Helper()
+	var data []byte
+	for {
+		chunk, err := reader.Next(context.Background())
+		if errors.Is(err, io.EOF) {
+			return data
+		}
+		if err != nil {
+			t.Fatalf("error reading the output: %v", err)
+		}
+		data = append(data, chunk.Data...)
+	}
+}
+
+func TestOutputReaderOffsets(t *testing.T) {
+	job, err := NewJob(LogOptions{Store: NewMemoryStore()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the output is spread over several files, and the buffer
+	job.log.segmentSize = 64
+	var out []byte
+	for i := range 50 {
+		line := fmt.Appendf(nil, "line %03d\n", i)
+		if err := job.ProcessOutput(Stream(i%2), line); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, line...)
+	}
+	waitStored(t, job)
+	if len(job.log.segments) < 2 || len(*job.log.buffer) == 0 {
+		t.Fatalf("the output is in %d files and %d bytes of buffer", len(job.log.segments), len(*job.log.buffer))
+	}
+	seg := job.log.segments[1]
+
+	tests := []struct {
+		name   string
+		offset int64
+	}{
+		{name: "start", offset: 0},
+		{name: "inside the first file", offset: 5},
+		{name: "start of a file", offset: seg.offset},
+		{name: "end of a file", offset: seg.offset + seg.size - 1},
+		{name: "buffer", offset: int64(len(out)) - 3},
+		{name: "end", offset: int64(len(out))},
+		{name: "past the end", offset: int64(len(out)) + 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := job.NewReader(tt.offset, false)
+			defer reader.Close()
+			data := readAll(t, reader)
+			if expected := out[min(tt.offset, int64(len(out))):]; !bytes.Equal(data, expected) {
+				t.Fatalf("read %q from offset %d, expected %q", data, tt.offset, expected)
+			}
+		})
+	}
+}
+
+func TestOutputReaderFollow(t *testing.T) {
+	job, err := NewJob(LogOptions{Store: NewMemoryStore()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := job.ProcessOutput(Stdout, []byte("first\n")); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := job.NewReader(0, false)
+	defer snapshot.Close()
+	follow := job.NewReader(0, true)
+	defer follow.Close()
+	if chunk, err := follow.Next(context.Background()); err != nil || string(chunk.Data) != "first\n" {
+		t.Fatalf("unexpected chunk: %q, %v", chunk.Data, err)
+	}
+
+	// the follow reader waits for new output
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := follow.Next(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("reading past the end returned %v", err)
+	}
+	next := make(chan Chunk)
+	go func() {
+		chunk, _ := follow.Next(context.Background())
+		next <- chunk
+	}()
+	if err := job.ProcessOutput(Stderr, []byte("second\n")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case chunk := <-next:
+		if string(chunk.Data) != "second\n" || chunk.Stream != Stderr || chunk.Offset != 6 {
+			t.Fatalf("unexpected chunk: %+v", chunk)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the new output")
+	}
+
+	// the snapshot stops at the output available when it was created
+	if data := readAll(t, snapshot); string(data) != "first\n" {
+		t.Fatalf("unexpected snapshot: %q", data)
+	}
+	job.CloseOutput()
+	if _, err := follow.Next(context.Background()); !errors.Is(err, io.EOF) {
+		t.Fatalf("reading the closed output returned %v", err)
+	}
+}
+
+func TestTailOffset(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		lines    int64
+		expected string
+	}{
+		{name: "last line", output: "a\nb\nc\n", lines: 1, expected: "c\n"},
+		{name: "last lines", output: "a\nb\nc\n", lines: 2, expected: "b\nc\n"},
+		{name: "more lines than the output", output: "a\nb\nc\n", lines: 10, expected: "a\nb\nc\n"},
+		{name: "no lines", output: "a\nb\nc\n", lines: 0, expected: ""},
+		{name: "last line without a new line", output: "a\nb\nc", lines: 1, expected: "c"},
+		{name: "lines across files", output: strings.Repeat("0123456789abcdef\n", 20), lines: 12,
+			expected: strings.Repeat("0123456789abcdef\n", 12)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job, err := NewJob(LogOptions{Store: NewMemoryStore()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			job.log.segmentSize = 64
+			for line := range strings.Lines(tt.output) {
+				if err := job.ProcessOutput(Stdout, []byte(line)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			job.CloseOutput()
+			offset, err := job.TailOffset(tt.lines)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reader := job.NewReader(offset, false)
+			defer reader.Close()
+			if data := readAll(t, reader); string(data) != tt.expected {
+				t.Fatalf("the last %d lines are %q, expected %q", tt.lines, data, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSlowReaderDoesNotBlockOutput(t *testing.T) {
+	job, err := NewJob(LogOptions{Store: NewMemoryStore()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the reader is created before the output, and doesn't read anything while it's written
+	slow := job.NewReader(0, true)
+	defer slow.Close()
+
+	written := make(chan []byte)
+	go func() {
+		out, err := writeLines(t, job, 4*1024)
+		if err != nil {
+			t.Error(err)
+		}
+		job.CloseOutput()
+		written <- out
+	}()
+	var out []byte
+	select {
+	case out = <-written:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the output was blocked by the reader")
+	}
+
+	// the reader catches up from the files once it reads
+	if data := readAll(t, slow); !bytes.Equal(data, out) {
+		t.Fatalf("the slow reader read %d bytes, expected %d", len(data), len(out))
+	}
+}
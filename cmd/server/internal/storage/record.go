@@ -0,0 +1,416 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mhsantos/rlcp/cmd/server/internal/webhook"
+)
+
+// marksObject is the object in the store with the marks of a finished job. While the job runs, the marks of
+// each segment stored are kept in an object next to it, named by segmentMarks, so the output of a job
+// interrupted by a restart keeps its times and streams. Those objects are removed once the job finishes.
+const marksObject = "marks"
+
+// segmentMarks returns the name of the object with the marks of the segment, while the job is running
+func segmentMarks(seg segment) string {
+	return fmt.Sprintf("%d.marks", seg.index)
+}
+
+// JobRecord is the job as persisted by a durable storage. The marks of the output are too many to keep in the
+// record, so they are stored along with the output, once the job finishes.
+type JobRecord struct {
+	Id            string             `json:"id"`
+	User          string             `json:"user"`
+	Status        JobStatus          `json:"status"`
+	StatusMessage string             `json:"status_message,omitempty"`
+	Command       string             `json:"command,omitempty"`
+	Args          []string           `json:"args,omitempty"`
+	Interpreter   string             `json:"interpreter,omitempty"`
+	Description   string             `json:"description,omitempty"`
+	Name          string             `json:"name,omitempty"`
+	Labels        map[string]string  `json:"labels,omitempty"`
+	Host          string             `json:"host"`
+	SubmitTime    time.Time          `json:"submit_time"`
+	StartTime     time.Time          `json:"start_time"`
+	Pid           int                `json:"pid,omitempty"`
+	ProcessStart  uint64             `json:"process_start,omitempty"`
+	Workspace     string             `json:"workspace"`
+	ArtifactGlobs []string           `json:"artifact_globs,omitempty"`
+	Artifacts     []string           `json:"artifacts,omitempty"`
+	Webhooks      []string           `json:"webhooks,omitempty"`
+	Deliveries    []webhook.Delivery `json:"deliveries,omitempty"`
+	Finished      bool               `json:"finished"`
+	EndTime       time.Time          `json:"end_time"`
+	ExitCode      *int               `json:"exit_code,omitempty"`
+	Log           LogRecord          `json:"log"`
+}
+
+// LogRecord has the state of the output of a job, except for its marks
+type LogRecord struct {
+	Compress   bool            `json:"compress"`
+	Segments   []SegmentRecord `json:"segments,omitempty"`
+	NextIndex  int             `json:"next_index"`
+	Lines      int64           `json:"lines"`
+	Open       [2]bool         `json:"open"`
+	Size       int64           `json:"size"`
+	Stored     int64           `json:"stored"`
+	Dropped    int64           `json:"dropped"`
+	Refused    int64           `json:"refused,omitempty"`
+	Truncation OutputPolicy    `json:"truncation"`
+	Redactions int64           `json:"redactions"`
+	WrappedKey []byte          `json:"wrapped_key,omitempty"`
+	Purged     bool            `json:"purged"`
+}
+
+// SegmentRecord is a segment of the output in the store
+type SegmentRecord struct {
+	Index      int   `json:"index"`
+	Offset     int64 `json:"offset"`
+	Size       int64 `json:"size"`
+	Stored     int64 `json:"stored"`
+	Compressed bool  `json:"compressed"`
+	Encrypted  bool  `json:"encrypted"`
+}
+
+// Record returns the state of the job to be persisted
+func (j *Job) Record() JobRecord {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	finished := j.persisted
+	l := j.log
+	rec := JobRecord{
+		Id:            j.Id.String(),
+		User:          j.User,
+		Status:        j.status,
+		StatusMessage: j.statusMessage,
+		Command:       j.Command,
+		Args:          j.Args,
+		Interpreter:   j.Interpreter,
+		Description:   j.Description,
+		Name:          j.Name,
+		Labels:        j.Labels,
+		Host:          j.Host,
+		SubmitTime:    j.SubmitTime,
+		StartTime:     j.StartTime,
+		Pid:           j.pid,
+		ProcessStart:  j.processStart,
+		Workspace:     j.Workspace,
+		ArtifactGlobs: j.ArtifactGlobs,
+		Artifacts:     j.artifacts,
+		Webhooks:      j.Webhooks,
+		Deliveries:    slices.Clone(j.deliveries),
+		Finished:      finished,
+		EndTime:       j.endTime,
+		Log: LogRecord{
+			Compress:   l.compress,
+			NextIndex:  l.nextIndex,
+			Lines:      l.lines,
+			Open:       l.open,
+			Size:       l.size,
+			Stored:     l.stored,
+			Dropped:    l.dropped,
+			Refused:    l.refused,
+			Truncation: l.truncation,
+			Redactions: l.redactions,
+			WrappedKey: l.wrappedKey,
+			Purged:     l.purged,
+		},
+	}
+	if finished {
+		exitCode := j.exitCode
+		rec.ExitCode = &exitCode
+	}
+	for _, seg := range l.segments {
+		// the segments not stored yet are left out, like the output in the buffer
+		if seg.data != nil {
+			rec.Log.Stored -= seg.stored
+			continue
+		}
+		rec.Log.Segments = append(rec.Log.Segments, SegmentRecord{
+			Index:      seg.index,
+			Offset:     seg.offset,
+			Size:       seg.size,
+			Stored:     seg.stored,
+			Compressed: seg.compressed,
+			Encrypted:  seg.encrypted,
+		})
+	}
+	return rec
+}
+
+// RestoreJob returns a job from its record, reading the marks of a finished job from the store in opts.
+// A job which didn't finish was interrupted when the server stopped: it's restored as running, but with its
+// output closed and only partially restored, from the segments in the record, until it's marked as exited.
+func RestoreJob(rec JobRecord, opts LogOptions) (*Job, error) {
+	id, err := uuid.Parse(rec.Id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid job id %q: %w", rec.Id, err)
+	}
+	buffer := make([]byte, 0)
+	job := &Job{
+		Id:            id,
+		User:          rec.User,
+		status:        rec.Status,
+		statusMessage: rec.StatusMessage,
+		Command:       rec.Command,
+		Args:          rec.Args,
+		Interpreter:   rec.Interpreter,
+		Description:   rec.Description,
+		Name:          rec.Name,
+		Labels:        rec.Labels,
+		Host:          rec.Host,
+		SubmitTime:    rec.SubmitTime,
+		StartTime:     rec.StartTime,
+		pid:           rec.Pid,
+		processStart:  rec.ProcessStart,
+		Workspace:     rec.Workspace,
+		ArtifactGlobs: rec.ArtifactGlobs,
+		artifacts:     rec.Artifacts,
+		Webhooks:      rec.Webhooks,
+		deliveries:    rec.Deliveries,
+		endTime:       rec.EndTime,
+		exitCode:      UnknownExitCode,
+		log: &CmdLog{
+			id:         rec.Id,
+			store:      opts.Store,
+			compress:   rec.Log.Compress,
+			nextIndex:  rec.Log.NextIndex,
+			lines:      rec.Log.Lines,
+			open:       rec.Log.Open,
+			size:       rec.Log.Size,
+			stored:     rec.Log.Stored,
+			dropped:    rec.Log.Dropped,
+			refused:    rec.Log.Refused,
+			truncation: rec.Log.Truncation,
+			redactions: rec.Log.Redactions,
+			wrappedKey: rec.Log.WrappedKey,
+			purged:     rec.Log.Purged,
+			buffer:     &buffer,
+			changed:    make(chan struct{}),
+			closed:     true,
+		},
+		exited: make(chan struct{}),
+	}
+	l := job.log
+	for _, seg := range rec.Log.Segments {
+		l.segments = append(l.segments, segment{
+			index:      seg.Index,
+			offset:     seg.Offset,
+			size:       seg.Size,
+			stored:     seg.Stored,
+			compressed: seg.Compressed,
+			encrypted:  seg.Encrypted,
+		})
+	}
+	if len(l.wrappedKey) > 0 {
+		if opts.MasterKey == nil {
+			return nil, errors.New("the output is encrypted, but no master key was informed")
+		}
+		// the key stored along with the output is the one rewrapped when the master key is rotated. It's written
+		// with the first output file, so without it there's no output to decrypt.
+		wrapped, err := l.readKey()
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("could not read the output key: %w", err)
+		}
+		if err == nil {
+			l.wrappedKey = wrapped
+			key, err := opts.MasterKey.unwrap(l.wrappedKey, rec.Id)
+			if err != nil {
+				return nil, fmt.Errorf("could not unwrap the output key: %w", err)
+			}
+			if l.dataKey, err = newAEAD(key); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if !rec.Finished {
+		// the output past the last segment stored was lost, and the marks of the segments are read back, so
+		// they are stored along with the output once the job is marked as exited
+		if n := len(l.segments); n > 0 {
+			l.size = l.segments[n-1].offset + l.segments[n-1].size
+		} else {
+			l.size = 0
+		}
+		for _, seg := range l.segments {
+			marks, err := l.readMarks(segmentMarks(seg))
+			if err != nil {
+				slog.Warn("could not read the marks of the output", slog.String("jobid", rec.Id),
+					slog.Int("segment", seg.index), slog.Any("error", err))
+				continue
+			}
+			l.marks = append(l.marks, marks...)
+		}
+		return job, nil
+	}
+	if rec.ExitCode != nil {
+		job.exitCode = *rec.ExitCode
+	}
+	job.persisted = true
+	close(job.exited)
+	if !l.purged && l.size > 0 {
+		if l.marks, err = l.readMarks(marksObject); err != nil {
+			return nil, fmt.Errorf("could not read the output marks: %w", err)
+		}
+	}
+	return job, nil
+}
+
+// Persist writes the output left in the buffer to the store, as the last segment, waits for the uploader to
+// store all the segments and writes the marks, so the job can be restored once it's finished
+func (j *Job) Persist() error {
+	j.mu.Lock()
+	j.persisted = true
+	if j.log.purged || j.log.size == 0 {
+		j.mu.Unlock()
+		return nil
+	}
+	if len(*j.log.buffer) > 0 {
+		j.log.seal()
+		j.startUpload()
+	}
+	j.mu.Unlock()
+
+	if err := j.storeSegments(); err != nil {
+		return err
+	}
+	j.mu.Lock()
+	marks, segments := slices.Clone(j.log.marks), j.log.segments
+	j.mu.Unlock()
+	return j.log.persistMarks(marks, segments)
+}
+
+// Persisted returns true once the output of the finished job was stored, and the job can be written as finished
+func (j *Job) Persisted() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.persisted
+}
+
+// persistMarks writes the marks of the finished job to the store, and removes the marks stored for each
+// segment while it was running. It's called without the lock, with the final marks and segments.
+func (c *CmdLog) persistMarks(marks []mark, segments []segment) error {
+	if err := c.putMarks(marksObject, marks); err != nil {
+		return err
+	}
+	for _, seg := range segments {
+		if err := c.store.Delete(c.id, segmentMarks(seg)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// marksIn returns a copy of the marks of the segment, which starts with a mark of its own
+func (c *CmdLog) marksIn(seg segment) []mark {
+	i := sort.Search(len(c.marks), func(i int) bool {
+		return c.marks[i].offset >= seg.offset
+	})
+	j := sort.Search(len(c.marks), func(i int) bool {
+		return c.marks[i].offset >= seg.offset+seg.size
+	})
+	return slices.Clone(c.marks[i:j])
+}
+
+// putMarks writes the marks to the object with the name, encoded as deltas from the previous mark, compressed,
+// and encrypted with the data key, when set
+func (c *CmdLog) putMarks(name string, marks []mark) error {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	var prev mark
+	var scratch [3 * binary.MaxVarintLen64]byte
+	for _, m := range marks {
+		n := binary.PutVarint(scratch[:], m.offset-prev.offset)
+		n += binary.PutVarint(scratch[n:], m.time-prev.time)
+		n += binary.PutVarint(scratch[n:], m.line-prev.line)
+		flags := byte(m.stream)
+		if m.open[Stdout] {
+			flags |= 2
+		}
+		if m.open[Stderr] {
+			flags |= 4
+		}
+		if _, err := zw.Write(append(scratch[:n], flags)); err != nil {
+			return err
+		}
+		prev = m
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	data := buf.Bytes()
+	if c.dataKey != nil {
+		var err error
+		if data, err = seal(c.dataKey, data, []byte(c.id+"/"+name)); err != nil {
+			return err
+		}
+	}
+	return c.store.Put(c.id, name, data)
+}
+
+// readMarks reads the marks written by putMarks to the object with the name
+func (c *CmdLog) readMarks(name string) ([]mark, error) {
+	rc, err := c.store.Get(c.id, name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return nil, err
+	}
+	if c.dataKey != nil {
+		if data, err = open(c.dataKey, data, []byte(c.id+"/"+name)); err != nil {
+			return nil, err
+		}
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(zr)
+	var marks []mark
+	var prev mark
+	for {
+		offset, err := binary.ReadVarint(r)
+		if err == io.EOF {
+			return marks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		t, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, err
+		}
+		line, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, err
+		}
+		flags, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		m := mark{
+			offset: prev.offset + offset,
+			time:   prev.time + t,
+			line:   prev.line + line,
+			stream: Stream(flags & 1),
+			open:   [2]bool{flags&2 != 0, flags&4 != 0},
+		}
+		marks = append(marks, m)
+		prev = m
+	}
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	// redactionMask replaces the output matching a redaction
+	redactionMask = "[REDACTED]"
+	// redactWindow is the most output held back from a line still missing the new line, waiting for the
+	// rest of a match
+	redactWindow int = 4 * 1024 // 4KB
+)
+
+// Redactor masks secrets in the output from the jobs before it's stored. It matches regular expressions
+// and exact secret values, which never span multiple lines.
+type Redactor struct {
+	re       *regexp.Regexp
+	patterns []string
+	secrets  []string
+}
+
+// NewRedactor returns a redactor for the patterns, which are regular expressions, and the secret values.
+// It returns nil when there's nothing to redact.
+func NewRedactor(patterns []string, secrets []string) (*Redactor, error) {
+	var exprs []string
+	for _, pattern := range patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		exprs = append(exprs, "(?:"+pattern+")")
+	}
+	// longer secrets are matched first, so a secret containing another one is masked entirely
+	secrets = slices.Clone(secrets)
+	slices.SortFunc(secrets, func(a, b string) int {
+		return cmp.Compare(len(b), len(a))
+	})
+	for _, secret := range secrets {
+		if secret != "" {
+			exprs = append(exprs, regexp.QuoteMeta(secret))
+		}
+	}
+	if len(exprs) == 0 {
+		return nil, nil
+	}
+	re, err := regexp.Compile(strings.Join(exprs, "|"))
+	if err != nil {
+		return nil, err
+	}
+	return &Redactor{re: re, patterns: patterns, secrets: secrets}, nil
+}
+
+// WithSecrets returns a redactor which also masks the secret values, used for the secrets injected into a job.
+// It can be called on a nil redactor.
+func (r *Redactor) WithSecrets(secrets []string) (*Redactor, error) {
+	if len(secrets) == 0 {
+		return r, nil
+	}
+	if r == nil {
+		return NewRedactor(nil, secrets)
+	}
+	return NewRedactor(r.patterns, slices.Concat(r.secrets, secrets))
+}
+
+// redact masks the matches in the output held back for a stream followed by data. It returns the output
+// which can be stored, the output held back for the rest of a match, and the number of matches masked.
+// Unless final is set, the last line is held back until it's complete, keeping up to redactWindow bytes.
+func (r *Redactor) redact(held, data []byte, final bool) ([]byte, []byte, int64) {
+	buf := append(held, data...)
+	cut := len(buf)
+	if !final {
+		cut = max(bytes.LastIndexByte(buf, '\n')+1, len(buf)-redactWindow)
+	}
+
+	var out []byte
+	var count int64
+	last := 0
+	for _, m := range r.re.FindAllIndex(buf, -1) {
+		if m[0] >= cut {
+			break
+		}
+		if m[0] == m[1] {
+			continue
+		}
+		out = append(out, buf[last:m[0]]...)
+		out = append(out, redactionMask...)
+		last = m[1]
+		count++
+	}
+	cut = max(cut, last)
+	out = append(out, buf[last:cut]...)
+	return out, bytes.Clone(buf[cut:]), count
+}
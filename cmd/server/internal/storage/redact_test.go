@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactorChunks(t *testing.T) {
+	redactor, err := NewRedactor([]string{`password=\S+`}, []string{"s3cr3t", "s3cr3t-t0k3n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		chunks   []string
+		expected string
+		count    int64
+	}{
+		{name: "secret in one chunk", chunks: []string{"token s3cr3t-t0k3n\n"},
+			expected: "token [REDACTED]\n", count: 1},
+		{name: "secret split across two chunks", chunks: []string{"token s3c", "r3t-t0k3n\n"},
+			expected: "token [REDACTED]\n", count: 1},
+		{name: "secret split on its shorter prefix", chunks: []string{"token s3cr3t", "-t0k3n done\n"},
+			expected: "token [REDACTED] done\n", count: 1},
+		{name: "pattern split across chunks", chunks: []string{"login pass", "word=hunter2 ok\n"},
+			expected: "login [REDACTED] ok\n", count: 1},
+		{name: "secret on the last line flushed", chunks: []string{"first\nlast s3cr3t-t0k3n"},
+			expected: "first\nlast [REDACTED]", count: 1},
+		{name: "secret split on the last line flushed", chunks: []string{"last s3cr", "3t"},
+			expected: "last [REDACTED]", count: 1},
+		{name: "incomplete secret flushed as is", chunks: []string{"almost s3cr3"},
+			expected: "almost s3cr3", count: 0},
+		{name: "no secrets", chunks: []string{"plain ", "output\n", "more"},
+			expected: "plain output\nmore", count: 0},
+		{name: "line longer than the window", chunks: []string{strings.Repeat("x", redactWindow+10), "s3cr3t\n"},
+			expected: strings.Repeat("x", redactWindow+10) + "[REDACTED]\n", count: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, held []byte
+			var count int64
+			for _, chunk := range tt.chunks {
+				stored, rest, n := redactor.redact(held, []byte(chunk), false)
+				if strings.Contains(string(stored), "s3cr3t") || strings.Contains(string(stored), "hunter2") {
+					t.Fatalf("a secret was stored before it was redacted: %q", stored)
+				}
+				out, held, count = append(out, stored...), rest, count+n
+			}
+			// the output held back is stored once the stream is idle or closed
+			stored, rest, n := redactor.redact(held, nil, true)
+			out, count = append(out, stored...), count+n
+			if len(rest) != 0 {
+				t.Fatalf("output still held after the final flush: %q", rest)
+			}
+			if string(out) != tt.expected || count != tt.count {
+				t.Fatalf("redacted %q with %d matches, expected %q with %d", out, count, tt.expected, tt.count)
+			}
+		})
+	}
+}
+
+func TestJobRedactsSplitSecret(t *testing.T) {
+	redactor, err := NewRedactor(nil, []string{"s3cr3t-t0k3n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := NewJob(LogOptions{Store: NewMemoryStore(), Redactor: redactor})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, out := range []string{"token=s3cr3t", "-t0k3n\nlast line s3c"} {
+		if err := job.ProcessOutput(Stdout, []byte(out)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !job.Holding(Stdout) || job.Holding(Stderr) {
+		t.Fatal("the incomplete line is not held back")
+	}
+	if err := job.FlushOutput(Stdout); err != nil {
+		t.Fatal(err)
+	}
+	if job.Holding(Stdout) {
+		t.Fatal("the output is still held after it was flushed")
+	}
+	if err := job.ProcessOutput(Stdout, []byte("\n")); err != nil {
+		t.Fatal(err)
+	}
+	// the last line, held back until the output is closed, is stored masked
+	if err := job.ProcessOutput(Stderr, []byte("error s3cr3t-t0k3n")); err != nil {
+		t.Fatal(err)
+	}
+	job.CloseOutput()
+
+	out := readOutput(t, job)
+	expected := "token=[REDACTED]\nlast line s3c\nerror [REDACTED]"
+	if out != expected || job.Redactions() != 2 {
+		t.Fatalf("unexpected output: %q with %d redactions, expected %q", out, job.Redactions(), expected)
+	}
+}
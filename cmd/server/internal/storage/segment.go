@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// segment is a file with part of the output from a command, starting at offset. index is the number of
+// the file, size is the number of bytes of output in it, and stored is the size of the file on disk, which
+// is smaller than size when the file is compressed. Encrypted files are compressed before being encrypted.
+// data has the output of a segment sealed but not stored yet, which is read from memory until it's stored.
+type segment struct {
+	index      int
+	offset     int64
+	size       int64
+	stored     int64
+	compressed bool
+	encrypted  bool
+	data       []byte
+}
+
+// fileName returns the name of the object storing the output for the segment
+func fileName(seg segment) string {
+	name := fmt.Sprintf("%d.log", seg.index)
+	if seg.compressed {
+		name += ".gz"
+	}
+	if seg.encrypted {
+		name += ".enc"
+	}
+	return name
+}
+
+// seal moves the output in the log buffer to a new segment, kept in memory until the uploader writes it
+// to the store, and starts a new buffer
+func (c *CmdLog) seal() {
+	size := int64(len(*c.buffer))
+	c.segments = append(c.segments, segment{
+		index:      c.nextIndex,
+		offset:     c.size - size,
+		size:       size,
+		stored:     size,
+		compressed: c.compress,
+		encrypted:  c.dataKey != nil,
+		data:       *c.buffer,
+	})
+	c.nextIndex++
+	c.stored += size
+	c.segmentStart = true
+	*c.buffer = make([]byte, 0)
+}
+
+// writeSegment writes the output of the sealed segment to the store in an object named index.log, with the
+// .gz suffix when compressed and .enc when encrypted, along with its marks, and returns the size of the
+// object. The wrapped data key is written first when writeKey is set.
+func (c *CmdLog) writeSegment(seg segment, marks []mark, writeKey bool) (int64, error) {
+	if writeKey {
+		if err := c.writeKey(); err != nil {
+			return 0, err
+		}
+	}
+	data := seg.data
+	if seg.compressed {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return 0, err
+		}
+		if err := zw.Close(); err != nil {
+			return 0, err
+		}
+		data = buf.Bytes()
+	}
+	if seg.encrypted {
+		var err error
+		if data, err = seal(c.dataKey, data, c.segmentAAD(seg.index)); err != nil {
+			return 0, err
+		}
+	}
+	if err := c.store.Put(c.id, fileName(seg), data); err != nil {
+		return 0, err
+	}
+	if err := c.putMarks(segmentMarks(seg), marks); err != nil {
+		return 0, err
+	}
+	return int64(len(data)), nil
+}
+
+// remove deletes the objects for the segment with index i and removes it from the log
+func (c *CmdLog) remove(i int) error {
+	seg := c.segments[i]
+	// a segment not stored yet is deleted by the uploader, once it finds the segment is no longer in the log
+	if seg.data == nil {
+		if err := c.deleteSegment(seg); err != nil {
+			return err
+		}
+	}
+	// a new slice is created, since readers may be using the current one
+	c.segments = slices.Concat(c.segments[:i], c.segments[i+1:])
+	c.stored -= seg.stored
+	return nil
+}
+
+// openSegment opens the object for the segment, decrypting and decompressing it when needed, or its output in
+// memory when it's not stored yet. Encrypted segments are decrypted to memory, since they are authenticated as
+// a whole.
+func (c *CmdLog) openSegment(seg segment) (io.ReadCloser, error) {
+	if seg.data != nil {
+		return io.NopCloser(bytes.NewReader(seg.data)), nil
+	}
+	var rc io.ReadCloser
+	if seg.encrypted {
+		data, err := c.decryptSegment(seg)
+		if err != nil {
+			return nil, err
+		}
+		rc = io.NopCloser(bytes.NewReader(data))
+	} else {
+		var err error
+		if rc, err = c.store.Get(c.id, fileName(seg)); err != nil {
+			return nil, err
+		}
+	}
+	if !seg.compressed {
+		return rc, nil
+	}
+	zr, err := gzip.NewReader(rc)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	return &gzipObject{Reader: zr, object: rc}, nil
+}
+
+// decryptSegment reads and decrypts the object for the segment
+func (c *CmdLog) decryptSegment(seg segment) ([]byte, error) {
+	rc, err := c.store.Get(c.id, fileName(seg))
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := open(c.dataKey, data, c.segmentAAD(seg.index))
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt %s/%s: %w", c.id, fileName(seg), err)
+	}
+	return plain, nil
+}
+
+// segmentReaderAt returns a random access reader for the segment. Unless the store supports random access
+// to the object, and the segment is neither compressed nor encrypted, it's read to memory.
+func (c *CmdLog) segmentReaderAt(seg segment) (io.ReaderAt, io.Closer, error) {
+	if seg.data != nil {
+		return bytes.NewReader(seg.data), nil, nil
+	}
+	rc, err := c.openSegment(seg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ra, ok := rc.(io.ReaderAt); ok {
+		return ra, rc, nil
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return bytes.NewReader(data), nil, nil
+}
+
+// gzipObject decompresses an object, closing both the decompressor and the object when closed
+type gzipObject struct {
+	*gzip.Reader
+	object io.ReadCloser
+}
+
+func (g *gzipObject) Close() error {
+	g.Reader.Close()
+	return g.object.Close()
+}
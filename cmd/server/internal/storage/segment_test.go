@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+// waitStored waits for the uploader to store the segments sealed by the job
+func waitStored(t *testing.T, job *Job) {
+	t.Helper()
+	if err := job.storeSegments(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCompressedSegments(t *testing.T) {
+	store := NewMemoryStore()
+	job, err := NewJob(LogOptions{Store: store, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := writeLines(t, job, 2500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.CloseOutput()
+	waitStored(t, job)
+
+	if len(job.log.segments) < 2 {
+		t.Fatalf("the output was stored in %d files", len(job.log.segments))
+	}
+	for _, seg := range job.log.segments {
+		if !seg.compressed || seg.stored >= seg.size/10 {
+			t.Fatalf("file %d: compressed %t, with %d bytes stored for %d", seg.index, seg.compressed, seg.stored,
+				seg.size)
+		}
+		if _, err := store.Get(job.Id.String(), fileName(seg)); err != nil {
+			t.Fatalf("the compressed file %s was not stored: %v", fileName(seg), err)
+		}
+	}
+	if _, err := store.Get(job.Id.String(), "0.log"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("the uncompressed file was stored: %v", err)
+	}
+	// the output still in the buffer is counted uncompressed
+	if job.StoredSize() >= job.Size()/2 {
+		t.Fatalf("%d bytes stored for %d bytes of output", job.StoredSize(), job.Size())
+	}
+
+	// the compressed files are read from the start, from the middle, and backwards for the tail
+	if data, _ := readRanges(t, job); !bytes.Equal(data, out) {
+		t.Fatalf("read %d bytes of output, expected %d", len(data), len(out))
+	}
+	seg := job.log.segments[1]
+	offset := seg.offset + seg.size/2
+	reader := job.NewReader(offset, false)
+	defer reader.Close()
+	if data := readAll(t, reader); !bytes.Equal(data, out[offset:]) {
+		t.Fatalf("read %d bytes from offset %d, expected %d", len(data), offset, len(out)-int(offset))
+	}
+	tail, err := job.TailOffset(int64(len(out)/1024 - 1))
+	if err != nil || tail != 1024 {
+		t.Fatalf("the tail starts at %d, expected 1024: %v", tail, err)
+	}
+}
@@ -2,27 +2,47 @@ package storage
 
 import (
 	"bytes"
-	"fmt"
+	"context"
+	"crypto/cipher"
+	"errors"
 	"io"
+	"log/slog"
 	"os"
 	"os/exec"
+	"slices"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/mhsantos/rlcp/cmd/server/internal/webhook"
 )
 
 type Operation uint
 type JobStatus uint
+type Stream uint8
 
 const (
-	logFileSize int = 1024 * 1024 // 1MB
+	logFileSize    int           = 1024 * 1024 // 1MB
+	tailBlockSize  int           = 64 * 1024   // 64KB
+	markResolution time.Duration = time.Millisecond
+	markMaxBytes   int64         = 64 * 1024 // 64KB
 )
 
+// UnknownExitCode is the exit code of the jobs which didn't exit yet, or whose exit status is unknown
+const UnknownExitCode = -1
+
 const (
 	Run Operation = iota
 	Status
 	Output
 	Stop
+	Upload
+	Download
+	Purge
+	ManageSecrets
+	// ListAll lists the jobs from every user, instead of only the ones owned by the user
+	ListAll
 )
 
 const (
@@ -30,6 +50,13 @@ const (
 	Completed
 	Errored
 	Stopped
+	// Lost is the status of the jobs which were running when the server stopped
+	Lost
+)
+
+const (
+	Stdout Stream = iota
+	Stderr
 )
 
 // JobStorage defines the methods persist and access job relevant data.
@@ -45,98 +72,624 @@ type JobStorage interface {
 
 	// Returns the details for a job, including its storage and output
 	GetJob(jobId string) (*Job, bool)
+
+	// ListJobs returns all the jobs in the storage
+	ListJobs() []*Job
+
+	// DeleteJob removes the job from the storage
+	DeleteJob(jobId string)
 }
 
+// ErrOutputPurged is returned when reading the output from a job after it was purged
+var ErrOutputPurged = errors.New("the job output was purged")
+
 // Job contains the fields necessary to identify a command running on the server
 // and report its output to the clients
 type Job struct {
-	Id        uuid.UUID
-	Status    JobStatus
-	Cmd       *exec.Cmd
-	mu        sync.Mutex
-	log       *CmdLog
-	listeners []chan []byte
+	Id uuid.UUID
+	// Name is an optional unique name for the job
+	Name string
+	// User is the email of the user who scheduled the job
+	User string
+	// Command and Args are the command line run by the job, or the Interpreter and the Args for a script
+	Command     string
+	Args        []string
+	Interpreter string
+	// Description is a free-form text informed by the user
+	Description string
+	// Labels are key=value pairs used to select groups of jobs
+	Labels map[string]string
+	// Host is the server the job runs on
+	Host string
+	// SubmitTime is when the job was scheduled and StartTime when its process started
+	SubmitTime time.Time
+	StartTime  time.Time
+	Cmd        *exec.Cmd
+	// Workspace is the private working directory for the command. Once the command exits, only the files
+	// matching ArtifactGlobs are kept in it.
+	Workspace     string
+	ArtifactGlobs []string
+	// Webhooks are the urls called once the job ends, besides the ones configured on the server
+	Webhooks []string
+	mu       sync.Mutex
+	log      *CmdLog
+	exited   chan struct{}
+	endTime  time.Time
+	exitCode int
+	// deliveries has each attempt to call the webhooks
+	deliveries []webhook.Delivery
+	// artifacts are the paths of the files kept in the workspace, relative to it
+	artifacts []string
+	status    JobStatus
+	// statusMessage explains the status, when it's not clear from the status alone
+	statusMessage string
+	// statusTime is when the status was last set while the server runs, and is zero for the jobs restored
+	statusTime time.Time
+	// pid is the process id of the command, and processStart the time it started, in clock ticks since the
+	// boot, which tells it apart from a later process reusing the same pid
+	pid          int
+	processStart uint64
+	// persisted is set once the output of the finished job was stored, so its record is only written as
+	// finished after that
+	persisted bool
+}
+
+// Chunk is a piece of the command output, starting at Offset bytes from the beginning of the output.
+// All the data in a chunk was written to Stream, and captured at Time.
+// A chunk for output dropped from the log has no data, and Missing has the number of bytes dropped.
+type Chunk struct {
+	Offset  int64
+	Data    []byte
+	Time    time.Time
+	Stream  Stream
+	Missing int64
 }
 
-// CmdLog manages the files and byte buffers storing the output from a command
+// mark records when the output starting at offset was captured, and the stream it was written to.
+// The output up to the offset of the next mark shares the same time and stream.
+// line is the number of lines started before offset, and open tells, for each stream, if its last line
+// was still missing the new line at offset.
+type mark struct {
+	offset int64
+	time   int64
+	stream Stream
+	line   int64
+	open   [2]bool
+}
+
+// CmdLog manages the files and byte buffers storing the output from a command.
+// It's an append only log: segments has the files sealed, in order, followed by the output in the buffer.
 type CmdLog struct {
-	nFiles int
+	// id names the objects of the log in the store
+	id       string
+	store    OutputStore
+	compress bool
+	// dataKey encrypts the segments when set, and wrappedKey is the data key encrypted by the master key
+	dataKey    cipher.AEAD
+	wrappedKey []byte
+	// maxBytes limits the output kept, and policy is applied once the output gets to it
+	maxBytes     int64
+	policy       OutputPolicy
+	segmentSize  int
+	segments     []segment
+	nextIndex    int
+	segmentStart bool
+	// marks has the capture time and stream for the output, in order, and lines and open keep track of the
+	// lines in the output, as recorded in the marks
+	marks []mark
+	lines int64
+	open  [2]bool
+	// size is the size of all the output appended, and stored the size of the segments in the store
+	size   int64
+	stored int64
+	// dropped is the output removed from the log by the policy, and refused the output never appended once
+	// the log or the server budget got to the limit, with truncation being the policy applied
+	dropped    int64
+	refused    int64
+	truncation OutputPolicy
+	// budget is the server limit for the output kept by the running jobs, when set, and taken the part of it
+	// taken by the log
+	budget *OutputBudget
+	taken  int64
+	// redactor masks the secrets before the output is appended, and held has the output from each stream held
+	// back by it
+	redactor   *Redactor
+	held       [2][]byte
+	redactions int64
+	// sink receives the output once it's appended, when set
+	sink   OutputSink
 	buffer *[]byte
+	// uploading is set while the uploader stores the sealed segments, and uploaded is closed once it's done.
+	// uploadErr is the error storing a segment, and keyStored is set once the wrapped key is stored.
+	uploading bool
+	uploaded  chan struct{}
+	uploadErr error
+	keyStored bool
+	// changed notifies the readers of new output, and is closed and replaced every time output is appended or
+	// the log is closed
+	changed chan struct{}
+	closed  bool
+	purged  bool
 }
 
-func NewJob() *Job {
+// LogOptions defines where and how the output from the jobs is stored
+type LogOptions struct {
+	// Store keeps the output once it's flushed from the job buffer
+	Store OutputStore
+	// Compress enables gzip compression for the output files
+	Compress bool
+	// MaxBytes limits the output stored for the job, 0 for no limit
+	MaxBytes int64
+	// Policy is applied once the output gets to MaxBytes
+	Policy OutputPolicy
+	// Redactor masks the secrets in the output, nil to store it as is
+	Redactor *Redactor
+	// MasterKey wraps the keys encrypting the output files of each job, nil to store them unencrypted
+	MasterKey *MasterKey
+	// Sink receives a copy of the output stored, nil to only store it
+	Sink OutputSink
+	// Budget limits the output stored by all the running jobs together, nil for no limit
+	Budget *OutputBudget
+}
+
+// OutputSink receives a copy of the output from the jobs as it's stored, after the redaction, to forward it
+// somewhere else. Its methods are called while storing the output, so they must never block.
+type OutputSink interface {
+	// Send receives the output the job wrote to the stream at time t. data is only valid during the call.
+	Send(jobId, user string, stream Stream, t time.Time, data []byte)
+	// CloseJob is called once the job won't output anything else
+	CloseJob(jobId string)
+}
+
+// NewJob creates a job storing its output in the store, under its id. It returns an error if the key
+// encrypting the output can't be generated.
+func NewJob(opts LogOptions) (*Job, error) {
+	id := uuid.New()
 	buffer := make([]byte, 0)
-	return &Job{
-		Id: uuid.New(),
+	job := &Job{
+		Id:         id,
+		SubmitTime: time.Now(),
 		log: &CmdLog{
-			buffer: &buffer,
+			id:          id.String(),
+			store:       opts.Store,
+			compress:    opts.Compress,
+			maxBytes:    opts.MaxBytes,
+			policy:      opts.Policy,
+			budget:      opts.Budget,
+			redactor:    opts.Redactor,
+			sink:        opts.Sink,
+			segmentSize: segmentSize(opts),
+			buffer:      &buffer,
+			changed:     make(chan struct{}),
 		},
-		listeners: make([]chan []byte, 0),
+		exited:   make(chan struct{}),
+		exitCode: UnknownExitCode,
 	}
+	if opts.MasterKey != nil {
+		var err error
+		if job.log.dataKey, job.log.wrappedKey, err = opts.MasterKey.newDataKey(id.String()); err != nil {
+			return nil, err
+		}
+	}
+	return job, nil
 }
 
-// ProcessOutput receives an array of bytes from the command's output and sends it to the receiver channels.
-// After that it stores it in a temporary buffer. Once that buffer is full, it's stored on disk and flushed.
-func (j *Job) ProcessOutput(out []byte) error {
+// MarkExited signals that the command has exited with exitCode and its workspace was cleaned up
+func (j *Job) MarkExited(exitCode int) {
 	j.mu.Lock()
-	for _, listener := range j.listeners {
-		listener <- out
+	j.endTime = time.Now()
+	j.exitCode = exitCode
+	j.mu.Unlock()
+	close(j.exited)
+}
+
+// Finished returns true once the command has exited and its workspace was cleaned up
+func (j *Job) Finished() bool {
+	select {
+	case <-j.exited:
+		return true
+	default:
+		return false
 	}
-	j.log.appendBytes(out)
+}
 
-	if len(*j.log.buffer) >= logFileSize {
-		err := persistLog(j)
-		if err != nil {
-			j.Status = Errored
-			j.mu.Unlock()
-			return err
-		}
-		j.log.nFiles++
-		*j.log.buffer = make([]byte, 0)
+// EndTime returns the time when the command exited, or the zero time while it's running
+func (j *Job) EndTime() time.Time {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.endTime
+}
+
+// ExitCode returns the exit code of the command, which is UnknownExitCode while it's running, or when the
+// command didn't start or was lost
+func (j *Job) ExitCode() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.exitCode
+}
+
+// Status returns the status of the job
+func (j *Job) Status() JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
+
+// StatusMessage returns the message explaining the status, if any
+func (j *Job) StatusMessage() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.statusMessage
+}
+
+// SetStatus sets the status of the job, with a message explaining it, which may be empty
+func (j *Job) SetStatus(status JobStatus, message string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status = status
+	j.statusMessage = message
+	j.statusTime = time.Now()
+}
+
+// UpdateStatus sets the status of the job to status, as long as it's still from. It returns false if the
+// status was something else, and was kept.
+func (j *Job) UpdateStatus(from, status JobStatus) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.status != from {
+		return false
 	}
+	j.status = status
+	j.statusTime = time.Now()
+	return true
+}
+
+// StatusTime returns when the status was last set, or the zero time if it wasn't set since the job was
+// created or restored
+func (j *Job) StatusTime() time.Time {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.statusTime
+}
+
+// SetProcess records the process running the command, once it starts
+func (j *Job) SetProcess(pid int, start uint64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.pid = pid
+	j.processStart = start
+}
+
+// Process returns the id of the process running the command and the time it started, in clock ticks since the
+// boot, or zeros before it starts
+func (j *Job) Process() (int, uint64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.pid, j.processStart
+}
+
+// SetArtifacts records the files kept in the workspace once the command exits, relative to the workspace
+func (j *Job) SetArtifacts(artifacts []string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.artifacts = artifacts
+}
+
+// Artifacts returns the files kept in the workspace, relative to it, which are only known once the command exits
+func (j *Job) Artifacts() []string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return slices.Clone(j.artifacts)
+}
+
+// AddDelivery adds an attempt to call a webhook to the deliveries of the job
+func (j *Job) AddDelivery(delivery webhook.Delivery) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.deliveries = append(j.deliveries, delivery)
+}
+
+// Deliveries returns the attempts to call the webhooks for the job, in the order they were made
+func (j *Job) Deliveries() []webhook.Delivery {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return slices.Clone(j.deliveries)
+}
+
+// Exited returns a channel which is closed once the command has exited and its workspace was cleaned up
+func (j *Job) Exited() <-chan struct{} {
+	return j.exited
+}
+
+// ProcessOutput appends an array of bytes the command wrote to stream to the log and notifies the readers.
+// It never waits for the readers, which read the log at their own pace. The output is stored in a temporary
+// buffer, and once that buffer is full, it's sealed and stored by the uploader in the background. It only
+// waits for the uploader when the store falls behind the command.
+// When redacting, the secrets are masked before the output is stored, and the end of the output may be held
+// back until the rest of a secret is written, or until FlushOutput is called.
+// With the StopJob policy, or once the output of the running jobs gets to the server budget, the output past
+// the limit is dropped and ErrOutputLimit is returned, so the command is stopped.
+func (j *Job) ProcessOutput(stream Stream, out []byte) error {
+	j.mu.Lock()
+	err := j.appendOutput(stream, out, false)
+	j.mu.Unlock()
+	j.waitUploads()
+	return err
+}
+
+// FlushOutput stores the output held back for the stream by the redaction
+func (j *Job) FlushOutput(stream Stream) error {
+	j.mu.Lock()
+	err := j.appendOutput(stream, nil, true)
 	j.mu.Unlock()
+	j.waitUploads()
+	return err
+}
+
+// Holding returns true if some output from the stream is held back by the redaction
+func (j *Job) Holding(stream Stream) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.log.held[stream]) > 0
+}
+
+// Redactions returns the number of secrets masked in the output
+func (j *Job) Redactions() int64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.log.redactions
+}
+
+// appendOutput redacts the output, and appends it to the log. final stores all the output held back.
+// Once the output couldn't be stored, it returns the error from the uploader.
+func (j *Job) appendOutput(stream Stream, out []byte, final bool) error {
+	if j.log.uploadErr != nil {
+		return j.log.uploadErr
+	}
+	if j.log.redactor != nil {
+		var count int64
+		out, j.log.held[stream], count = j.log.redactor.redact(j.log.held[stream], out, final)
+		j.log.redactions += count
+	}
+	if len(out) == 0 {
+		return nil
+	}
+
+	if j.log.truncation == StopJob {
+		j.log.refused += int64(len(out))
+		return nil
+	}
+	var err error
+	if j.log.policy == StopJob && j.log.maxBytes > 0 && j.log.size+int64(len(out)) > j.log.maxBytes {
+		keep := j.log.maxBytes - j.log.size
+		j.log.refused += int64(len(out)) - keep
+		j.log.truncation = StopJob
+		j.status = Stopped
+		out = out[:keep]
+		err = ErrOutputLimit
+	}
+	if taken := j.log.takeBudget(int64(len(out))); taken < int64(len(out)) {
+		j.log.refused += int64(len(out)) - taken
+		j.log.truncation = StopJob
+		j.status = Stopped
+		j.statusMessage = "the output of the running jobs got to the server limit"
+		out = out[:taken]
+		err = ErrOutputLimit
+	}
+
+	now := time.Now()
+	if len(out) > 0 && j.log.sink != nil {
+		j.log.sink.Send(j.Id.String(), j.User, stream, now, out)
+	}
+	// the output is split at the end of the segment, so the buffer never holds more than a segment
+	for len(out) > 0 {
+		n := min(len(out), j.log.segmentSize-len(*j.log.buffer))
+		j.log.addMark(stream, now)
+		j.log.countLines(stream, out[:n])
+		j.log.appendBytes(out[:n])
+		out = out[n:]
+		if len(*j.log.buffer) == j.log.segmentSize {
+			j.log.seal()
+			j.startUpload()
+		}
+	}
+	j.log.notify()
+
+	// the output in the buffer counts towards the limit as well, so it's checked on every write
+	if err := j.log.enforceLimit(); err != nil {
+		j.status = Errored
+		return err
+	}
+	return err
+}
+
+// NewReader returns a reader for the output, starting at offset. When follow is true, the reader waits for
+// new output until the output is closed, otherwise it stops at the end of the output available at the time of the call.
+func (j *Job) NewReader(offset int64, follow bool) *OutputReader {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	end := int64(-1)
+	if !follow {
+		end = j.log.size
+	}
+	return &OutputReader{
+		job:    j,
+		offset: offset,
+		end:    end,
+	}
+}
+
+// PurgeOutput removes the output stored and the buffer for a finished job. Reading the output after
+// that returns ErrOutputPurged.
+func (j *Job) PurgeOutput() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.log.store.DeleteJob(j.log.id); err != nil {
+		return err
+	}
+	j.log.purged = true
+	j.log.segments = nil
+	j.log.nextIndex = 0
+	j.log.marks = nil
+	j.log.lines = 0
+	j.log.open = [2]bool{}
+	j.log.stored = 0
+	*j.log.buffer = make([]byte, 0)
+	j.log.notify()
 	return nil
 }
 
-// RegisterListener adds a listener channel to the poll of listener channels.
-// It also reads the files and buffer array to send all the output, from its beginning to the client.
-func (j *Job) RegisterListener(listener chan []byte) {
-	// first read the logs stored in files
-	i := 0
-	for {
-		j.mu.Lock()
-		if i == j.log.nFiles {
-			j.listeners = append(j.listeners, listener)
-			// then loads the logs from the buffer
-			readLogBuffer(listener, *j.log.buffer)
-			if j.Status != Running {
-				// this is the scenario where we are registering a listener for a command that already
-				// finished, no matter if due to error, complete or stop
-				close(listener)
-			}
-			j.mu.Unlock()
-			break
+// Remove deletes everything stored on disk for the job: its output files and the artifacts kept in its workspace
+func (j *Job) Remove() error {
+	if err := j.PurgeOutput(); err != nil {
+		return err
+	}
+	if len(j.Workspace) == 0 {
+		return nil
+	}
+	return os.RemoveAll(j.Workspace)
+}
+
+// CloseOutput marks the output as finished, once the command won't output anything else, storing the
+// output held back by the redaction. Readers following the output stop once they get to its end.
+func (j *Job) CloseOutput() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, stream := range []Stream{Stdout, Stderr} {
+		if err := j.appendOutput(stream, nil, true); err != nil && err != ErrOutputLimit {
+			slog.Error("error storing the output held back", slog.String("jobid", j.Id.String()), slog.Any("error", err))
 		}
-		j.mu.Unlock()
-		for i < j.log.nFiles {
-			err := readFile(listener, fmt.Sprintf("%s_%d.log", j.Id, i))
+	}
+	if !j.log.closed {
+		j.log.closed = true
+		j.log.releaseBudget(j.log.taken)
+		j.log.notify()
+		if j.log.sink != nil {
+			j.log.sink.CloseJob(j.Id.String())
+		}
+	}
+}
+
+// TailOffset returns the offset where the last n lines of the output start.
+// It reads the buffer and then the files backwards, from the last one, until n lines are found, or until
+// the start of the output, or of a gap left by the output dropped.
+func (j *Job) TailOffset(n int64) (int64, error) {
+	j.mu.Lock()
+	segments := j.log.segments
+	buffer := *j.log.buffer
+	size := j.log.size
+	j.mu.Unlock()
+
+	if n <= 0 {
+		return size, nil
+	}
+
+	// the last byte is skipped, so a trailing new line doesn't count as an extra line
+	limit := size - 1
+	end := size
+	for i := len(segments); i >= 0; i-- {
+		var part io.ReaderAt
+		var partSize int64
+		var file io.Closer
+		if i == len(segments) {
+			part, partSize = bytes.NewReader(buffer), int64(len(buffer))
+		} else {
+			if segments[i].offset+segments[i].size < end {
+				return end, nil
+			}
+			var err error
+			part, file, err = j.log.segmentReaderAt(segments[i])
 			if err != nil {
-				return
+				return 0, err
 			}
-			i++
+			partSize = segments[i].size
+		}
+		start := end - partSize
+		offset, found, err := findLineStart(part, start, min(end, limit), &n)
+		if file != nil {
+			file.Close()
+		}
+		if err != nil {
+			return 0, err
+		}
+		if found {
+			return offset, nil
+		}
+		end = start
+	}
+	return end, nil
+}
+
+// Size returns the number of bytes the command has output so far
+func (j *Job) Size() int64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.log.size
+}
+
+// WaitOutput waits until the output gets to size bytes, returning true, or returns false once the output is
+// closed before that, or when ctx is done
+func (j *Job) WaitOutput(ctx context.Context, size int64) bool {
+	for {
+		j.mu.Lock()
+		reached, closed, changed := j.log.size >= size, j.log.closed, j.log.changed
+		j.mu.Unlock()
+		if reached {
+			return true
+		}
+		if closed {
+			return false
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return false
 		}
 	}
 }
 
-// CloseListeners iterates over the listeners pool closing each listener channel.
-// It is important in several scenarios where we have listeners getting output and a command ends,
-// to do not leave those listeners hanging.
-func (j *Job) CloseListeners() {
+// StoredSize returns the number of bytes used to store the output, adding the size of the files on disk,
+// which may be compressed, and the output in the buffer
+func (j *Job) StoredSize() int64 {
 	j.mu.Lock()
-	for _, listener := range j.listeners {
-		close(listener)
+	defer j.mu.Unlock()
+	return j.log.stored + int64(len(*j.log.buffer))
+}
+
+func (o Operation) String() string {
+	switch o {
+	case Run:
+		return "Run"
+	case Status:
+		return "Status"
+	case Output:
+		return "Output"
+	case Stop:
+		return "Stop"
+	case Upload:
+		return "Upload"
+	case Download:
+		return "Download"
+	case Purge:
+		return "Purge"
+	case ManageSecrets:
+		return "ManageSecrets"
+	case ListAll:
+		return "ListAll"
+	default:
+		return "Undefined"
+	}
+}
+
+func (s Stream) String() string {
+	switch s {
+	case Stdout:
+		return "stdout"
+	case Stderr:
+		return "stderr"
+	default:
+		return "Undefined"
 	}
-	j.mu.Unlock()
 }
 
 func (s JobStatus) String() string {
@@ -149,6 +702,8 @@ func (s JobStatus) String() string {
 		return "Errored"
 	case Stopped:
 		return "Stopped"
+	case Lost:
+		return "Lost"
 	default:
 		return "Undefined"
 	}
@@ -157,60 +712,88 @@ func (s JobStatus) String() string {
 // appendBytes appends bytes to the output buffer
 func (c *CmdLog) appendBytes(out []byte) {
 	*c.buffer = append(*c.buffer, out...)
-	//	(*c).buffer = append(c.buffer, out...)
+	c.size += int64(len(out))
 }
 
-// readFile reads the contents of a log file from persistent storage
-func readFile(ch chan []byte, filename string) error {
-	file, err := os.Open(filename)
-	if err != nil {
-		return err
+// addMark records the time and stream for the output appended next. Output written to the same stream
+// within markResolution of the last mark shares that mark, up to markMaxBytes, so the log doesn't keep
+// a mark for every read. Every segment starts with a new mark, so the lines are counted correctly
+// after a segment is dropped.
+func (c *CmdLog) addMark(stream Stream, now time.Time) {
+	t := now.UnixNano()
+	if n := len(c.marks); n > 0 && !c.segmentStart {
+		last := c.marks[n-1]
+		if last.stream == stream && t-last.time < int64(markResolution) && c.size-last.offset < markMaxBytes {
+			return
+		}
 	}
-	defer file.Close()
+	c.segmentStart = false
+	c.marks = append(c.marks, mark{offset: c.size, time: t, stream: stream, line: c.lines, open: c.open})
+}
 
-	buffer := make([]byte, 1024)
-	for {
-		bytesRead, err := file.Read(buffer)
-		if err != nil {
-			if err != io.EOF {
-				return err
-			}
-			break
-		}
-		ch <- buffer[:bytesRead]
+// countLines updates the number of lines started in the output with the output appended next
+func (c *CmdLog) countLines(stream Stream, out []byte) {
+	if len(out) == 0 {
+		return
 	}
-	return nil
+	c.lines += lineStarts(out, c.open[stream])
+	c.open[stream] = out[len(out)-1] != '\n'
 }
 
-func readLogBuffer(ch chan []byte, log []byte) {
-	reader := bytes.NewReader(log)
-	buffer := make([]byte, 1024) // Buffer to read into
-	for {
-		n, err := reader.Read(buffer)
-		if n > 0 {
-			ch <- buffer[:n]
-		} else {
-			if err != nil {
-				if err == io.EOF {
-					break
-				}
-			}
-		}
+// markAt returns the mark for the output at offset, and the offset where the next mark starts
+func (c *CmdLog) markAt(offset int64) (mark, int64) {
+	i := sort.Search(len(c.marks), func(i int) bool {
+		return c.marks[i].offset > offset
+	})
+	end := c.size
+	if i < len(c.marks) {
+		end = c.marks[i].offset
+	}
+	if i == 0 {
+		return mark{}, end
 	}
+	return c.marks[i-1], end
 }
 
-// persistLog writes the log buffer to persistent storage in a file named jobid_index.log
-func persistLog(job *Job) error {
-	filename := fmt.Sprintf("%s_%d.log", job.Id, job.log.nFiles)
-	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return err
+// notify wakes up the readers waiting for changes in the log
+func (c *CmdLog) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// lineStarts returns the number of lines starting in out, which is all output from the same stream.
+// open tells if the line before out was still missing the new line.
+func lineStarts(out []byte, open bool) int64 {
+	if len(out) == 0 {
+		return 0
 	}
-	defer file.Close()
+	n := int64(bytes.Count(out[:len(out)-1], []byte{'\n'}))
+	if !open {
+		n++
+	}
+	return n
+}
 
-	_, err = file.Write(*job.log.buffer)
-	if err != nil {
-		return err
+// findLineStart reads part backwards, from end to start, decrementing lines on every new line found.
+// When lines gets to zero, it returns the offset of the byte following that new line.
+// start and end are offsets in the command output, and start is the offset of the first byte in part.
+func findLineStart(part io.ReaderAt, start, end int64, lines *int64) (int64, bool, error) {
+	block := make([]byte, tailBlockSize)
+	for end > start {
+		n := min(int64(len(block)), end-start)
+		from := end - n
+		if _, err := part.ReadAt(block[:n], from-start); err != nil && err != io.EOF {
+			return 0, false, err
+		}
+		for k := n - 1; k >= 0; k-- {
+			if block[k] == '\n' {
+				*lines--
+				if *lines == 0 {
+					return from + k + 1, true, nil
+				}
+			}
+		}
+		end = from
 	}
-	return nil
+	return 0, false, nil
 }
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// OutputStore keeps the output of the jobs once it's flushed from their buffers, as objects named by the job id
+// and the segment file name. The jobs append to their logs through the executor.LogHandler methods, and the logs
+// write full segments to the store, and read them back for the readers lagging behind the buffer.
+type OutputStore interface {
+	// Put stores data as the object with the name for the job, replacing it if it exists
+	Put(jobId, name string, data []byte) error
+	// Get opens the object with the name for the job. It returns an error matching fs.ErrNotExist when the
+	// object doesn't exist.
+	Get(jobId, name string) (io.ReadCloser, error)
+	// Delete removes the object with the name for the job
+	Delete(jobId, name string) error
+	// DeleteJob removes all the objects for the job
+	DeleteJob(jobId string) error
+	// Jobs returns the ids of the jobs with objects in the store
+	Jobs() ([]string, error)
+}
+
+// MemoryStore keeps the output in memory, so it's lost when the server stops
+type MemoryStore struct {
+	mu      sync.RWMutex
+	objects map[string]map[string][]byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{objects: make(map[string]map[string][]byte)}
+}
+
+func (m *MemoryStore) Put(jobId, name string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.objects[jobId] == nil {
+		m.objects[jobId] = make(map[string][]byte)
+	}
+	m.objects[jobId][name] = bytes.Clone(data)
+	return nil
+}
+
+func (m *MemoryStore) Get(jobId, name string) (io.ReadCloser, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, ok := m.objects[jobId][name]
+	if !ok {
+		return nil, fmt.Errorf("%s/%s: %w", jobId, name, fs.ErrNotExist)
+	}
+	// the objects are never modified once stored, so they can be read without copying
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *MemoryStore) Delete(jobId, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.objects[jobId][name]; !ok {
+		return fmt.Errorf("%s/%s: %w", jobId, name, fs.ErrNotExist)
+	}
+	delete(m.objects[jobId], name)
+	return nil
+}
+
+func (m *MemoryStore) DeleteJob(jobId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, jobId)
+	return nil
+}
+
+func (m *MemoryStore) Jobs() ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	jobs := make([]string, 0, len(m.objects))
+	for jobId := range m.objects {
+		jobs = append(jobs, jobId)
+	}
+	slices.Sort(jobs)
+	return jobs, nil
+}
+
+// LocalStore keeps the output in files in the root directory, in a subdirectory per job.
+// The files are only accessible by the server user.
+type LocalStore struct {
+	root string
+}
+
+func NewLocalStore(root string) *LocalStore {
+	return &LocalStore{root: root}
+}
+
+// Put writes the file through a temporary file, which is renamed once complete, so the file is never
+// left partially written
+func (l *LocalStore) Put(jobId, name string, data []byte) error {
+	dir := filepath.Join(l.root, jobId)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func (l *LocalStore) Get(jobId, name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(l.root, jobId, name))
+}
+
+func (l *LocalStore) Delete(jobId, name string) error {
+	return os.Remove(filepath.Join(l.root, jobId, name))
+}
+
+func (l *LocalStore) DeleteJob(jobId string) error {
+	return os.RemoveAll(filepath.Join(l.root, jobId))
+}
+
+func (l *LocalStore) Jobs() ([]string, error) {
+	entries, err := os.ReadDir(l.root)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var jobs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			jobs = append(jobs, entry.Name())
+		}
+	}
+	return jobs, nil
+}
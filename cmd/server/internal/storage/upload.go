@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"slices"
+)
+
+// maxPendingSegments is the number of segments waiting to be stored past which the writes of the command
+// wait for the uploader, so the output of a command writing faster than the store doesn't pile up in memory
+const maxPendingSegments = 4
+
+// startUpload starts the uploader for the segments sealed by the log, unless it's already running.
+// It's called with the lock held.
+func (j *Job) startUpload() {
+	if j.log.uploading {
+		return
+	}
+	j.log.uploading = true
+	j.log.uploaded = make(chan struct{})
+	go j.upload(j.log.uploaded)
+}
+
+// upload stores the sealed segments in order, along with their marks, and closes done once none is left.
+// The segments are compressed, encrypted and written to the store, which may be over the network, without
+// holding the lock, which is only taken to swap in the file stored for the output kept in memory.
+// When a segment can't be stored, the job is marked as Errored, and the writes to the log return the error
+// from then on, so the command is killed. The segment is kept in memory, so its output can still be read.
+func (j *Job) upload(done chan struct{}) {
+	defer close(done)
+	for {
+		j.mu.Lock()
+		i := slices.IndexFunc(j.log.segments, func(seg segment) bool { return seg.data != nil })
+		if i < 0 || j.log.uploadErr != nil {
+			j.log.uploading = false
+			j.mu.Unlock()
+			return
+		}
+		seg := j.log.segments[i]
+		marks := j.log.marksIn(seg)
+		writeKey := seg.encrypted && !j.log.keyStored
+		j.mu.Unlock()
+
+		stored, err := j.log.writeSegment(seg, marks, writeKey)
+
+		j.mu.Lock()
+		if err != nil {
+			j.log.uploadErr = err
+			j.status = Errored
+			j.statusMessage = fmt.Sprintf("the output could not be stored: %v", err)
+			j.log.notify()
+			j.mu.Unlock()
+			slog.Error("error storing the job output", slog.String("jobid", j.log.id), slog.Int("segment", seg.index),
+				slog.Any("error", err))
+			return
+		}
+		j.log.keyStored = j.log.keyStored || writeKey
+		purged := j.log.purged
+		i = slices.IndexFunc(j.log.segments, func(s segment) bool { return s.index == seg.index })
+		if i >= 0 {
+			// a new slice is created, since readers may be using the current one
+			j.log.segments = slices.Clone(j.log.segments)
+			j.log.segments[i].data = nil
+			j.log.segments[i].stored = stored
+			j.log.stored += stored - seg.size
+			j.log.notify()
+		}
+		j.mu.Unlock()
+
+		// the output was purged, or the segment dropped by the limit, while it was stored
+		if purged {
+			err = j.log.store.DeleteJob(j.log.id)
+		} else if i < 0 {
+			err = j.log.deleteSegment(seg)
+		}
+		if err != nil {
+			slog.Error("error removing the output dropped", slog.String("jobid", j.log.id),
+				slog.Int("segment", seg.index), slog.Any("error", err))
+		}
+	}
+}
+
+// waitUploads waits while more than maxPendingSegments segments are waiting to be stored, unless the uploader
+// failed, in which case the error is returned by the next write
+func (j *Job) waitUploads() {
+	for {
+		j.mu.Lock()
+		pending := 0
+		for _, seg := range j.log.segments {
+			if seg.data != nil {
+				pending++
+			}
+		}
+		failed, changed := j.log.uploadErr != nil, j.log.changed
+		j.mu.Unlock()
+		if pending <= maxPendingSegments || failed {
+			return
+		}
+		<-changed
+	}
+}
+
+// storeSegments waits for the uploader to store the segments sealed so far, and returns the error storing
+// them, if any
+func (j *Job) storeSegments() error {
+	j.mu.Lock()
+	uploaded := j.log.uploaded
+	j.mu.Unlock()
+	if uploaded != nil {
+		<-uploaded
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.log.uploadErr
+}
+
+// deleteSegment deletes the objects stored for the segment
+func (c *CmdLog) deleteSegment(seg segment) error {
+	if err := c.store.Delete(c.id, fileName(seg)); err != nil {
+		return err
+	}
+	if err := c.store.Delete(c.id, segmentMarks(seg)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// slowStore is a store whose writes wait for release, or fail with err when set
+type slowStore struct {
+	*MemoryStore
+	release chan struct{}
+	err     error
+}
+
+func (s *slowStore) Put(jobId, name string, data []byte) error {
+	<-s.release
+	if s.err != nil {
+		return s.err
+	}
+	return s.MemoryStore.Put(jobId, name, data)
+}
+
+func TestUploadWithoutLock(t *testing.T) {
+	store := &slowStore{MemoryStore: NewMemoryStore(), release: make(chan struct{})}
+	job, err := NewJob(LogOptions{Store: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.log.segmentSize = 4 * 1024
+
+	// the writes and the readers go on while the first segment is being stored
+	out, err := writeLines(t, job, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := readRanges(t, job); !bytes.Equal(data, out) {
+		t.Fatalf("read %d bytes of output, expected %d", len(data), len(out))
+	}
+	if rec := job.Record(); len(rec.Log.Segments) != 0 || rec.Log.Stored != 0 {
+		t.Fatalf("the segments not stored yet were recorded: %+v", rec.Log)
+	}
+
+	close(store.release)
+	job.CloseOutput()
+	if err := job.Persist(); err != nil {
+		t.Fatal(err)
+	}
+	if rec := job.Record(); len(rec.Log.Segments) != 2 || rec.Log.Stored != int64(len(out)) {
+		t.Fatalf("unexpected segments recorded: %+v", rec.Log)
+	}
+	if data, _ := readRanges(t, job); !bytes.Equal(data, out) {
+		t.Fatalf("read %d bytes of stored output, expected %d", len(data), len(out))
+	}
+}
+
+func TestUploadError(t *testing.T) {
+	store := &slowStore{MemoryStore: NewMemoryStore(), release: make(chan struct{}), err: errors.New("store is down")}
+	close(store.release)
+	job, err := NewJob(LogOptions{Store: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.log.segmentSize = 4 * 1024
+
+	out, err := writeLines(t, job, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := job.storeSegments(); !errors.Is(err, store.err) {
+		t.Fatalf("storing the segments returned %v", err)
+	}
+	if job.Status() != Errored || !strings.Contains(job.StatusMessage(), "store is down") {
+		t.Fatalf("unexpected status %s: %s", job.Status(), job.StatusMessage())
+	}
+	// the writes fail from then on, so the command is killed, but the output kept in memory can still be read
+	if _, err := writeLines(t, job, 1); !errors.Is(err, store.err) {
+		t.Fatalf("writing after the error returned %v", err)
+	}
+	job.CloseOutput()
+	if data, _ := readRanges(t, job); !bytes.Equal(data, out) {
+		t.Fatalf("read %d bytes of output, expected %d", len(data), len(out))
+	}
+	if err := job.Persist(); !errors.Is(err, store.err) {
+		t.Fatalf("persisting the job returned %v", err)
+	}
+}
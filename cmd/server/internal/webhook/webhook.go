@@ -0,0 +1,272 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Headers sent with every notification. The signature is only sent when the notifier has a key.
+const (
+	EventHeader     = "X-Rlcp-Event"
+	DeliveryHeader  = "X-Rlcp-Delivery"
+	TimestampHeader = "X-Rlcp-Timestamp"
+	SignatureHeader = "X-Rlcp-Signature"
+)
+
+const (
+	defaultMaxAttempts = 5
+	defaultMinBackoff  = time.Second
+	defaultMaxBackoff  = time.Minute
+	defaultTimeout     = 10 * time.Second
+)
+
+// Options configure the deliveries. Key signs the notifications, and each notification is attempted up to
+// MaxAttempts times, waiting from MinBackoff up to MaxBackoff between the attempts, doubling every time.
+// Timeout limits each attempt. The defaults are used for the options not set.
+// Unless AllowPrivate is set, the notifier only connects to public addresses, so the webhooks can't reach the
+// services next to the server, like the cloud metadata endpoints.
+type Options struct {
+	Key          []byte
+	MaxAttempts  int
+	MinBackoff   time.Duration
+	MaxBackoff   time.Duration
+	Timeout      time.Duration
+	AllowPrivate bool
+}
+
+// Delivery is an attempt to post a notification to a webhook. URL only has the scheme and host of the webhook,
+// StatusCode is the status of the response, 0 when there was none, and Error explains why the attempt failed,
+// when it did.
+type Delivery struct {
+	URL        string    `json:"url"`
+	Attempt    int       `json:"attempt"`
+	Time       time.Time `json:"time"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// reserved are the IPv4 ranges not covered by the checks from netip which still don't lead to public hosts:
+// this network, the shared address space used by carrier NAT and some metadata endpoints, the IETF protocol
+// assignments, benchmarking and the reserved ranges
+var reserved = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+}
+
+// errPrivateAddress is returned when a webhook resolves to an address it's not allowed to reach
+var errPrivateAddress = errors.New("webhooks can't call private, loopback or link-local addresses")
+
+// Notifier posts notifications to webhooks, in the background. Each notification is a JSON body, signed with
+// HMAC-SHA256 over the timestamp header, a dot and the body. A notification is retried on network errors,
+// 5xx responses, 408 and 429, and given up on other responses.
+type Notifier struct {
+	opts   Options
+	client *http.Client
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewNotifier(opts Options) *Notifier {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultMaxAttempts
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = defaultMinBackoff
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = max(defaultMaxBackoff, opts.MinBackoff)
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !opts.AllowPrivate {
+		// the addresses are checked once resolved, right before connecting, so a name can't resolve to a
+		// public address when validated and to a private one when called. A proxy would be checked instead
+		// of the webhook, so none is used.
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: checkAddress}
+		transport.DialContext = dialer.DialContext
+		transport.Proxy = nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Notifier{
+		opts: opts,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   opts.Timeout,
+			// a redirect could lead anywhere, so it's taken as the response
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// ValidateURL returns an error if the url can't be used as a webhook. Unless allowPrivate is set, the urls with
+// the address of a host which is not public are rejected as well. The names are checked once they are resolved,
+// when the webhook is called.
+func ValidateURL(value string, allowPrivate bool) error {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid webhook url %q", Redact(value))
+	}
+	if allowPrivate {
+		return nil
+	}
+	if addr, err := netip.ParseAddr(u.Hostname()); (err == nil && !public(addr)) || u.Hostname() == "localhost" {
+		return fmt.Errorf("invalid webhook url %q: %w", Redact(value), errPrivateAddress)
+	}
+	return nil
+}
+
+// public returns true if the address belongs to a public host
+func public(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range reserved {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkAddress is the control function of the dialer, which refuses to connect to addresses which are not public
+func checkAddress(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !public(addrPort.Addr()) {
+		return errPrivateAddress
+	}
+	return nil
+}
+
+// Redact returns the scheme and host of the url, which are recorded for the deliveries, since the path and
+// query of webhook urls often hold tokens
+func Redact(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		return "invalid url"
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// Signature returns the value of the signature header for the body sent at the timestamp: sha256= followed by
+// the hex encoded HMAC-SHA256 of the timestamp, a dot and the body
+func Signature(key []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Notify posts the body for the event to each of the urls, in the background. record is called after
+// every attempt, and may be called concurrently for different urls.
+func (n *Notifier) Notify(urls []string, event string, body []byte, record func(Delivery)) {
+	for _, u := range urls {
+		n.wg.Add(1)
+		go func() {
+			defer n.wg.Done()
+			n.deliver(u, event, uuid.NewString(), body, record)
+		}()
+	}
+}
+
+// Close gives up the notifications waiting to be retried, and waits for the attempts in progress
+func (n *Notifier) Close() {
+	n.cancel()
+	n.wg.Wait()
+	n.client.CloseIdleConnections()
+}
+
+// deliver posts the notification to the url until it's accepted, it fails for good, or the attempts run out.
+// Every attempt has the same delivery id, so the receiver can tell the retries apart.
+func (n *Notifier) deliver(u, event, id string, body []byte, record func(Delivery)) {
+	backoff := n.opts.MinBackoff
+	for attempt := 1; ; attempt++ {
+		delivery := Delivery{URL: Redact(u), Attempt: attempt, Time: time.Now()}
+		code, err := n.post(u, event, id, body)
+		delivery.StatusCode = code
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+		record(delivery)
+		if err == nil {
+			return
+		}
+		if !retryable(code) || errors.Is(err, errPrivateAddress) || attempt == n.opts.MaxAttempts {
+			slog.Warn("giving up webhook delivery", slog.String("url", delivery.URL), slog.String("event", event),
+				slog.Int("attempts", attempt), slog.Any("error", err))
+			return
+		}
+		select {
+		case <-time.After(backoff):
+		case <-n.ctx.Done():
+			return
+		}
+		backoff = min(2*backoff, n.opts.MaxBackoff)
+	}
+}
+
+// post sends the notification once, returning the status code of the response, or 0 when there's none
+func (n *Notifier) post(u, event, id string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(n.ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return 0, errors.New("invalid webhook url")
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(DeliveryHeader, id)
+	req.Header.Set(TimestampHeader, timestamp)
+	if len(n.opts.Key) > 0 {
+		req.Header.Set(SignatureHeader, Signature(n.opts.Key, timestamp, body))
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		// the url is left out of the error, since it may hold a token
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode/100 != 2 {
+		return resp.StatusCode, fmt.Errorf("unexpected response: %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryable returns true if the attempt failed with a network error, with code 0, or a response which may
+// succeed later
+func retryable(code int) bool {
+	return code == 0 || code >= 500 || code == http.StatusRequestTimeout || code == http.StatusTooManyRequests
+}
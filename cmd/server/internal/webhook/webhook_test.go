@@ -0,0 +1,205 @@
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder keeps the deliveries recorded by the notifier, and signals once the last one is recorded
+type recorder struct {
+	mu         sync.Mutex
+	deliveries []Delivery
+	done       chan struct{}
+	last       func(Delivery) bool
+}
+
+func newRecorder(last func(Delivery) bool) *recorder {
+	return &recorder{done: make(chan struct{}), last: last}
+}
+
+func (r *recorder) record(delivery Delivery) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deliveries = append(r.deliveries, delivery)
+	if r.last(delivery) {
+		close(r.done)
+	}
+}
+
+// wait waits for the last delivery, and returns all of them once the notifier is closed
+func (r *recorder) wait(t *testing.T, n *Notifier) []Delivery {
+	select {
+	case <-r.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the deliveries")
+	}
+	n.Close()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.deliveries
+}
+
+func TestNotifyRetriesAndSigns(t *testing.T) {
+	key := []byte("webhook-key")
+	body := []byte(`{"event":"job.completed"}`)
+	var mu sync.Mutex
+	var ids []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ := io.ReadAll(r.Body)
+		if string(received) != string(body) || r.Header.Get(EventHeader) != "job.completed" {
+			t.Errorf("unexpected notification %s for event %s", received, r.Header.Get(EventHeader))
+		}
+		expected := Signature(key, r.Header.Get(TimestampHeader), received)
+		if r.Header.Get(SignatureHeader) != expected {
+			t.Errorf("unexpected signature %s, expected %s", r.Header.Get(SignatureHeader), expected)
+		}
+		mu.Lock()
+		ids = append(ids, r.Header.Get(DeliveryHeader))
+		attempt := len(ids)
+		mu.Unlock()
+		if attempt < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	n := NewNotifier(Options{Key: key, MaxAttempts: 5, MinBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond, AllowPrivate: true})
+	r := newRecorder(func(d Delivery) bool { return d.Error == "" })
+	n.Notify([]string{server.URL + "/hooks/secret-token"}, "job.completed", body, r.record)
+
+	deliveries := r.wait(t, n)
+	if len(deliveries) != 3 {
+		t.Fatalf("unexpected deliveries: %+v", deliveries)
+	}
+	for i, d := range deliveries {
+		if d.Attempt != i+1 || d.URL != server.URL || strings.Contains(d.Error, "secret-token") {
+			t.Fatalf("unexpected delivery: %+v", d)
+		}
+	}
+	if deliveries[0].StatusCode != http.StatusServiceUnavailable || deliveries[2].StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status codes: %+v", deliveries)
+	}
+	if ids[0] == "" || ids[0] != ids[1] || ids[1] != ids[2] {
+		t.Fatalf("the attempts should share the delivery id: %v", ids)
+	}
+}
+
+func TestNotifyGivesUp(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		attempts int
+	}{
+		{name: "client error is not retried", status: http.StatusBadRequest, attempts: 1},
+		{name: "server error is retried until the attempts run out", status: http.StatusInternalServerError, attempts: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get(SignatureHeader) != "" {
+					t.Errorf("unexpected signature without a key")
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			n := NewNotifier(Options{MaxAttempts: 3, MinBackoff: time.Millisecond, AllowPrivate: true})
+			attempts := tt.attempts
+			r := newRecorder(func(d Delivery) bool { return d.Attempt == attempts })
+			n.Notify([]string{server.URL}, "job.errored", []byte("{}"), r.record)
+
+			deliveries := r.wait(t, n)
+			if len(deliveries) != tt.attempts {
+				t.Fatalf("unexpected deliveries: %+v", deliveries)
+			}
+			for _, d := range deliveries {
+				if d.StatusCode != tt.status || d.Error == "" {
+					t.Fatalf("unexpected delivery: %+v", d)
+				}
+			}
+		})
+	}
+}
+
+func TestNotifyRefusesPrivateAddresses(t *testing.T) {
+	called := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called <- struct{}{}
+	}))
+	defer server.Close()
+
+	n := NewNotifier(Options{MaxAttempts: 3, MinBackoff: time.Millisecond})
+	r := newRecorder(func(Delivery) bool { return true })
+	n.Notify([]string{server.URL}, "job.completed", []byte("{}"), r.record)
+
+	deliveries := r.wait(t, n)
+	if len(deliveries) != 1 || deliveries[0].StatusCode != 0 || !strings.Contains(deliveries[0].Error, "private") {
+		t.Fatalf("unexpected deliveries: %+v", deliveries)
+	}
+	select {
+	case <-called:
+		t.Fatal("the webhook on the loopback address was called")
+	default:
+	}
+}
+
+func TestNotifyDoesNotFollowRedirects(t *testing.T) {
+	called := make(chan struct{}, 1)
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called <- struct{}{}
+	}))
+	defer target.Close()
+	server := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer server.Close()
+
+	n := NewNotifier(Options{MaxAttempts: 3, MinBackoff: time.Millisecond, AllowPrivate: true})
+	r := newRecorder(func(Delivery) bool { return true })
+	n.Notify([]string{server.URL}, "job.completed", []byte("{}"), r.record)
+
+	deliveries := r.wait(t, n)
+	if len(deliveries) != 1 || deliveries[0].StatusCode != http.StatusTemporaryRedirect {
+		t.Fatalf("unexpected deliveries: %+v", deliveries)
+	}
+	select {
+	case <-called:
+		t.Fatal("the redirect was followed")
+	default:
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	tests := []struct {
+		url          string
+		allowPrivate bool
+		valid        bool
+	}{
+		{url: "https://hooks.example.com/token", valid: true},
+		{url: "http://93.184.215.14:8080/hook", valid: true},
+		{url: "ftp://hooks.example.com", valid: false},
+		{url: "https:///hook", valid: false},
+		{url: "http://localhost:9000/hook", valid: false},
+		{url: "http://127.0.0.1/hook", valid: false},
+		{url: "http://10.1.2.3/hook", valid: false},
+		{url: "http://169.254.169.254/latest/meta-data", valid: false},
+		{url: "http://100.100.100.200/latest/meta-data", valid: false},
+		{url: "http://[::1]/hook", valid: false},
+		{url: "http://[::ffff:127.0.0.1]/hook", valid: false},
+		{url: "http://[fd00:ec2::254]/hook", valid: false},
+		{url: "http://0.0.0.0/hook", valid: false},
+		{url: "http://127.0.0.1/hook", allowPrivate: true, valid: true},
+		{url: "http://localhost:9000/hook", allowPrivate: true, valid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := ValidateURL(tt.url, tt.allowPrivate)
+			if (err == nil) != tt.valid {
+				t.Fatalf("ValidateURL(%q, %t) returned %v", tt.url, tt.allowPrivate, err)
+			}
+		})
+	}
+}
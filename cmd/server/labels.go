@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const maxLabels = 32
+
+var (
+	// labelKeyPattern and labelValuePattern restrict the labels, so they can be written in a selector
+	labelKeyPattern   = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._/-]{0,62})$`)
+	labelValuePattern = regexp.MustCompile(`^[A-Za-z0-9._/-]{0,63}$`)
+)
+
+// validateLabels returns an error if there are too many labels, or if any of them is malformed
+func validateLabels(labels map[string]string) error {
+	if len(labels) > maxLabels {
+		return fmt.Errorf("a job can have at most %d labels", maxLabels)
+	}
+	for key, value := range labels {
+		if !labelKeyPattern.MatchString(key) {
+			return fmt.Errorf("invalid label key %q", key)
+		}
+		if !labelValuePattern.MatchString(value) {
+			return fmt.Errorf("invalid value %q for label %s", value, key)
+		}
+	}
+	return nil
+}
+
+// requirement is a condition on a label. With op exists, the label must be set, or must not be set when
+// negated. With op equals, the label must be set to value, or must not be set to it when negated.
+type requirement struct {
+	key     string
+	value   string
+	equals  bool
+	negated bool
+}
+
+// selector is a list of requirements, which must all match
+type selector []requirement
+
+// parseSelector parses a list of requirements separated by commas: key=value, key!=value, key or !key
+func parseSelector(text string) (selector, error) {
+	var sel selector
+	for _, part := range strings.Split(text, ",") {
+		part = strings.TrimSpace(part)
+		var req requirement
+		switch {
+		case strings.Contains(part, "!="):
+			req.key, req.value, _ = strings.Cut(part, "!=")
+			req.equals, req.negated = true, true
+		case strings.Contains(part, "="):
+			req.key, req.value, _ = strings.Cut(part, "=")
+			req.equals = true
+		case strings.HasPrefix(part, "!"):
+			req.key = part[1:]
+			req.negated = true
+		default:
+			req.key = part
+		}
+		req.key, req.value = strings.TrimSpace(req.key), strings.TrimSpace(req.value)
+		if !labelKeyPattern.MatchString(req.key) || !labelValuePattern.MatchString(req.value) {
+			return nil, fmt.Errorf("invalid label selector requirement %q", part)
+		}
+		sel = append(sel, req)
+	}
+	return sel, nil
+}
+
+// parseOptionalSelector parses the selector, which matches every job when empty
+func parseOptionalSelector(text string) (selector, error) {
+	if text == "" {
+		return nil, nil
+	}
+	return parseSelector(text)
+}
+
+// matches returns true if the labels match all the requirements
+func (sel selector) matches(labels map[string]string) bool {
+	for _, req := range sel {
+		value, ok := labels[req.key]
+		match := ok
+		if req.equals {
+			match = ok && value == req.value
+		}
+		if match == req.negated {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mhsantos/rlcp/cmd/internal/pb"
+	"github.com/mhsantos/rlcp/cmd/server/internal/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// listKey is the position of a job in a sorted list: the value it's sorted by, a time or a text, and its id
+// to break the ties
+type listKey struct {
+	Time int64  `json:"t,omitempty"`
+	Text string `json:"s,omitempty"`
+	Id   string `json:"id"`
+}
+
+// pageToken is the position where the next page of a list starts, after the key of the last job returned.
+// It's sent to the client encoded as base64, and is only valid for the same sorting.
+type pageToken struct {
+	SortBy    pb.ListJobsRequest_SortBy `json:"sort"`
+	Ascending bool                      `json:"asc"`
+	After     listKey                   `json:"after"`
+}
+
+func (s *server) ListJobs(ctx context.Context, req *pb.ListJobsRequest) (*pb.ListJobsResponse, error) {
+	userId, err := s.authorize(ctx, storage.Status)
+	if err != nil {
+		return nil, err
+	}
+	// users other than admins only list their own jobs
+	owner := req.Owner
+	if !s.db.Authorized(userId, storage.ListAll) {
+		email := getRequesterEmail(ctx)
+		if owner != "" && owner != email {
+			return nil, status.Errorf(codes.PermissionDenied, "only admins can list the jobs from other users")
+		}
+		owner = email
+	}
+
+	if req.PageSize < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "page size must not be negative")
+	}
+	pageSize := int(req.PageSize)
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+	pageSize = min(pageSize, maxPageSize)
+	sel, err := parseOptionalSelector(req.LabelSelector)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	var after *listKey
+	if req.PageToken != "" {
+		token, err := decodePageToken(req.PageToken)
+		if err != nil || token.SortBy != req.SortBy || token.Ascending != req.Ascending {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid page token")
+		}
+		after = &token.After
+	}
+
+	type entry struct {
+		job *storage.Job
+		key listKey
+	}
+	var entries []entry
+	for _, job := range s.db.ListJobs() {
+		if matchJob(job, req, owner) && sel.matches(job.Labels) {
+			entries = append(entries, entry{job, sortKey(job, req.SortBy)})
+		}
+	}
+	compare := func(a, b listKey) int {
+		c := cmp.Or(cmp.Compare(a.Time, b.Time), strings.Compare(a.Text, b.Text), strings.Compare(a.Id, b.Id))
+		if !req.Ascending {
+			return -c
+		}
+		return c
+	}
+	slices.SortFunc(entries, func(a, b entry) int {
+		return compare(a.key, b.key)
+	})
+
+	start := 0
+	if after != nil {
+		start, _ = slices.BinarySearchFunc(entries, *after, func(e entry, key listKey) int {
+			return compare(e.key, key)
+		})
+		// the last job returned may have been removed in the meantime
+		if start < len(entries) && entries[start].key == *after {
+			start++
+		}
+	}
+	end := min(start+pageSize, len(entries))
+	resp := &pb.ListJobsResponse{}
+	for _, e := range entries[start:end] {
+		resp.Jobs = append(resp.Jobs, jobDetails(e.job))
+	}
+	if end < len(entries) {
+		resp.NextPageToken = encodePageToken(pageToken{
+			SortBy:    req.SortBy,
+			Ascending: req.Ascending,
+			After:     entries[end-1].key,
+		})
+	}
+	return resp, nil
+}
+
+// matchJob returns true if the job matches all the filters in the request, and is owned by owner, when set
+func matchJob(job *storage.Job, req *pb.ListJobsRequest, owner string) bool {
+	if owner != "" && job.User != owner {
+		return false
+	}
+	if len(req.Statuses) > 0 && !slices.Contains(req.Statuses, pb.JobDetails_Status(job.Status())) {
+		return false
+	}
+	if req.Since != nil && job.SubmitTime.Before(req.Since.AsTime()) {
+		return false
+	}
+	if req.Until != nil && job.SubmitTime.After(req.Until.AsTime()) {
+		return false
+	}
+	return req.Command == "" || strings.Contains(commandLine(job), req.Command)
+}
+
+// commandLine returns the command line run by the job, or the interpreter and the arguments for a script
+func commandLine(job *storage.Job) string {
+	command := job.Command
+	if command == "" {
+		command = job.Interpreter
+	}
+	return strings.Join(append([]string{command}, job.Args...), " ")
+}
+
+// sortKey returns the key the job is sorted by
+func sortKey(job *storage.Job, sortBy pb.ListJobsRequest_SortBy) listKey {
+	key := listKey{Id: job.Id.String()}
+	switch sortBy {
+	case pb.ListJobsRequest_START_TIME:
+		key.Time = unixNano(job.StartTime)
+	case pb.ListJobsRequest_END_TIME:
+		key.Time = unixNano(job.EndTime())
+	case pb.ListJobsRequest_OWNER:
+		key.Text = job.User
+	case pb.ListJobsRequest_COMMAND:
+		key.Text = commandLine(job)
+	default:
+		key.Time = unixNano(job.SubmitTime)
+	}
+	return key
+}
+
+// unixNano returns the time in nanoseconds, or 0 when it's not set, so the jobs without it are sorted together
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func encodePageToken(token pageToken) string {
+	data, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodePageToken(value string) (pageToken, error) {
+	var token pageToken
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return token, err
+	}
+	err = json.Unmarshal(data, &token)
+	return token, err
+}
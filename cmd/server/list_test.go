@@ -0,0 +1,133 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/mhsantos/rlcp/cmd/internal/pb"
+	"github.com/mhsantos/rlcp/cmd/server/internal/storage"
+)
+
+// addListJobs saves the jobs listed in the tests, submitted a minute apart in the order of their ids
+func addListJobs(t *testing.T, s *server) time.Time {
+	t.Helper()
+	start := time.Now().Add(-time.Hour)
+	jobs := []struct {
+		user    string
+		command string
+		labels  map[string]string
+		status  storage.JobStatus
+	}{
+		{adminEmail, "make", map[string]string{"deploy": "42", "role": "build"}, storage.Completed},
+		{writerEmail, "migrate", map[string]string{"deploy": "42", "role": "migrate"}, storage.Errored},
+		{readerEmail, "make", map[string]string{"deploy": "43", "role": "build"}, storage.Completed},
+		{readerEmail, "test", nil, storage.Stopped},
+	}
+	for i, j := range jobs {
+		job := addJob(t, s, j.user, "00000000-0000-0000-0000-00000000000"+string(rune('1'+i)), "")
+		job.Command = j.command
+		job.Labels = j.labels
+		job.SubmitTime = start.Add(time.Duration(i) * time.Minute)
+		job.SetStatus(j.status, "")
+	}
+	return start
+}
+
+// jobIds returns the last digit of the ids of the jobs listed
+func jobIds(jobs []*pb.JobDetails) string {
+	var ids []byte
+	for _, job := range jobs {
+		ids = append(ids, job.JobId[len(job.JobId)-1])
+	}
+	return string(ids)
+}
+
+func TestListJobs(t *testing.T) {
+	s := newTestServer(t)
+	start := addListJobs(t, s)
+
+	tests := []struct {
+		name     string
+		user     string
+		req      *pb.ListJobsRequest
+		expected string
+		code     codes.Code
+	}{
+		{name: "all jobs as admin", user: adminEmail, req: &pb.ListJobsRequest{}, expected: "4321"},
+		{name: "ascending", user: adminEmail, req: &pb.ListJobsRequest{Ascending: true}, expected: "1234"},
+		{name: "own jobs only", user: readerEmail, req: &pb.ListJobsRequest{}, expected: "43"},
+		{name: "jobs from other users as reader", user: readerEmail, req: &pb.ListJobsRequest{Owner: adminEmail},
+			code: codes.PermissionDenied},
+		{name: "jobs from other users as writer", user: writerEmail, req: &pb.ListJobsRequest{Owner: adminEmail},
+			code: codes.PermissionDenied},
+		{name: "by owner", user: adminEmail, req: &pb.ListJobsRequest{Owner: readerEmail}, expected: "43"},
+		{name: "by label", user: adminEmail, req: &pb.ListJobsRequest{LabelSelector: "deploy=42"}, expected: "21"},
+		{name: "by labels", user: adminEmail, req: &pb.ListJobsRequest{LabelSelector: "role=build,deploy!=42"},
+			expected: "3"},
+		{name: "by label set", user: adminEmail, req: &pb.ListJobsRequest{LabelSelector: "!deploy"}, expected: "4"},
+		{name: "by label as reader", user: readerEmail, req: &pb.ListJobsRequest{LabelSelector: "role=build"},
+			expected: "3"},
+		{name: "invalid selector", user: adminEmail, req: &pb.ListJobsRequest{LabelSelector: "deploy=4 2"},
+			code: codes.InvalidArgument},
+		{name: "by status", user: adminEmail, req: &pb.ListJobsRequest{
+			Statuses: []pb.JobDetails_Status{pb.JobDetails_ERRORED, pb.JobDetails_STOPPED}}, expected: "42"},
+		{name: "by command", user: adminEmail, req: &pb.ListJobsRequest{Command: "mak"}, expected: "31"},
+		{name: "by time", user: adminEmail, req: &pb.ListJobsRequest{
+			Since: timestamppb.New(start.Add(time.Minute)), Until: timestamppb.New(start.Add(2 * time.Minute))},
+			expected: "32"},
+		{name: "sorted by owner", user: adminEmail, req: &pb.ListJobsRequest{SortBy: pb.ListJobsRequest_OWNER,
+			Ascending: true}, expected: "1342"},
+		{name: "negative page size", user: adminEmail, req: &pb.ListJobsRequest{PageSize: -1},
+			code: codes.InvalidArgument},
+		{name: "invalid page token", user: adminEmail, req: &pb.ListJobsRequest{PageToken: "x"},
+			code: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.ListJobs(userContext(tt.user), tt.req)
+			if status.Code(err) != tt.code {
+				t.Fatalf("ListJobs returned %v, expected code %s", err, tt.code)
+			}
+			if got := jobIds(resp.GetJobs()); got != tt.expected {
+				t.Fatalf("unexpected jobs listed: %s, expected %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestListJobsPages(t *testing.T) {
+	s := newTestServer(t)
+	addListJobs(t, s)
+	ctx := userContext(adminEmail)
+
+	var pages []string
+	req := &pb.ListJobsRequest{PageSize: 3, Ascending: true}
+	for {
+		resp, err := s.ListJobs(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, jobIds(resp.Jobs))
+		if resp.NextPageToken == "" {
+			break
+		}
+		req.PageToken = resp.NextPageToken
+		if len(pages) == 1 {
+			// the page token is only valid for the same sorting
+			_, err := s.ListJobs(ctx, &pb.ListJobsRequest{PageSize: 3, PageToken: req.PageToken})
+			if status.Code(err) != codes.InvalidArgument {
+				t.Fatalf("a page token was used with another sorting: %v", err)
+			}
+			// a job removed after its page was returned doesn't change the next page
+			s.db.DeleteJob("00000000-0000-0000-0000-000000000003")
+		}
+	}
+	if expected := []string{"123", "4"}; !cmp.Equal(pages, expected) {
+		t.Fatalf("unexpected pages: %v", cmp.Diff(expected, pages))
+	}
+}
@@ -5,21 +5,48 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
+	"io"
 	"log/slog"
 	"net"
 	"os"
+	"os/signal"
+	"slices"
+	"syscall"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/peer"
 
 	"github.com/mhsantos/rlcp/cmd/internal/pb"
+	"github.com/mhsantos/rlcp/cmd/server/internal/executor"
+	"github.com/mhsantos/rlcp/cmd/server/internal/sink"
 	"github.com/mhsantos/rlcp/cmd/server/internal/storage"
 )
 
 func main() {
 	slog.SetLogLoggerLevel(slog.LevelDebug)
 	slog.Debug("starting server")
+
+	if len(os.Args) > 1 && os.Args[1] == "rotate-keys" {
+		if err := rotateKeys(os.Args[2:]); err != nil {
+			slog.Error("error rotating the master key", slog.Any("error", err))
+			os.Exit(1)
+		}
+		return
+	}
+
+	cfg, err := parseConfig(os.Args[1:])
+	if err != nil {
+		slog.Error("invalid configuration", slog.Any("error", err))
+		os.Exit(2)
+	}
+
+	if len(cfg.sinks) > 0 {
+		forwarder := sink.NewForwarder(cfg.sinks)
+		defer forwarder.Close()
+		cfg.log.Sink = forwarder
+	}
+
 	// Listen for incoming connections on port 8080
 	ln, err := net.Listen("tcp", ":8087")
 	if err != nil {
@@ -28,7 +55,18 @@ func main() {
 	}
 
 	// db
-	storage := storage.NewMemStorage()
+	db, lost, err := openJobStorage(cfg)
+	if err != nil {
+		slog.Error("error opening the job storage", slog.Any("error", err))
+		os.Exit(1)
+	}
+
+	// the server stops on SIGINT or SIGTERM, and the jobs keep running until the process exits
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	collector := storage.NewCollector(db, cfg.retention, cfg.gcInterval)
+	go collector.Run(ctx)
 
 	tlsConfig, err := getTLSConfig()
 	if err != nil {
@@ -37,12 +75,43 @@ func main() {
 	}
 
 	s := grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsConfig)))
-	server := NewServer(storage)
+	server := NewServer(db, cfg)
 	pb.RegisterRemoteExecutorServer(s, server)
+	for _, job := range lost {
+		server.notifyEnd(job)
+	}
+	go func() {
+		<-ctx.Done()
+		slog.Info("stopping server")
+		s.Stop()
+	}()
 
 	if err := s.Serve(ln); err != nil {
 		slog.Error("failed to serve grpc server", slog.Any("error", err))
 	}
+	server.closeWebhooks()
+	// the bolt storage writes the final records of the jobs which finished while the server was stopping
+	if closer, ok := db.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			slog.Error("error closing the job storage", slog.Any("error", err))
+		}
+	}
+}
+
+// openJobStorage returns the job storage selected by the configuration. The bolt storage restores the
+// jobs with the output store and master key configured for the output, and the jobs interrupted by the
+// restart are reconciled with their processes. Those jobs are returned, since they were lost.
+func openJobStorage(cfg config) (storage.JobStorage, []*storage.Job, error) {
+	if cfg.jobStore != "bolt" {
+		return storage.NewMemStorage(), nil, nil
+	}
+	db, err := storage.NewBoltStorage(cfg.jobDB, cfg.log)
+	if err != nil {
+		return nil, nil, err
+	}
+	lost := db.Interrupted()
+	executor.RecoverJobs(lost)
+	return db, lost, nil
 }
 
 func getTLSConfig() (*tls.Config, error) {
@@ -87,9 +156,10 @@ func authorizeClient(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) er
 	}
 
 	// Check the Common Name
-	expectedCN := "marcel+client@email.com" // Replace with your expected CN
-	if clientCert.Subject.CommonName != expectedCN {
-		return fmt.Errorf("invalid client certificate CN: got %s, want %s", clientCert.Subject.CommonName, expectedCN)
+	expectedCNs := []string{"marcel+client@email.com", "marcel+admin@email.com"} // Replace with your expected CNs
+	if !slices.Contains(expectedCNs, clientCert.Subject.CommonName) {
+		return fmt.Errorf("invalid client certificate CN: got %s, want one of %v", clientCert.Subject.CommonName,
+			expectedCNs)
 	}
 
 	fmt.Println("Client certificate CN verified:", clientCert.Subject.CommonName)
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/mhsantos/rlcp/cmd/internal/pb"
+	"github.com/mhsantos/rlcp/cmd/server/internal/executor"
+	"github.com/mhsantos/rlcp/cmd/server/internal/storage"
+)
+
+func TestOpenJobStorageRecoversLostJobs(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config{
+		jobStore: "bolt",
+		jobDB:    filepath.Join(dir, "jobs.db"),
+		log:      storage.LogOptions{Store: storage.NewLocalStore(filepath.Join(dir, "output"))},
+	}
+
+	// the previous server saved the job while its process, leading its own group, was running
+	cmd := exec.Command("sleep", "60")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		cmd.Wait()
+	})
+	db, err := storage.NewBoltStorage(cfg.jobDB, cfg.log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := storage.NewJob(cfg.log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.User = adminEmail
+	job.Command = "sleep"
+	job.SetProcess(cmd.Process.Pid, executor.ProcessStart(cmd.Process.Pid))
+	jobId := job.Id.String()
+	db.SaveJob(jobId, job)
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted, lost, err := openJobStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lost) != 1 || lost[0].Id != job.Id {
+		t.Fatalf("unexpected lost jobs: %v", lost)
+	}
+	if err := cmd.Wait(); err == nil {
+		t.Fatal("the process of the lost job was not stopped")
+	}
+	s := NewServer(restarted, cfg)
+	t.Cleanup(s.closeWebhooks)
+	details, err := s.GetStatus(userContext(adminEmail), &pb.GetRequest{JobId: jobId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.Status != pb.JobDetails_LOST || details.EndTime == nil ||
+		!strings.Contains(details.StatusMessage, "killed") {
+		t.Fatalf("unexpected lost job: status %s, message %q", details.Status, details.StatusMessage)
+	}
+	if err := restarted.(*storage.BoltStorage).Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the lost job is final, so it's not recovered again on the next start
+	restarted, lost, err = openJobStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restarted.(*storage.BoltStorage).Close()
+	if len(lost) != 0 {
+		t.Fatalf("the lost job was recovered again: %v", lost)
+	}
+	restored, ok := restarted.GetJob(jobId)
+	if !ok || restored.Status() != storage.Lost || restored.StatusMessage() != details.StatusMessage {
+		t.Fatalf("unexpected job restored: %t", ok)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mhsantos/rlcp/cmd/server/internal/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxCandidates limits the jobs listed in the error for an ambiguous id prefix
+const maxCandidates = 10
+
+// jobNamePattern restricts the job names, which start with a letter so they are easier to tell apart from ids
+var jobNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9._-]{0,62}$`)
+
+// validateJobName returns an error if the name is malformed
+func validateJobName(name string) error {
+	if !jobNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid job name %q: it must start with a letter, followed by up to 62 letters, digits, ., _ or -", name)
+	}
+	if _, err := uuid.Parse(name); err == nil {
+		return fmt.Errorf("invalid job name %q: it must not be a job id", name)
+	}
+	return nil
+}
+
+// nameTaken returns true if a job in the storage already has the name
+func (s *server) nameTaken(name string) bool {
+	return slices.ContainsFunc(s.db.ListJobs(), func(job *storage.Job) bool {
+		return job.Name == name
+	})
+}
+
+// jobOwner returns the email of the caller, when it's a user other than an admin, who only finds its own jobs,
+// or "" for the admins, who find the jobs from every user
+func (s *server) jobOwner(ctx context.Context) string {
+	email := getRequesterEmail(ctx)
+	if userId, ok := s.db.GetUserId(email); ok && s.db.Authorized(userId, storage.ListAll) {
+		return ""
+	}
+	return email
+}
+
+// findJob returns the job referenced by ref, which is the job id, its name, or a prefix of the id matching
+// a single job. The id is tried first, then the name and then the prefix. Users other than admins only match
+// their own jobs, and the jobs from other users are not found, as if they didn't exist.
+func (s *server) findJob(ctx context.Context, ref string) (*storage.Job, error) {
+	owner := s.jobOwner(ctx)
+	if job, ok := s.db.GetJob(ref); ok && (owner == "" || job.User == owner) {
+		return job, nil
+	}
+	if ref == "" {
+		return nil, status.Errorf(codes.NotFound, "Could not find a job for the id provided")
+	}
+
+	jobs := s.db.ListJobs()
+	if owner != "" {
+		jobs = slices.DeleteFunc(jobs, func(job *storage.Job) bool { return job.User != owner })
+	}
+	if i := slices.IndexFunc(jobs, func(job *storage.Job) bool { return job.Name == ref }); i >= 0 {
+		return jobs[i], nil
+	}
+	var candidates []string
+	var found *storage.Job
+	prefix := strings.ToLower(ref)
+	for _, job := range jobs {
+		if strings.HasPrefix(job.Id.String(), prefix) {
+			candidates = append(candidates, job.Id.String())
+			found = job
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return nil, status.Errorf(codes.NotFound, "Could not find a job for the id provided")
+	case 1:
+		return found, nil
+	}
+	slices.Sort(candidates)
+	listed := candidates[:min(len(candidates), maxCandidates)]
+	more := ""
+	if len(candidates) > len(listed) {
+		more = fmt.Sprintf(" and %d more", len(candidates)-len(listed))
+	}
+	return nil, status.Errorf(codes.InvalidArgument, "the job id prefix %s is ambiguous, it matches %s%s",
+		ref, strings.Join(listed, ", "), more)
+}
+
+// saveNewJob saves the job, after checking its name, when set, is not taken by another job
+func (s *server) saveNewJob(job *storage.Job) error {
+	if job.Name == "" {
+		s.db.SaveJob(job.Id.String(), job)
+		return nil
+	}
+	s.names.Lock()
+	defer s.names.Unlock()
+	if s.nameTaken(job.Name) {
+		return status.Errorf(codes.AlreadyExists, "there is already a job named %s", job.Name)
+	}
+	s.db.SaveJob(job.Id.String(), job)
+	return nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/mhsantos/rlcp/cmd/server/internal/storage"
+)
+
+const (
+	adminEmail  = "marcel+admin@email.com"
+	writerEmail = "marcel+client@email.com"
+	readerEmail = "marcel+client2@email.com"
+)
+
+// userContext returns the context of a call made with the client certificate of the user
+func userContext(email string) context.Context {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: email}}
+	info := credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}}
+	return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: info})
+}
+
+// newTestServer returns a server keeping the jobs and their output in memory, and the job workspaces in a
+// temporary directory
+func newTestServer(t *testing.T) *server {
+	t.Helper()
+	s := NewServer(storage.NewMemStorage(), config{
+		log:           storage.LogOptions{Store: storage.NewMemoryStore()},
+		workspaceRoot: t.TempDir(),
+	})
+	t.Cleanup(s.closeWebhooks)
+	return s
+}
+
+// addJob saves a finished job from the user, with the id and name
+func addJob(t *testing.T, s *server, user, id, name string) *storage.Job {
+	t.Helper()
+	job, err := storage.NewJob(s.cfg.log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.Id = uuid.MustParse(id)
+	job.User = user
+	job.Name = name
+	job.SetStatus(storage.Completed, "")
+	job.CloseOutput()
+	job.MarkExited(0)
+	if err := s.saveNewJob(job); err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func TestFindJob(t *testing.T) {
+	s := newTestServer(t)
+	build := addJob(t, s, adminEmail, "11111111-1111-4111-8111-111111111111", "build")
+	deploy := addJob(t, s, readerEmail, "12000000-0000-4000-8000-000000000000", "deploy")
+	addJob(t, s, readerEmail, "12100000-0000-4000-8000-000000000000", "")
+
+	tests := []struct {
+		name     string
+		user     string
+		ref      string
+		expected *storage.Job
+		code     codes.Code
+		message  string
+	}{
+		{name: "id", user: readerEmail, ref: deploy.Id.String(), expected: deploy},
+		{name: "name", user: readerEmail, ref: "deploy", expected: deploy},
+		{name: "prefix", user: readerEmail, ref: "120", expected: deploy},
+		{name: "long prefix", user: adminEmail, ref: "11111111-1111-4111-8", expected: build},
+		{name: "admin finds any name", user: adminEmail, ref: "deploy", expected: deploy},
+		{name: "admin finds any prefix", user: adminEmail, ref: "120", expected: deploy},
+		{name: "id from another user", user: readerEmail, ref: build.Id.String(), code: codes.NotFound},
+		{name: "admin finds any id", user: adminEmail, ref: deploy.Id.String(), expected: deploy},
+		{name: "name from another user", user: readerEmail, ref: "build", code: codes.NotFound},
+		{name: "prefix from another user", user: readerEmail, ref: "111", code: codes.NotFound},
+		{name: "unknown", user: adminEmail, ref: "nightly", code: codes.NotFound},
+		{name: "empty", user: adminEmail, ref: "", code: codes.NotFound},
+		{name: "ambiguous prefix lists own jobs", user: readerEmail, ref: "1", code: codes.InvalidArgument,
+			message: "matches 12000000-0000-4000-8000-000000000000, 12100000-0000-4000-8000-000000000000"},
+		{name: "ambiguous prefix for admins", user: adminEmail, ref: "1", code: codes.InvalidArgument,
+			message: "matches 11111111-1111-4111-8111-111111111111, 12000000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job, err := s.findJob(userContext(tt.user), tt.ref)
+			if status.Code(err) != tt.code {
+				t.Fatalf("findJob(%q) returned %v, expected code %s", tt.ref, err, tt.code)
+			}
+			if job != tt.expected {
+				t.Fatalf("findJob(%q) returned job %v", tt.ref, job)
+			}
+			if tt.message != "" && !strings.Contains(status.Convert(err).Message(), tt.message) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestSaveNewJobTakenName(t *testing.T) {
+	s := newTestServer(t)
+	addJob(t, s, adminEmail, "11111111-1111-4111-8111-111111111111", "build")
+	job, err := storage.NewJob(s.cfg.log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.Name = "build"
+	if err := s.saveNewJob(job); status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("saveNewJob returned %v", err)
+	}
+	if _, ok := s.db.GetJob(job.Id.String()); ok {
+		t.Fatal("the job with a taken name was saved")
+	}
+}
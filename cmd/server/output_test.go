@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/mhsantos/rlcp/cmd/internal/pb"
+	"github.com/mhsantos/rlcp/cmd/server/internal/storage"
+)
+
+// outputStream keeps the messages sent by GetOutput
+type outputStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent []*pb.JobOutput
+}
+
+func (o *outputStream) Context() context.Context { return o.ctx }
+
+func (o *outputStream) Send(out *pb.JobOutput) error {
+	o.sent = append(o.sent, out)
+	return nil
+}
+
+// addOutputJob saves a finished job from the admin, which wrote the lines to stdout, and the lines starting
+// with "error" to stderr. It returns the job and the time before each line was written.
+func addOutputJob(t *testing.T, s *server, lines ...string) (*storage.Job, []time.Time) {
+	t.Helper()
+	job, err := storage.NewJob(s.cfg.log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.User = adminEmail
+	var times []time.Time
+	for _, line := range lines {
+		// the lines are captured at different times
+		time.Sleep(5 * time.Millisecond)
+		times = append(times, time.Now())
+		stream := storage.Stdout
+		if strings.HasPrefix(line, "error") {
+			stream = storage.Stderr
+		}
+		if err := job.ProcessOutput(stream, []byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	job.SetStatus(storage.Completed, "")
+	job.CloseOutput()
+	job.MarkExited(0)
+	if err := s.saveNewJob(job); err != nil {
+		t.Fatal(err)
+	}
+	return job, times
+}
+
+func TestGetOutputChunks(t *testing.T) {
+	s := newTestServer(t)
+	job, times := addOutputJob(t, s, "first\n", "error one\n", "last\n")
+
+	stream := &outputStream{ctx: userContext(adminEmail)}
+	if err := s.GetOutput(&pb.GetRequest{JobId: job.Id.String(), Mode: pb.GetRequest_SNAPSHOT}, stream); err != nil {
+		t.Fatal(err)
+	}
+	if len(stream.sent) != 3 {
+		t.Fatalf("unexpected output sent: %v", stream.sent)
+	}
+	var offset int64
+	for i, out := range stream.sent {
+		expected := pb.JobOutput_STDOUT
+		if i == 1 {
+			expected = pb.JobOutput_STDERR
+		}
+		// each chunk is sent with the time it was captured and its stream, so they can be exported line by line
+		captured := out.Time.AsTime()
+		if out.Stream != expected || captured.Before(times[i]) || (i < 2 && !captured.Before(times[i+1])) {
+			t.Fatalf("chunk %d: stream %s captured at %s, expected %s after %s", i, out.Stream, captured, expected,
+				times[i])
+		}
+		if out.Offset != offset || out.NextOffset != offset+int64(len(out.Output)) {
+			t.Fatalf("chunk %d: offset %d and next offset %d, expected %d", i, out.Offset, out.NextOffset, offset)
+		}
+		offset = out.NextOffset
+	}
+
+	tests := []struct {
+		name     string
+		req      *pb.GetRequest
+		expected string
+		code     codes.Code
+	}{
+		{name: "resumed", req: &pb.GetRequest{Offset: 6}, expected: "error one\nlast\n"},
+		{name: "tail lines", req: &pb.GetRequest{TailLines: 2}, expected: "error one\nlast\n"},
+		{name: "tail bytes", req: &pb.GetRequest{TailBytes: 3}, expected: "st\n"},
+		{name: "offset past the end", req: &pb.GetRequest{Offset: 100}, code: codes.OutOfRange},
+		{name: "negative tail", req: &pb.GetRequest{TailLines: -1}, code: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.JobId = job.Id.String()
+			stream := &outputStream{ctx: userContext(adminEmail)}
+			if err := s.GetOutput(tt.req, stream); status.Code(err) != tt.code {
+				t.Fatalf("GetOutput returned %v, expected code %s", err, tt.code)
+			}
+			var out []byte
+			for _, sent := range stream.sent {
+				out = append(out, sent.Output...)
+			}
+			if string(out) != tt.expected {
+				t.Fatalf("unexpected output: %q, expected %q", out, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetOutputFilters(t *testing.T) {
+	s := newTestServer(t)
+	job, times := addOutputJob(t, s, "starting\n", "error: disk full\n", "retrying\n", "error: timeout\n", "done\n")
+
+	tests := []struct {
+		name     string
+		req      *pb.GetRequest
+		expected []string
+		code     codes.Code
+	}{
+		{name: "include", req: &pb.GetRequest{Include: "^error"},
+			expected: []string{"error: disk full\n", "error: timeout\n"}},
+		{name: "exclude", req: &pb.GetRequest{Exclude: "error|ing$"}, expected: []string{"done\n"}},
+		{name: "include and exclude", req: &pb.GetRequest{Include: "^error", Exclude: "disk"},
+			expected: []string{"error: timeout\n"}},
+		{name: "max lines", req: &pb.GetRequest{Include: "^error", MaxLines: 1}, expected: []string{"error: disk full\n"}},
+		{name: "time range", req: &pb.GetRequest{Since: timestamppb.New(times[1]), Until: timestamppb.New(times[3])},
+			expected: []string{"error: disk full\n", "retrying\n"}},
+		{name: "filter on the tail", req: &pb.GetRequest{Include: "^error", TailLines: 2},
+			expected: []string{"error: timeout\n"}},
+		{name: "lines without a filter", req: &pb.GetRequest{Lines: true, Offset: 26},
+			expected: []string{"retrying\n", "error: timeout\n", "done\n"}},
+		{name: "invalid expression", req: &pb.GetRequest{Include: "("}, code: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.JobId = job.Id.String()
+			tt.req.Mode = pb.GetRequest_SNAPSHOT
+			stream := &outputStream{ctx: userContext(adminEmail)}
+			if err := s.GetOutput(tt.req, stream); status.Code(err) != tt.code {
+				t.Fatalf("GetOutput returned %v, expected code %s", err, tt.code)
+			}
+			var lines []string
+			for _, sent := range stream.sent {
+				lines = append(lines, string(sent.Output))
+				// the lines are sent with their number, and the offset to resume after them
+				if sent.Line == 0 || sent.NextOffset != sent.Offset+int64(len(sent.Output)) {
+					t.Fatalf("line %q sent as line %d at offset %d, resuming at %d", sent.Output, sent.Line,
+						sent.Offset, sent.NextOffset)
+				}
+			}
+			if !cmp.Equal(lines, tt.expected) {
+				t.Fatalf("unexpected lines: %v", cmp.Diff(tt.expected, lines))
+			}
+		})
+	}
+}
+
+func TestGetOutputUntilInterleaved(t *testing.T) {
+	s := newTestServer(t)
+	// the stdout line started before until is completed after a stderr line captured past until
+	job, times := addOutputJob(t, s, "slow ", "error: late\n", "line\n", "next\n")
+
+	stream := &outputStream{ctx: userContext(adminEmail)}
+	req := &pb.GetRequest{JobId: job.Id.String(), Mode: pb.GetRequest_SNAPSHOT, Until: timestamppb.New(times[1])}
+	if err := s.GetOutput(req, stream); err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for _, sent := range stream.sent {
+		lines = append(lines, string(sent.Output))
+	}
+	if expected := []string{"slow line\n"}; !cmp.Equal(lines, expected) {
+		t.Fatalf("unexpected lines: %v", cmp.Diff(expected, lines))
+	}
+}
+
+func TestExecCommandOutputLimit(t *testing.T) {
+	tests := []struct {
+		name string
+		// serverMax is the output limit of the server, applied with the stop policy
+		serverMax  int64
+		max        int64
+		policy     pb.OutputPolicy
+		status     pb.JobDetails_Status
+		truncation pb.OutputPolicy
+		code       codes.Code
+	}{
+		{name: "stop", max: 16 * 1024, policy: pb.OutputPolicy_STOP, status: pb.JobDetails_STOPPED,
+			truncation: pb.OutputPolicy_STOP},
+		{name: "ring", max: 16 * 1024, policy: pb.OutputPolicy_RING, status: pb.JobDetails_COMPLETED,
+			truncation: pb.OutputPolicy_RING},
+		{name: "head and tail", max: 16 * 1024, policy: pb.OutputPolicy_HEAD_TAIL, status: pb.JobDetails_COMPLETED,
+			truncation: pb.OutputPolicy_HEAD_TAIL},
+		{name: "server limit", serverMax: 16 * 1024, status: pb.JobDetails_STOPPED, truncation: pb.OutputPolicy_STOP},
+		{name: "limit over the server limit", serverMax: 16 * 1024, max: 1024 * 1024, status: pb.JobDetails_STOPPED,
+			truncation: pb.OutputPolicy_STOP},
+		{name: "under the limit", max: 1024 * 1024, status: pb.JobDetails_COMPLETED},
+		{name: "negative limit", max: -1, code: codes.InvalidArgument},
+		{name: "invalid policy", policy: 9, code: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			s.cfg.log.MaxBytes = tt.serverMax
+			s.cfg.log.Policy = storage.StopJob
+			details, err := s.ExecCommand(userContext(adminEmail), &pb.CmdRequest{
+				Command:        "sh",
+				Arguments:      []string{"-c", "yes | head -c 100000"},
+				MaxOutputBytes: tt.max,
+				OutputPolicy:   tt.policy,
+			})
+			if status.Code(err) != tt.code {
+				t.Fatalf("ExecCommand returned %v, expected code %s", err, tt.code)
+			}
+			if err != nil {
+				return
+			}
+			job, _ := s.db.GetJob(details.JobId)
+			select {
+			case <-job.Exited():
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for the job to exit")
+			}
+
+			details, err = s.GetStatus(userContext(adminEmail), &pb.GetRequest{JobId: details.JobId})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if details.Status != tt.status || details.Truncation != tt.truncation {
+				t.Fatalf("unexpected job: status %s with truncation %s", details.Status, details.Truncation)
+			}
+			// the server limit is the smaller one, when set
+			limit := tt.max
+			if tt.serverMax > 0 {
+				limit = tt.serverMax
+			}
+			if tt.truncation != pb.OutputPolicy_DEFAULT && (details.DroppedBytes == 0 || details.StoredBytes > limit) {
+				t.Fatalf("%d bytes stored and %d dropped, with a limit of %d", details.StoredBytes,
+					details.DroppedBytes, limit)
+			}
+		})
+	}
+}
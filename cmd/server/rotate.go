@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"log/slog"
+
+	"github.com/mhsantos/rlcp/cmd/server/internal/storage"
+)
+
+// rotateKeys rewraps the keys encrypting the output files of the jobs with a new master key.
+// It should run while the server is stopped, which is then started with the new master key.
+func rotateKeys(args []string) error {
+	flags := flag.NewFlagSet("rotate-keys", flag.ContinueOnError)
+	var store storeFlags
+	store.register(flags)
+	oldPath := flags.String("master-key", "", "file with the current master key")
+	newPath := flags.String("new-master-key", "", "file with the new master key")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *oldPath == "" || *newPath == "" {
+		return errors.New("-master-key and -new-master-key are required")
+	}
+	oldKey, err := storage.LoadMasterKey(*oldPath)
+	if err != nil {
+		return err
+	}
+	newKey, err := storage.LoadMasterKey(*newPath)
+	if err != nil {
+		return err
+	}
+
+	outputStore, err := store.open()
+	if err != nil {
+		return err
+	}
+
+	rewrapped, err := storage.RewrapKeys(outputStore, oldKey, newKey)
+	if err != nil {
+		return err
+	}
+	slog.Info("rotated the master key", slog.Int("jobs", rewrapped))
+	return nil
+}
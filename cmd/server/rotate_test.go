@@ -0,0 +1,103 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mhsantos/rlcp/cmd/server/internal/storage"
+)
+
+// writeKey writes a random key with size bytes to a file in dir
+func writeKey(t *testing.T, dir, name string, size int) string {
+	t.Helper()
+	key := make([]byte, size)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, key, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRotateKeys(t *testing.T) {
+	dir := t.TempDir()
+	logDir := filepath.Join(dir, "logs")
+	oldPath := writeKey(t, dir, "old.key", storage.KeySize)
+	newPath := writeKey(t, dir, "new.key", storage.KeySize)
+	oldKey, err := storage.LoadMasterKey(oldPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := storage.LogOptions{Store: storage.NewLocalStore(logDir), MasterKey: oldKey}
+	job, err := storage.NewJob(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := job.ProcessOutput(storage.Stdout, []byte("encrypted output\n")); err != nil {
+		t.Fatal(err)
+	}
+	job.CloseOutput()
+	job.MarkExited(0)
+	if err := job.Persist(); err != nil {
+		t.Fatal(err)
+	}
+	rec := job.Record()
+
+	tests := []struct {
+		name    string
+		args    []string
+		message string
+	}{
+		{name: "missing new key", args: []string{"-log-dir", logDir, "-master-key", oldPath},
+			message: "are required"},
+		{name: "invalid key size", args: []string{"-log-dir", logDir, "-master-key", oldPath,
+			"-new-master-key", writeKey(t, dir, "short.key", 16)}, message: "must have 32 bytes"},
+		{name: "wrong current key", args: []string{"-log-dir", logDir, "-master-key",
+			writeKey(t, dir, "other.key", storage.KeySize), "-new-master-key", newPath}, message: "could not unwrap"},
+		{name: "rotation", args: []string{"-log-dir", logDir, "-master-key", oldPath, "-new-master-key", newPath}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := rotateKeys(tt.args)
+			if tt.message == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.message != "" && (err == nil || !strings.Contains(err.Error(), tt.message)) {
+				t.Fatalf("rotateKeys returned %v, expected %q", err, tt.message)
+			}
+		})
+	}
+
+	newKey, err := storage.LoadMasterKey(newPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := storage.RestoreJob(rec, storage.LogOptions{Store: storage.NewLocalStore(logDir), MasterKey: newKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader := restored.NewReader(0, false)
+	defer reader.Close()
+	var out []byte
+	for {
+		chunk, err := reader.Next(t.Context())
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, chunk.Data...)
+	}
+	if string(out) != "encrypted output\n" {
+		t.Fatalf("unexpected output after the rotation: %q", out)
+	}
+}
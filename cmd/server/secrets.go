@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"regexp"
+	"slices"
+
+	"github.com/mhsantos/rlcp/cmd/internal/pb"
+	"github.com/mhsantos/rlcp/cmd/server/internal/secrets"
+	"github.com/mhsantos/rlcp/cmd/server/internal/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// envNamePattern restricts the names of the environment variables the secrets are injected as
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func (s *server) SetSecret(ctx context.Context, req *pb.SecretRequest) (*emptypb.Empty, error) {
+	if err := s.authorizeSecrets(ctx); err != nil {
+		return nil, err
+	}
+	if err := secrets.ValidName(req.Name); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if len(req.Value) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "the secret value must not be empty")
+	}
+	for _, user := range req.Users {
+		if _, ok := s.db.GetUserId(user); !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown user %s", user)
+		}
+	}
+
+	if err := s.cfg.secrets.Set(req.Name, req.Value, req.Users); err != nil {
+		slog.Error("error setting secret", slog.String("name", req.Name), slog.Any("error", err))
+		return nil, status.Errorf(codes.Internal, "Error storing the secret: %v", err)
+	}
+	slog.Info("secret set", slog.String("name", req.Name), slog.Any("users", req.Users))
+	return &emptypb.Empty{}, nil
+}
+
+func (s *server) ListSecrets(ctx context.Context, _ *emptypb.Empty) (*pb.SecretList, error) {
+	if err := s.authorizeSecrets(ctx); err != nil {
+		return nil, err
+	}
+	list := &pb.SecretList{}
+	for _, info := range s.cfg.secrets.List() {
+		list.Secrets = append(list.Secrets, &pb.SecretInfo{
+			Name:    info.Name,
+			Users:   info.Users,
+			Updated: timestamppb.New(info.Updated),
+		})
+	}
+	return list, nil
+}
+
+func (s *server) DeleteSecret(ctx context.Context, req *pb.SecretRequest) (*emptypb.Empty, error) {
+	if err := s.authorizeSecrets(ctx); err != nil {
+		return nil, err
+	}
+	err := s.cfg.secrets.Delete(req.Name)
+	if errors.Is(err, secrets.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "Could not find a secret named %s", req.Name)
+	}
+	if err != nil {
+		slog.Error("error deleting secret", slog.String("name", req.Name), slog.Any("error", err))
+		return nil, status.Errorf(codes.Internal, "Error deleting the secret: %v", err)
+	}
+	slog.Info("secret deleted", slog.String("name", req.Name))
+	return &emptypb.Empty{}, nil
+}
+
+// authorizeSecrets checks if the user is allowed to manage the secrets, and if the secret store is enabled
+func (s *server) authorizeSecrets(ctx context.Context) error {
+	if _, err := s.authorize(ctx, storage.ManageSecrets); err != nil {
+		return err
+	}
+	if s.cfg.secrets == nil {
+		return status.Errorf(codes.FailedPrecondition, "the secret store is not enabled on the server")
+	}
+	return nil
+}
+
+// secretEnv resolves the secrets referenced by a job, mapped by the environment variable they are injected as.
+// It returns the variables in the form key=value, and the secret values to be masked in the job output.
+// The requester must be in the list of users allowed to use every secret.
+func (s *server) secretEnv(ctx context.Context, secretEnv map[string]string) ([]string, []string, error) {
+	if len(secretEnv) == 0 {
+		return nil, nil, nil
+	}
+	if s.cfg.secrets == nil {
+		return nil, nil, status.Errorf(codes.FailedPrecondition, "the secret store is not enabled on the server")
+	}
+	email := getRequesterEmail(ctx)
+
+	names := slices.Sorted(maps.Keys(secretEnv))
+	env := make([]string, 0, len(names))
+	values := make([]string, 0, len(names))
+	for _, name := range names {
+		if !envNamePattern.MatchString(name) {
+			return nil, nil, status.Errorf(codes.InvalidArgument, "invalid environment variable name %q", name)
+		}
+		secret := secretEnv[name]
+		value, err := s.cfg.secrets.Resolve(email, secret)
+		switch {
+		case errors.Is(err, secrets.ErrNotFound):
+			return nil, nil, status.Errorf(codes.NotFound, "Could not find a secret named %s", secret)
+		case errors.Is(err, secrets.ErrNotAllowed):
+			slog.Error("secret not allowed", slog.String("email", email), slog.String("secret", secret))
+			return nil, nil, status.Errorf(codes.PermissionDenied, "user not allowed to use the secret %s", secret)
+		case err != nil:
+			return nil, nil, status.Errorf(codes.Internal, "Error reading the secret: %v", err)
+		}
+		env = append(env, name+"="+value)
+		values = append(values, value)
+	}
+	return env, values, nil
+}
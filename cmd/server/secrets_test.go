@@ -0,0 +1,177 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mhsantos/rlcp/cmd/internal/pb"
+	"github.com/mhsantos/rlcp/cmd/server/internal/secrets"
+)
+
+// newSecretsServer returns a test server with a secret store holding the token, which only the admin can use
+func newSecretsServer(t *testing.T) *server {
+	t.Helper()
+	s := newTestServer(t)
+	dir := t.TempDir()
+	store, err := secrets.Open(filepath.Join(dir, "secrets"), writeKey(t, dir, "secrets.key", secrets.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("api-token", "s3cr3t-t0k3n", []string{adminEmail}); err != nil {
+		t.Fatal(err)
+	}
+	s.cfg.secrets = store
+	return s
+}
+
+func TestSecretEnv(t *testing.T) {
+	s := newSecretsServer(t)
+
+	tests := []struct {
+		name     string
+		user     string
+		env      map[string]string
+		expected []string
+		code     codes.Code
+	}{
+		{name: "allowed user", user: adminEmail, env: map[string]string{"TOKEN": "api-token"},
+			expected: []string{"TOKEN=s3cr3t-t0k3n"}},
+		{name: "same secret twice", user: adminEmail, env: map[string]string{"B": "api-token", "A": "api-token"},
+			expected: []string{"A=s3cr3t-t0k3n", "B=s3cr3t-t0k3n"}},
+		{name: "no secrets", user: readerEmail},
+		{name: "user not allowed", user: readerEmail, env: map[string]string{"TOKEN": "api-token"},
+			code: codes.PermissionDenied},
+		{name: "unknown secret", user: adminEmail, env: map[string]string{"TOKEN": "db-password"},
+			code: codes.NotFound},
+		{name: "invalid variable name", user: adminEmail, env: map[string]string{"1TOKEN": "api-token"},
+			code: codes.InvalidArgument},
+		{name: "variable name with an assignment", user: adminEmail, env: map[string]string{"PATH=/tmp:X": "api-token"},
+			code: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, values, err := s.secretEnv(userContext(tt.user), tt.env)
+			if status.Code(err) != tt.code {
+				t.Fatalf("secretEnv returned %v, expected code %s", err, tt.code)
+			}
+			if !cmp.Equal(env, tt.expected) {
+				t.Fatalf("unexpected environment: %v", cmp.Diff(tt.expected, env))
+			}
+			if len(values) != len(tt.expected) {
+				t.Fatalf("unexpected secret values: %d", len(values))
+			}
+		})
+	}
+
+	s.cfg.secrets = nil
+	_, _, err := s.secretEnv(userContext(adminEmail), map[string]string{"TOKEN": "api-token"})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("secretEnv without a secret store returned %v", err)
+	}
+}
+
+func TestSecretRequests(t *testing.T) {
+	s := newSecretsServer(t)
+
+	tests := []struct {
+		name string
+		user string
+		op   string
+		req  *pb.SecretRequest
+		code codes.Code
+	}{
+		{name: "set", user: adminEmail, op: "set",
+			req: &pb.SecretRequest{Name: "db-password", Value: "hunter2", Users: []string{readerEmail}}},
+		{name: "set as reader", user: readerEmail, op: "set",
+			req: &pb.SecretRequest{Name: "db-password", Value: "x"}, code: codes.PermissionDenied},
+		{name: "set for unknown user", user: adminEmail, op: "set",
+			req:  &pb.SecretRequest{Name: "db-password", Value: "x", Users: []string{"eve@email.com"}},
+			code: codes.InvalidArgument},
+		{name: "set invalid name", user: adminEmail, op: "set",
+			req: &pb.SecretRequest{Name: "db password", Value: "x"}, code: codes.InvalidArgument},
+		{name: "set empty value", user: adminEmail, op: "set",
+			req: &pb.SecretRequest{Name: "db-password"}, code: codes.InvalidArgument},
+		{name: "delete as reader", user: readerEmail, op: "delete",
+			req: &pb.SecretRequest{Name: "db-password"}, code: codes.PermissionDenied},
+		{name: "delete", user: adminEmail, op: "delete", req: &pb.SecretRequest{Name: "api-token"}},
+		{name: "delete unknown", user: adminEmail, op: "delete",
+			req: &pb.SecretRequest{Name: "api-token"}, code: codes.NotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			switch tt.op {
+			case "set":
+				_, err = s.SetSecret(userContext(tt.user), tt.req)
+			case "delete":
+				_, err = s.DeleteSecret(userContext(tt.user), tt.req)
+			}
+			if status.Code(err) != tt.code {
+				t.Fatalf("%s returned %v, expected code %s", tt.op, err, tt.code)
+			}
+		})
+	}
+
+	// the secret set is the only one left, and the reader is allowed to use it
+	list, err := s.ListSecrets(userContext(adminEmail), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Secrets) != 1 || list.Secrets[0].Name != "db-password" {
+		t.Fatalf("unexpected secrets: %v", list.Secrets)
+	}
+	if env, _, err := s.secretEnv(userContext(readerEmail), map[string]string{"PASSWORD": "db-password"}); err != nil ||
+		!cmp.Equal(env, []string{"PASSWORD=hunter2"}) {
+		t.Fatalf("secretEnv returned %v, %v", env, err)
+	}
+}
+
+func TestExecCommandRedactsInjectedSecrets(t *testing.T) {
+	s := newSecretsServer(t)
+
+	details, err := s.ExecCommand(userContext(adminEmail), &pb.CmdRequest{
+		Command:   "sh",
+		Arguments: []string{"-c", `echo "token=$TOKEN"; echo "again $TOKEN."`},
+		SecretEnv: map[string]string{"TOKEN": "api-token"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, ok := s.db.GetJob(details.JobId)
+	if !ok {
+		t.Fatal("the job was not saved")
+	}
+	select {
+	case <-job.Exited():
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the job to exit")
+	}
+
+	reader := job.NewReader(0, false)
+	defer reader.Close()
+	var out []byte
+	for {
+		chunk, err := reader.Next(t.Context())
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, chunk.Data...)
+	}
+	if expected := "token=[REDACTED]\nagain [REDACTED].\n"; string(out) != expected {
+		t.Fatalf("unexpected output: %q, expected %q", out, expected)
+	}
+	if strings.Contains(string(out), "s3cr3t-t0k3n") || job.Redactions() != 2 {
+		t.Fatalf("the secret was not redacted: %d redactions", job.Redactions())
+	}
+}
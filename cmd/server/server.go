@@ -4,116 +4,451 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/mhsantos/rlcp/cmd/internal/pb"
 	"github.com/mhsantos/rlcp/cmd/server/internal/executor"
 	"github.com/mhsantos/rlcp/cmd/server/internal/storage"
+	"github.com/mhsantos/rlcp/cmd/server/internal/webhook"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	maxScriptSize      = 256 * 1024 // 256KB
+	maxDescriptionSize = 1024
+	defaultInterpreter = "/bin/sh"
+	lineFlushTimeout   = time.Second
 )
 
 type server struct {
 	pb.UnimplementedRemoteExecutorServer
-	db storage.JobStorage
+	db     storage.JobStorage
+	cfg    config
+	host   string
+	events *eventHub
+	// webhooks calls the webhooks of the jobs, and configWebhooks the ones configured on the server, which
+	// may call private addresses
+	webhooks       *webhook.Notifier
+	configWebhooks *webhook.Notifier
+	// names serializes the jobs saved with a name, so two of them can't take the same name
+	names sync.Mutex
 }
 
-func NewServer(db storage.JobStorage) *server {
+func NewServer(db storage.JobStorage, cfg config) *server {
+	host, err := os.Hostname()
+	if err != nil {
+		slog.Error("error getting the host name", slog.Any("error", err))
+	}
 	return &server{
-		db: db,
+		db:             db,
+		cfg:            cfg,
+		host:           host,
+		events:         newEventHub(),
+		webhooks:       webhook.NewNotifier(cfg.webhook),
+		configWebhooks: webhook.NewNotifier(configWebhookOptions(cfg.webhook)),
 	}
 }
-func (s *server) ExecCommand(ctx context.Context, req *pb.CmdRequest) (*pb.JobDetails, error) {
+
+// authorize checks if the user identified by the client certificate is allowed to execute the operation
+// and returns its user id
+func (s *server) authorize(ctx context.Context, op storage.Operation) (string, error) {
 	email := getRequesterEmail(ctx)
 	if len(email) == 0 {
 		slog.Error("invalid client email")
-		return nil, errors.New("email not informed on CommonName")
+		return "", errors.New("email not informed on CommonName")
 	}
 
 	userId, ok := s.db.GetUserId(email)
 	if !ok {
 		slog.Error("user id not found")
-		return nil, errors.New("couldn't find a user for the informed email")
+		return "", errors.New("couldn't find a user for the informed email")
 	}
 
-	if !s.db.Authorized(userId, storage.Run) {
-		slog.Error("not authorized")
-		return nil, errors.New("user not authorized to Run commands")
+	if !s.db.Authorized(userId, op) {
+		slog.Error("not authorized", slog.String("userid", userId), slog.Any("operation", op))
+		return "", status.Errorf(codes.PermissionDenied, "user not authorized to %s", op)
 	}
+	return userId, nil
+}
 
-	job := storage.NewJob()
-	s.db.SaveJob(job.Id.String(), job)
+func (s *server) ExecCommand(ctx context.Context, req *pb.CmdRequest) (*pb.JobDetails, error) {
+	if _, err := s.authorize(ctx, storage.Run); err != nil {
+		return nil, err
+	}
+
+	env, secretValues, err := s.secretEnv(ctx, req.SecretEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(req.Script) > maxScriptSize {
+		return nil, status.Errorf(codes.InvalidArgument, "script exceeds the maximum size of %d bytes", maxScriptSize)
+	}
+
+	if len(req.Description) > maxDescriptionSize {
+		return nil, status.Errorf(codes.InvalidArgument, "description exceeds the maximum size of %d bytes", maxDescriptionSize)
+	}
+
+	if err := validateLabels(req.Labels); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := validateWebhooks(req.Webhooks, s.cfg.webhook.AllowPrivate); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if req.Name != "" {
+		if err := validateJobName(req.Name); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	if err := executor.ValidateArtifactGlobs(req.Artifacts); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	logOptions, err := s.logOptions(req)
+	if err != nil {
+		return nil, err
+	}
+	// the secrets injected are masked in the output, along with the ones configured on the server
+	if logOptions.Redactor, err = logOptions.Redactor.WithSecrets(secretValues); err != nil {
+		return nil, status.Errorf(codes.Internal, "could not redact the secrets: %v", err)
+	}
+	job, err := storage.NewJob(logOptions)
+	if err != nil {
+		slog.Error("error creating job", slog.Any("error", err))
+		return nil, status.Errorf(codes.Internal, "could not create the job: %v", err)
+	}
+	workspace, err := filepath.Abs(filepath.Join(s.cfg.workspaceRoot, job.Id.String()))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not resolve the job workspace: %v", err)
+	}
+	job.Workspace = workspace
+	job.User = getRequesterEmail(ctx)
+	job.ArtifactGlobs = req.Artifacts
+	job.Command = req.Command
+	job.Args = req.Arguments
+	job.Description = req.Description
+	job.Labels = req.Labels
+	job.Host = s.host
+	job.Name = req.Name
+	job.Webhooks = req.Webhooks
+	if err := s.saveNewJob(job); err != nil {
+		return nil, err
+	}
+	s.events.publish(job, pb.JobEvent_SUBMITTED)
 
 	command := req.Command
 	args := req.Arguments
 
-	// Print the incoming data
-	slog.Debug("Received", slog.String("value", command))
-
-	err := executor.RunCommand(job, command, args)
+	if len(req.Script) > 0 {
+		interpreter := req.Interpreter
+		if len(interpreter) == 0 {
+			interpreter = defaultInterpreter
+		}
+		slog.Debug("Received script", slog.String("interpreter", interpreter), slog.Int("size", len(req.Script)))
+		job.Interpreter = interpreter
+		err = executor.RunScript(job, interpreter, req.Script, args, env)
+	} else {
+		// Print the incoming data
+		slog.Debug("Received", slog.String("value", command))
+		err = executor.RunCommand(job, command, args, env)
+	}
 	if err != nil {
 		slog.Error("error calling command execution")
+		// the job ends without starting, so it isn't left running
+		job.SetStatus(storage.Errored, fmt.Sprintf("the command could not be started: %v", err))
+		job.CloseOutput()
+		job.MarkExited(storage.UnknownExitCode)
+		s.db.SaveJob(job.Id.String(), job)
+		s.jobEnded(job)
 		return nil, err
 	}
+	// saved again with the process, so the job can be reconciled with it after a restart. A job which already
+	// finished is written along with its process once it's final.
+	if !job.Finished() {
+		s.db.SaveJob(job.Id.String(), job)
+	}
+	s.events.publish(job, pb.JobEvent_STARTED)
+	go s.jobEnded(job)
 
-	return &pb.JobDetails{
-		JobId:  job.Id.String(),
-		Status: pb.JobDetails_RUNNING,
-	}, nil
+	return jobDetails(job), nil
+}
+
+// logOptions returns the options to store the output for the job, applying the output limit and policy
+// requested, as long as the limit is not over the server limit
+func (s *server) logOptions(req *pb.CmdRequest) (storage.LogOptions, error) {
+	if req.MaxOutputBytes < 0 {
+		return storage.LogOptions{}, status.Errorf(codes.InvalidArgument, "max output bytes must not be negative")
+	}
+	if _, ok := pb.OutputPolicy_name[int32(req.OutputPolicy)]; !ok {
+		return storage.LogOptions{}, status.Errorf(codes.InvalidArgument, "invalid output policy %d", req.OutputPolicy)
+	}
+	opts := s.cfg.log
+	if req.MaxOutputBytes > 0 && (opts.MaxBytes == 0 || req.MaxOutputBytes < opts.MaxBytes) {
+		opts.MaxBytes = req.MaxOutputBytes
+	}
+	if req.OutputPolicy != pb.OutputPolicy_DEFAULT {
+		opts.Policy = storage.OutputPolicy(req.OutputPolicy)
+	}
+	return opts, nil
 }
 
 func (s *server) GetStatus(ctx context.Context, req *pb.GetRequest) (*pb.JobDetails, error) {
-	jobId := req.JobId
-	job, ok := s.db.GetJob(jobId)
-	if !ok {
-		return nil, status.Errorf(codes.NotFound, "Could not find a job for the id provided")
+	if _, err := s.authorize(ctx, storage.Status); err != nil {
+		return nil, err
 	}
+	job, err := s.findJob(ctx, req.JobId)
+	if err != nil {
+		return nil, err
+	}
+	jobId := job.Id.String()
 
-	fmt.Printf("jobid: %s, status:%s, pbStatus: %s\n", jobId, job.Status, pb.JobDetails_Status(job.Status))
+	fmt.Printf("jobid: %s, status:%s, pbStatus: %s\n", jobId, job.Status(), pb.JobDetails_Status(job.Status()))
 
-	return &pb.JobDetails{
-		JobId:  jobId,
-		Status: pb.JobDetails_Status(job.Status),
-	}, nil
+	return jobDetails(job), nil
+}
+
+// jobDetails returns the details reported for the job
+func jobDetails(job *storage.Job) *pb.JobDetails {
+	truncation, dropped := job.Truncation()
+	pid, _ := job.Process()
+	details := &pb.JobDetails{
+		JobId:         job.Id.String(),
+		Status:        pb.JobDetails_Status(job.Status()),
+		Artifacts:     job.Artifacts(),
+		OutputBytes:   job.Size(),
+		StoredBytes:   job.StoredSize(),
+		Truncation:    pb.OutputPolicy(truncation),
+		DroppedBytes:  dropped,
+		Redactions:    job.Redactions(),
+		StatusMessage: job.StatusMessage(),
+		Owner:         job.User,
+		Command:       job.Command,
+		Arguments:     job.Args,
+		Interpreter:   job.Interpreter,
+		Description:   job.Description,
+		SubmitTime:    timestamp(job.SubmitTime),
+		StartTime:     timestamp(job.StartTime),
+		EndTime:       timestamp(job.EndTime()),
+		Host:          job.Host,
+		Pid:           int32(pid),
+		Labels:        job.Labels,
+		Name:          job.Name,
+		ExitCode:      int32(job.ExitCode()),
+	}
+	for _, delivery := range job.Deliveries() {
+		details.WebhookDeliveries = append(details.WebhookDeliveries, &pb.WebhookDelivery{
+			Url:        delivery.URL,
+			Attempt:    int32(delivery.Attempt),
+			Time:       timestamppb.New(delivery.Time),
+			StatusCode: int32(delivery.StatusCode),
+			Error:      delivery.Error,
+		})
+	}
+	return details
 }
 
 func (s *server) GetOutput(req *pb.GetRequest, stream grpc.ServerStreamingServer[pb.JobOutput]) error {
-	jobId := req.JobId
-	job, ok := s.db.GetJob(jobId)
-	if !ok {
-		return status.Errorf(codes.NotFound, "Could not find a job for the id provided")
+	if _, err := s.authorize(stream.Context(), storage.Output); err != nil {
+		return err
+	}
+	job, err := s.findJob(stream.Context(), req.JobId)
+	if err != nil {
+		return err
+	}
+	jobId := job.Id.String()
+
+	size := job.Size()
+	if req.Offset < 0 || req.Offset > size {
+		return status.Errorf(codes.OutOfRange, "offset %d is outside of the job output with %d bytes", req.Offset, size)
+	}
+	if req.TailLines < 0 || req.TailBytes < 0 {
+		return status.Errorf(codes.InvalidArgument, "tail must not be negative")
+	}
+
+	offset := req.Offset
+	if req.TailBytes > 0 {
+		offset = max(offset, size-req.TailBytes)
+	}
+	if req.TailLines > 0 {
+		tailOffset, err := job.TailOffset(req.TailLines)
+		if err != nil {
+			slog.Error("error reading the output tail", slog.String("jobid", jobId), slog.Any("error", err))
+			return status.Errorf(codes.Internal, "could not read the output tail: %v", err)
+		}
+		offset = max(offset, tailOffset)
+	}
+	if req.TailBytes > 0 || req.TailLines > 0 {
+		// the tail starts after any output dropped
+		offset = job.Available(offset)
 	}
 
-	outCh := make(chan []byte)
+	filter, err := newOutputFilter(req)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid filter: %v", err)
+	}
+	if filter != nil || req.Lines {
+		return sendLines(job, offset, req.Mode != pb.GetRequest_SNAPSHOT, filter, stream)
+	}
 
-	go job.RegisterListener(outCh)
+	reader := job.NewReader(offset, req.Mode != pb.GetRequest_SNAPSHOT)
+	defer reader.Close()
 
-	for out := range outCh {
-		err := stream.Send(&pb.JobOutput{Output: out})
+	for {
+		out, err := reader.Next(stream.Context())
+		if err != nil {
+			return outputError(jobId, err)
+		}
+		err = stream.Send(&pb.JobOutput{
+			Output:     out.Data,
+			Offset:     out.Offset,
+			Time:       timestamp(out.Time),
+			Stream:     pb.JobOutput_Stream(out.Stream),
+			NextOffset: out.Offset + int64(len(out.Data)) + out.Missing,
+			Missing:    out.Missing,
+		})
 		if err != nil {
 			slog.Error("error sending response to client", slog.Any("error", err))
 			return err
 		}
 	}
-	return nil
 }
 
-func (s *server) StopJob(ctx context.Context, req *pb.StopRequest) (*emptypb.Empty, error) {
-	jobId := req.JobId
+// sendLines streams the lines of output matching the filter, one line per message, starting at offset.
+// The output dropped from the log is always reported, regardless of the filter.
+// Without a filter, the partial lines are sent once they wait longer than lineFlushTimeout, so the output
+// of commands waiting for input is not held back. When filtering, lines are only matched once complete.
+func sendLines(job *storage.Job, offset int64, follow bool, filter *outputFilter, stream grpc.ServerStreamingServer[pb.JobOutput]) error {
+	var flushAfter time.Duration
+	if filter == nil {
+		flushAfter = lineFlushTimeout
+	}
+	reader, err := job.NewLineReader(offset, follow, flushAfter)
+	if err != nil {
+		return outputError(job.Id.String(), err)
+	}
+	defer reader.Close()
 
-	job, ok := s.db.GetJob(jobId)
-	if !ok {
-		return nil, status.Errorf(codes.NotFound, "Could not find a job for the id provided")
+	var sent int64
+	var past [2]bool
+	for {
+		line, err := reader.Next(stream.Context())
+		if err != nil {
+			return outputError(job.Id.String(), err)
+		}
+		missing := line.Missing > 0
+		if !missing && filter.past(line) {
+			past[line.Stream] = true
+			if past[storage.Stdout] && past[storage.Stderr] {
+				return nil
+			}
+			continue
+		}
+		if !missing && !filter.match(line) {
+			continue
+		}
+		err = stream.Send(&pb.JobOutput{
+			Output:     line.Data,
+			Offset:     line.Offset,
+			Time:       timestamp(line.Time),
+			Stream:     pb.JobOutput_Stream(line.Stream),
+			NextOffset: reader.Resume(),
+			Line:       line.Number,
+			Missing:    line.Missing,
+		})
+		if err != nil {
+			slog.Error("error sending response to client", slog.Any("error", err))
+			return err
+		}
+		if !missing {
+			sent++
+		}
+		if filter.full(sent) {
+			return nil
+		}
+	}
+}
+
+// timestamp converts a time which may not be set, like the time a chunk was captured, for output dropped,
+// or the end time of a running job
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// outputError maps the errors reading the output to the status returned to the client. io.EOF ends the stream.
+func outputError(jobId string, err error) error {
+	if err == io.EOF {
+		return nil
+	}
+	if errors.Is(err, storage.ErrOutputPurged) {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	slog.Error("error reading job output", slog.String("jobid", jobId), slog.Any("error", err))
+	return err
+}
+
+func (s *server) StopJob(ctx context.Context, req *pb.StopRequest) (*emptypb.Empty, error) {
+	if _, err := s.authorize(ctx, storage.Stop); err != nil {
+		return nil, err
+	}
+	job, err := s.findJob(ctx, req.JobId)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := job.Cmd.Process.Kill(); err != nil {
-		job.Status = storage.Errored
-		return nil, status.Errorf(codes.Unknown, "Error killing the process: %v", err)
+	if err := stopJob(job); err != nil {
+		return nil, err
 	}
-	job.Status = storage.Stopped
-	job.CloseListeners()
 
 	return nil, nil
 }
+
+// stopJob kills the processes for a running job. A process which exits before it's killed still leaves the job
+// stopped, since the status was already set.
+func stopJob(job *storage.Job) error {
+	// the status is set before killing the process, so it's not overwritten once the output is closed
+	if job.Cmd == nil || job.Cmd.Process == nil || !job.UpdateStatus(storage.Running, storage.Stopped) {
+		return status.Errorf(codes.FailedPrecondition, "The job is not running")
+	}
+	if err := executor.SignalJob(job, syscall.SIGKILL); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		job.UpdateStatus(storage.Stopped, storage.Errored)
+		return status.Errorf(codes.Unknown, "Error killing the process: %v", err)
+	}
+	return nil
+}
+
+func (s *server) PurgeJobOutput(ctx context.Context, req *pb.PurgeRequest) (*emptypb.Empty, error) {
+	if _, err := s.authorize(ctx, storage.Purge); err != nil {
+		return nil, err
+	}
+
+	job, err := s.findJob(ctx, req.JobId)
+	if err != nil {
+		return nil, err
+	}
+	if !job.Finished() {
+		return nil, status.Errorf(codes.FailedPrecondition, "The output can only be purged after the job ends")
+	}
+
+	if err := job.PurgeOutput(); err != nil {
+		slog.Error("error purging job output", slog.String("jobid", job.Id.String()), slog.Any("error", err))
+		return nil, status.Errorf(codes.Internal, "Error purging the job output: %v", err)
+	}
+	s.db.SaveJob(job.Id.String(), job)
+	return &emptypb.Empty{}, nil
+}
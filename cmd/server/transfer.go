@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"syscall"
+
+	"github.com/mhsantos/rlcp/cmd/internal/pb"
+	"github.com/mhsantos/rlcp/cmd/server/internal/executor"
+	"github.com/mhsantos/rlcp/cmd/server/internal/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const fileChunkSize = 32 * 1024 // 32KB
+
+// UploadFile writes the chunks received from the client to the path informed on the first chunk.
+// Uploads starting at a non zero offset resume a previous transfer, so the offset must match the
+// current size of the file on the server.
+func (s *server) UploadFile(stream grpc.ClientStreamingServer[pb.FileChunk, pb.FileInfo]) error {
+	if _, err := s.authorize(stream.Context(), storage.Upload); err != nil {
+		return err
+	}
+
+	chunk, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	path := chunk.Path
+	if len(path) == 0 {
+		return status.Errorf(codes.InvalidArgument, "destination path not informed")
+	}
+
+	// a resumed upload only writes to the file left by the previous transfer, so it's not created. O_NONBLOCK
+	// keeps a fifo from blocking the open, and only regular files are written.
+	flags := os.O_WRONLY | syscall.O_NONBLOCK
+	if chunk.Offset == 0 {
+		flags |= os.O_CREATE | os.O_TRUNC
+	}
+	file, err := os.OpenFile(path, flags, 0600)
+	if chunk.Offset > 0 && errors.Is(err, fs.ErrNotExist) {
+		return status.Errorf(codes.FailedPrecondition, "cannot resume %s at offset %d, the file does not exist", path, chunk.Offset)
+	}
+	if err != nil {
+		slog.Error("error opening upload destination", slog.String("path", path), slog.Any("error", err))
+		return status.Errorf(codes.InvalidArgument, "could not open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return status.Errorf(codes.Internal, "could not stat %s: %v", path, err)
+	}
+	if !info.Mode().IsRegular() {
+		return status.Errorf(codes.InvalidArgument, "%s is not a regular file", path)
+	}
+	if info.Size() != chunk.Offset {
+		return status.Errorf(codes.FailedPrecondition, "cannot resume %s at offset %d, file has %d bytes", path, chunk.Offset, info.Size())
+	}
+	if _, err := file.Seek(chunk.Offset, io.SeekStart); err != nil {
+		return status.Errorf(codes.Internal, "could not seek %s: %v", path, err)
+	}
+
+	mode := fs.FileMode(chunk.Mode).Perm()
+	var checksum []byte
+	for {
+		if _, err := file.Write(chunk.Data); err != nil {
+			slog.Error("error writing upload", slog.String("path", path), slog.Any("error", err))
+			return status.Errorf(codes.Internal, "could not write %s: %v", path, err)
+		}
+		if len(chunk.Checksum) > 0 {
+			checksum = chunk.Checksum
+		}
+		chunk, err = stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if mode != 0 {
+		if err := file.Chmod(mode); err != nil {
+			return status.Errorf(codes.Internal, "could not set mode on %s: %v", path, err)
+		}
+	}
+
+	fileInfo, err := statFile(path)
+	if err != nil {
+		return err
+	}
+	if len(checksum) > 0 && !bytes.Equal(checksum, fileInfo.Checksum) {
+		return status.Errorf(codes.DataLoss, "checksum mismatch for %s", path)
+	}
+	return stream.SendAndClose(fileInfo)
+}
+
+// DownloadFile streams the file from the requested offset. The mode is sent on the first chunk
+// and the checksum for the whole file on the last one.
+func (s *server) DownloadFile(req *pb.FileRequest, stream grpc.ServerStreamingServer[pb.FileChunk]) error {
+	if _, err := s.authorize(stream.Context(), storage.Download); err != nil {
+		return err
+	}
+
+	// the file is described and sent from the same descriptor, so it can't be swapped in between
+	file, fileInfo, err := openFile(req.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if req.Offset < 0 || req.Offset > fileInfo.Size {
+		return status.Errorf(codes.OutOfRange, "offset %d is outside of %s with %d bytes", req.Offset, req.Path, fileInfo.Size)
+	}
+	if _, err := file.Seek(req.Offset, io.SeekStart); err != nil {
+		return status.Errorf(codes.Internal, "could not seek %s: %v", req.Path, err)
+	}
+
+	offset := req.Offset
+	buffer := make([]byte, fileChunkSize)
+	for {
+		n, err := io.ReadFull(file, buffer)
+		last := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !last {
+			return status.Errorf(codes.Internal, "could not read %s: %v", req.Path, err)
+		}
+		chunk := &pb.FileChunk{
+			Path:   req.Path,
+			Offset: offset,
+			Data:   buffer[:n],
+		}
+		if offset == req.Offset {
+			chunk.Mode = fileInfo.Mode
+		}
+		if last {
+			chunk.Checksum = fileInfo.Checksum
+		}
+		if err := stream.Send(chunk); err != nil {
+			slog.Error("error sending file chunk", slog.Any("error", err))
+			return err
+		}
+		offset += int64(n)
+		if last {
+			return nil
+		}
+	}
+}
+
+func (s *server) StatFile(ctx context.Context, req *pb.FileRequest) (*pb.FileInfo, error) {
+	if _, err := s.authorize(ctx, storage.Download); err != nil {
+		return nil, err
+	}
+	return statFile(req.Path)
+}
+
+// DownloadArtifacts streams the artifacts kept in the workspace of a finished job as a tar.gz archive
+func (s *server) DownloadArtifacts(req *pb.GetRequest, stream grpc.ServerStreamingServer[pb.ArchiveChunk]) error {
+	if _, err := s.authorize(stream.Context(), storage.Output); err != nil {
+		return err
+	}
+
+	job, err := s.findJob(stream.Context(), req.JobId)
+	if err != nil {
+		return err
+	}
+	if job.Status() == storage.Running {
+		return status.Errorf(codes.FailedPrecondition, "artifacts are only available after the job ends")
+	}
+	select {
+	case <-job.Exited():
+	case <-stream.Context().Done():
+		return stream.Context().Err()
+	}
+
+	w := bufio.NewWriterSize(archiveWriter{stream}, fileChunkSize)
+	if err := executor.WriteArtifacts(job, w); err != nil {
+		slog.Error("error archiving artifacts", slog.String("jobid", job.Id.String()), slog.Any("error", err))
+		return status.Errorf(codes.Internal, "could not archive the artifacts: %v", err)
+	}
+	return w.Flush()
+}
+
+// archiveWriter sends everything written to it as ArchiveChunk messages
+type archiveWriter struct {
+	stream grpc.ServerStreamingServer[pb.ArchiveChunk]
+}
+
+func (a archiveWriter) Write(p []byte) (int, error) {
+	if err := a.stream.Send(&pb.ArchiveChunk{Data: p}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// statFile returns the size, permission bits and sha256 checksum for the file
+func statFile(path string) (*pb.FileInfo, error) {
+	file, fileInfo, err := openFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file.Close()
+	return fileInfo, nil
+}
+
+// openFile opens the file for reading and returns it along with its size, permission bits and sha256 checksum.
+// Only regular files are opened, since the others, like fifos or devices, may block or never end. O_NONBLOCK
+// keeps a fifo from blocking the open, and doesn't affect the reads from regular files.
+func openFile(path string) (*os.File, *pb.FileInfo, error) {
+	file, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, status.Errorf(codes.NotFound, "%s does not exist", path)
+		}
+		return nil, nil, status.Errorf(codes.InvalidArgument, "could not open %s: %v", path, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, status.Errorf(codes.Internal, "could not stat %s: %v", path, err)
+	}
+	if info.IsDir() {
+		file.Close()
+		return nil, nil, status.Errorf(codes.InvalidArgument, "%s is a directory", path)
+	}
+	if !info.Mode().IsRegular() {
+		file.Close()
+		return nil, nil, status.Errorf(codes.InvalidArgument, "%s is not a regular file", path)
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, io.NewSectionReader(file, 0, info.Size())); err != nil {
+		file.Close()
+		return nil, nil, status.Errorf(codes.Internal, "could not read %s: %v", path, err)
+	}
+	return file, &pb.FileInfo{
+		Path:     path,
+		Size:     info.Size(),
+		Mode:     uint32(info.Mode().Perm()),
+		Checksum: hash.Sum(nil),
+	}, nil
+}
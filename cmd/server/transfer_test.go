@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mhsantos/rlcp/cmd/internal/pb"
+)
+
+// uploadStream sends the chunks to UploadFile, and keeps the file info it returns
+type uploadStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	chunks []*pb.FileChunk
+	info   *pb.FileInfo
+}
+
+func (u *uploadStream) Context() context.Context { return u.ctx }
+
+func (u *uploadStream) Recv() (*pb.FileChunk, error) {
+	if len(u.chunks) == 0 {
+		return nil, io.EOF
+	}
+	chunk := u.chunks[0]
+	u.chunks = u.chunks[1:]
+	return chunk, nil
+}
+
+func (u *uploadStream) SendAndClose(info *pb.FileInfo) error {
+	u.info = info
+	return nil
+}
+
+// downloadStream keeps the chunks sent by DownloadFile
+type downloadStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	chunks []*pb.FileChunk
+}
+
+func (d *downloadStream) Context() context.Context { return d.ctx }
+
+func (d *downloadStream) Send(chunk *pb.FileChunk) error {
+	d.chunks = append(d.chunks, &pb.FileChunk{Offset: chunk.Offset, Data: append([]byte(nil), chunk.Data...),
+		Checksum: chunk.Checksum})
+	return nil
+}
+
+func TestStatFile(t *testing.T) {
+	dir := t.TempDir()
+	regular := filepath.Join(dir, "data.txt")
+	if err := os.WriteFile(regular, []byte("some data"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	fifo := filepath.Join(dir, "fifo")
+	if err := syscall.Mkfifo(fifo, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		code codes.Code
+	}{
+		{name: "regular file", path: regular, code: codes.OK},
+		{name: "missing file", path: filepath.Join(dir, "missing"), code: codes.NotFound},
+		{name: "directory", path: dir, code: codes.InvalidArgument},
+		{name: "fifo", path: fifo, code: codes.InvalidArgument},
+		{name: "device", path: "/dev/zero", code: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := statFile(tt.path)
+			if status.Code(err) != tt.code {
+				t.Fatalf("statFile(%s) returned %v, expected code %s", tt.path, err, tt.code)
+			}
+			if err != nil {
+				return
+			}
+			checksum := sha256.Sum256([]byte("some data"))
+			if info.Size != 9 || info.Mode != 0640 || string(info.Checksum) != string(checksum[:]) {
+				t.Fatalf("unexpected file info: %v", info)
+			}
+		})
+	}
+}
+
+func TestUploadFile(t *testing.T) {
+	s := newTestServer(t)
+	ctx := userContext(adminEmail)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "upload.bin")
+
+	upload := &uploadStream{ctx: ctx, chunks: []*pb.FileChunk{{Path: path, Data: []byte("first ")}}}
+	if err := s.UploadFile(upload); err != nil {
+		t.Fatal(err)
+	}
+	checksum := sha256.Sum256([]byte("first second"))
+	upload = &uploadStream{ctx: ctx, chunks: []*pb.FileChunk{
+		{Path: path, Offset: 6, Data: []byte("second"), Mode: 0600, Checksum: checksum[:]},
+	}}
+	if err := s.UploadFile(upload); err != nil {
+		t.Fatal(err)
+	}
+	if upload.info.Size != 12 || string(upload.info.Checksum) != string(checksum[:]) {
+		t.Fatalf("unexpected file info: %v", upload.info)
+	}
+
+	missing := filepath.Join(dir, "missing.bin")
+	upload = &uploadStream{ctx: ctx, chunks: []*pb.FileChunk{{Path: missing, Offset: 6, Data: []byte("second")}}}
+	if err := s.UploadFile(upload); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("resuming a missing file returned %v", err)
+	}
+	if _, err := os.Stat(missing); !os.IsNotExist(err) {
+		t.Fatalf("the failed resume left a file behind: %v", err)
+	}
+
+	upload = &uploadStream{ctx: ctx, chunks: []*pb.FileChunk{{Path: path, Offset: 3, Data: []byte("x")}}}
+	if err := s.UploadFile(upload); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("resuming at the wrong offset returned %v", err)
+	}
+}
+
+func TestDownloadFile(t *testing.T) {
+	s := newTestServer(t)
+	ctx := userContext(adminEmail)
+	path := filepath.Join(t.TempDir(), "download.bin")
+	data := make([]byte, fileChunkSize+100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	download := &downloadStream{ctx: ctx}
+	if err := s.DownloadFile(&pb.FileRequest{Path: path, Offset: 50}, download); err != nil {
+		t.Fatal(err)
+	}
+	var received []byte
+	for _, chunk := range download.chunks {
+		received = append(received, chunk.Data...)
+	}
+	checksum := sha256.Sum256(data)
+	last := download.chunks[len(download.chunks)-1]
+	if string(received) != string(data[50:]) || string(last.Checksum) != string(checksum[:]) {
+		t.Fatalf("unexpected download: %d bytes in %d chunks", len(received), len(download.chunks))
+	}
+
+	for _, req := range []*pb.FileRequest{{Path: "/dev/zero"}, {Path: path, Offset: int64(len(data) + 1)}} {
+		if err := s.DownloadFile(req, &downloadStream{ctx: ctx}); err == nil {
+			t.Fatalf("downloading %s at %d succeeded", req.Path, req.Offset)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/mhsantos/rlcp/cmd/server/internal/storage"
+	"github.com/mhsantos/rlcp/cmd/server/internal/webhook"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	maxWebhooks = 8
+	// maxTailBytes limits the output tail sent to the webhooks
+	maxTailBytes = 16 * 1024
+)
+
+// webhookPayload is the body posted to the webhooks once a job ends. Event is job. followed by the final
+// status of the job, like job.completed, and Job has its details, in the same format as rlcp list --format json.
+type webhookPayload struct {
+	Event      string          `json:"event"`
+	Job        json.RawMessage `json:"job"`
+	OutputTail string          `json:"output_tail"`
+}
+
+// validateWebhooks returns an error if there are too many webhooks, or if any of them is not a valid url, or one
+// with an address the webhooks of the jobs are not allowed to call
+func validateWebhooks(urls []string, allowPrivate bool) error {
+	if len(urls) > maxWebhooks {
+		return fmt.Errorf("a job can have at most %d webhooks", maxWebhooks)
+	}
+	for _, u := range urls {
+		if err := webhook.ValidateURL(u, allowPrivate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notifyEnd posts the end of the job to its webhooks and to the ones configured on the server. Every attempt
+// is recorded on the job. The attempts made before the final record of the job is written are part of it, and
+// the job is saved again for the later ones, unless it was removed in the meantime.
+func (s *server) notifyEnd(job *storage.Job) {
+	if len(s.cfg.webhooks) == 0 && len(job.Webhooks) == 0 {
+		return
+	}
+	jobId := job.Id.String()
+	event := "job." + strings.ToLower(job.Status().String())
+	tail, err := outputTail(job, s.cfg.tailLines)
+	if err != nil {
+		slog.Error("error reading the output tail for the webhooks", slog.String("jobid", jobId), slog.Any("error", err))
+	}
+	details, err := protojson.MarshalOptions{EmitDefaultValues: true}.Marshal(jobDetails(job))
+	if err != nil {
+		slog.Error("error encoding the job for the webhooks", slog.String("jobid", jobId), slog.Any("error", err))
+		return
+	}
+	body, err := json.Marshal(webhookPayload{Event: event, Job: details, OutputTail: tail})
+	if err != nil {
+		slog.Error("error encoding the webhook payload", slog.String("jobid", jobId), slog.Any("error", err))
+		return
+	}
+	record := func(delivery webhook.Delivery) {
+		job.AddDelivery(delivery)
+		if !job.Persisted() {
+			return
+		}
+		if current, ok := s.db.GetJob(jobId); ok && current == job {
+			s.db.SaveJob(jobId, job)
+		}
+	}
+	s.configWebhooks.Notify(s.cfg.webhooks, event, body, record)
+	s.webhooks.Notify(job.Webhooks, event, body, record)
+}
+
+// configWebhookOptions returns the options for the webhooks configured on the server, which are trusted to call
+// the hosts in private networks
+func configWebhookOptions(opts webhook.Options) webhook.Options {
+	opts.AllowPrivate = true
+	return opts
+}
+
+// closeWebhooks gives up the webhook notifications waiting to be retried, and waits for the ones in progress
+func (s *server) closeWebhooks() {
+	s.configWebhooks.Close()
+	s.webhooks.Close()
+}
+
+// outputTail returns the last lines of the output from a finished job, up to maxTailBytes. The output purged
+// has no tail.
+func outputTail(job *storage.Job, lines int64) (string, error) {
+	if lines == 0 {
+		return "", nil
+	}
+	offset, err := job.TailOffset(lines)
+	if errors.Is(err, storage.ErrOutputPurged) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	offset = job.Available(max(offset, job.Size()-maxTailBytes))
+	reader := job.NewReader(offset, false)
+	defer reader.Close()
+	var tail bytes.Buffer
+	for {
+		chunk, err := reader.Next(context.Background())
+		if err == io.EOF || errors.Is(err, storage.ErrOutputPurged) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		tail.Write(chunk.Data)
+	}
+	return strings.ToValidUTF8(tail.String(), "�"), nil
+}
@@ -5,6 +5,7 @@ go 1.24.3
 require (
 	github.com/google/go-cmp v0.7.0
 	github.com/google/uuid v1.6.0
+	go.etcd.io/bbolt v1.4.0
 	google.golang.org/grpc v1.72.2
 	google.golang.org/protobuf v1.36.6
 )